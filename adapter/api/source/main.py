@@ -1,4 +1,4 @@
-from flask import Flask, jsonify
+from flask import Flask, jsonify, request
 import random
 
 app = Flask(__name__)
@@ -45,5 +45,11 @@ def calculate_loss():
     return "1.0"
 
 
+
+@app.route('/calculate_batch', methods=['POST'])
+def calculate_losses():
+    y_pred = request.get_json()["y_pred"]
+    return jsonify({"losses": ["1.0" for _ in y_pred]})
+
 if __name__ == '__main__':
     app.run(debug=True, host='0.0.0.0', port=8000)
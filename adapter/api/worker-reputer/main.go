@@ -3,6 +3,7 @@ package api_worker_reputer
 import (
 	"allora_offchain_node/lib"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,6 +15,9 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+var _ lib.BatchLossFunctionAdapter = (*AlloraAdapter)(nil)
+var _ lib.GroundTruthSourceAdapter = (*AlloraAdapter)(nil)
+
 type AlloraAdapter struct {
 	name string
 }
@@ -44,9 +48,13 @@ func replaceExtendedPlaceholders(urlTemplate string, params map[string]string, b
 	return urlTemplate
 }
 
-func requestEndpoint(url string) (string, error) {
+func requestEndpoint(ctx context.Context, url string) (string, error) {
 	// make request to url
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to make request to %s: %w", url, err)
 	}
@@ -69,11 +77,11 @@ func requestEndpoint(url string) (string, error) {
 }
 
 // Expects an inference as a string scalar value
-func (a *AlloraAdapter) CalcInference(node lib.WorkerConfig, blockHeight int64) (string, error) {
+func (a *AlloraAdapter) CalcInference(ctx context.Context, node lib.WorkerConfig, blockHeight int64) (string, error) {
 	urlTemplate := node.Parameters["InferenceEndpoint"]
 	url := replaceExtendedPlaceholders(urlTemplate, node.Parameters, blockHeight, node.TopicId)
 	log.Debug().Str("url", url).Msg("Inference")
-	return requestEndpoint(url)
+	return requestEndpoint(ctx, url)
 }
 
 // parseJSONToNodeValues parses the incoming JSON string and returns a slice of NodeValue.
@@ -106,12 +114,12 @@ func parseJSONToNodeValues(jsonStr string) ([]lib.NodeValue, error) {
 }
 
 // Expects forecast as a json array of NodeValue
-func (a *AlloraAdapter) CalcForecast(node lib.WorkerConfig, blockHeight int64) ([]lib.NodeValue, error) {
+func (a *AlloraAdapter) CalcForecast(ctx context.Context, node lib.WorkerConfig, blockHeight int64) ([]lib.NodeValue, error) {
 	urlTemplate := node.Parameters["ForecastEndpoint"]
 	url := replaceExtendedPlaceholders(urlTemplate, node.Parameters, blockHeight, node.TopicId)
 	log.Debug().Str("url", url).Msg("Forecasts endpoint")
 
-	forecastsAsJsonString, err := requestEndpoint(url)
+	forecastsAsJsonString, err := requestEndpoint(ctx, url)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get forecasts")
 		return []lib.NodeValue{}, err
@@ -126,11 +134,16 @@ func (a *AlloraAdapter) CalcForecast(node lib.WorkerConfig, blockHeight int64) (
 	return nodeValues, nil
 }
 
-func (a *AlloraAdapter) GroundTruth(node lib.ReputerConfig, blockHeight int64) (lib.Truth, error) {
+// The ground truth comes from the GroundTruthEndpoint URL with its placeholders replaced
+func (a *AlloraAdapter) GroundTruthSource(node lib.ReputerConfig, blockHeight int64) string {
 	urlTemplate := node.GroundTruthParameters["GroundTruthEndpoint"]
-	url := replaceExtendedPlaceholders(urlTemplate, node.GroundTruthParameters, blockHeight, node.TopicId)
+	return replaceExtendedPlaceholders(urlTemplate, node.GroundTruthParameters, blockHeight, node.TopicId)
+}
+
+func (a *AlloraAdapter) GroundTruth(ctx context.Context, node lib.ReputerConfig, blockHeight int64) (lib.Truth, error) {
+	url := a.GroundTruthSource(node, blockHeight)
 	log.Debug().Str("url", url).Msg("Source of truth")
-	groundTruth, err := requestEndpoint(url)
+	groundTruth, err := requestEndpoint(ctx, url)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get ground truth")
 		return "", err
@@ -148,7 +161,7 @@ func (a *AlloraAdapter) GroundTruth(node lib.ReputerConfig, blockHeight int64) (
 	return lib.Truth(groundTruthDec.String()), nil
 }
 
-func (a *AlloraAdapter) LossFunction(node lib.ReputerConfig, groundTruth string, inferenceValue string, options map[string]string) (string, error) {
+func (a *AlloraAdapter) LossFunction(ctx context.Context, node lib.ReputerConfig, groundTruth string, inferenceValue string, options map[string]string) (string, error) {
 	url := node.LossFunctionParameters.LossFunctionService
 	if url == "" {
 		return "", fmt.Errorf("no loss function endpoint provided")
@@ -170,7 +183,7 @@ func (a *AlloraAdapter) LossFunction(node lib.ReputerConfig, groundTruth string,
 	}
 
 	// Create a new POST request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -206,7 +219,65 @@ func (a *AlloraAdapter) LossFunction(node lib.ReputerConfig, groundTruth string,
 	return result.Loss, nil
 }
 
-func (a *AlloraAdapter) IsLossFunctionNeverNegative(node lib.ReputerConfig, options map[string]string) (bool, error) {
+// Computes the losses of all the values against the ground truth in a single request to the /calculate_batch endpoint
+// of the loss function service, or returns lib.ErrBatchLossFunctionUnsupported if the service has no such endpoint
+func (a *AlloraAdapter) BatchLossFunction(ctx context.Context, node lib.ReputerConfig, groundTruth string, values []string, options map[string]string) ([]string, error) {
+	url := node.LossFunctionParameters.LossFunctionService
+	if url == "" {
+		return nil, fmt.Errorf("no loss function endpoint provided")
+	}
+	// Use /calculate_batch endpoint of loss-functions service
+	url = fmt.Sprintf("%s/calculate_batch", url)
+
+	payload := map[string]interface{}{
+		"y_true":  groundTruth,
+		"y_pred":  values,
+		"options": options,
+	}
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil, lib.ErrBatchLossFunctionUnsupported
+	default:
+		return nil, fmt.Errorf("received non-OK HTTP status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	var result struct {
+		Losses []string `json:"losses"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Losses) != len(values) {
+		return nil, fmt.Errorf("got %d losses for %d values", len(result.Losses), len(values))
+	}
+
+	log.Debug().Int("count", len(result.Losses)).Msg("Calculated loss values in batch from external endpoint")
+	return result.Losses, nil
+}
+
+func (a *AlloraAdapter) IsLossFunctionNeverNegative(ctx context.Context, node lib.ReputerConfig, options map[string]string) (bool, error) {
 	url := node.LossFunctionParameters.LossFunctionService
 	if url == "" {
 		return false, fmt.Errorf("no loss function endpoint provided")
@@ -227,7 +298,7 @@ func (a *AlloraAdapter) IsLossFunctionNeverNegative(node lib.ReputerConfig, opti
 	}
 
 	// Create a new POST request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		return false, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -0,0 +1,44 @@
+package api_worker_reputer
+
+import (
+	"allora_offchain_node/lib"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchLossFunction(t *testing.T) {
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			YTrue   string            `json:"y_true"`
+			YPred   []string          `json:"y_pred"`
+			Options map[string]string `json:"options"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.Equal(t, "10", request.YTrue)
+		require.Equal(t, map[string]string{"loss_method": "sqe"}, request.Options)
+		losses := make([]string, len(request.YPred))
+		for i := range request.YPred {
+			losses[i] = "0.5"
+		}
+		json.NewEncoder(w).Encode(map[string][]string{"losses": losses})
+	}))
+	defer service.Close()
+	legacyService := httptest.NewServer(http.NotFoundHandler())
+	defer legacyService.Close()
+
+	adapter := NewAlloraAdapter()
+	options := map[string]string{"loss_method": "sqe"}
+	reputer := lib.ReputerConfig{LossFunctionParameters: lib.LossFunctionParameters{LossFunctionService: service.URL}}
+	losses, err := adapter.BatchLossFunction(context.Background(), reputer, "10", []string{"9", "11"}, options)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0.5", "0.5"}, losses)
+
+	reputer.LossFunctionParameters.LossFunctionService = legacyService.URL
+	_, err = adapter.BatchLossFunction(context.Background(), reputer, "10", []string{"9", "11"}, options)
+	require.ErrorIs(t, err, lib.ErrBatchLossFunctionUnsupported)
+}
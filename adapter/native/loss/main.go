@@ -0,0 +1,94 @@
+package native_loss
+
+import (
+	"allora_offchain_node/lib"
+	"context"
+	"errors"
+	"fmt"
+
+	alloraMath "github.com/allora-network/allora-chain/math"
+	"github.com/rs/zerolog/log"
+)
+
+var _ lib.BatchLossFunctionAdapter = (*AlloraAdapter)(nil)
+
+// Adapter computing the losses of a reputer in-process with the native loss functions of lib,
+// selected by the loss_method of LossMethodOptions. It neither infers, forecasts nor sources ground truth.
+type AlloraAdapter struct {
+	name string
+}
+
+func (a *AlloraAdapter) Name() string {
+	return a.name
+}
+
+func (a *AlloraAdapter) CalcInference(ctx context.Context, node lib.WorkerConfig, blockHeight int64) (string, error) {
+	return "", errors.New("the native loss adapter cannot infer")
+}
+
+func (a *AlloraAdapter) CalcForecast(ctx context.Context, node lib.WorkerConfig, blockHeight int64) ([]lib.NodeValue, error) {
+	return nil, errors.New("the native loss adapter cannot forecast")
+}
+
+func (a *AlloraAdapter) GroundTruth(ctx context.Context, node lib.ReputerConfig, blockHeight int64) (lib.Truth, error) {
+	return "", errors.New("the native loss adapter cannot source ground truth")
+}
+
+func (a *AlloraAdapter) LossFunction(ctx context.Context, node lib.ReputerConfig, groundTruth string, inferenceValue string, options map[string]string) (string, error) {
+	groundTruthDec, err := alloraMath.NewDecFromString(groundTruth)
+	if err != nil {
+		return "", fmt.Errorf("invalid ground truth %q: %w", groundTruth, err)
+	}
+	valueDec, err := alloraMath.NewDecFromString(inferenceValue)
+	if err != nil {
+		return "", fmt.Errorf("invalid value %q: %w", inferenceValue, err)
+	}
+	loss, err := lib.NativeLoss(groundTruthDec, valueDec, options)
+	if err != nil {
+		return "", err
+	}
+	log.Debug().Str("Loss", loss.String()).Msg("Calculated loss value natively")
+	return loss.String(), nil
+}
+
+func (a *AlloraAdapter) BatchLossFunction(ctx context.Context, node lib.ReputerConfig, groundTruth string, values []string, options map[string]string) ([]string, error) {
+	groundTruthDec, err := alloraMath.NewDecFromString(groundTruth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ground truth %q: %w", groundTruth, err)
+	}
+	losses := make([]string, len(values))
+	for i, value := range values {
+		valueDec, err := alloraMath.NewDecFromString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %d %q: %w", i, value, err)
+		}
+		loss, err := lib.NativeLoss(groundTruthDec, valueDec, options)
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %w", i, err)
+		}
+		losses[i] = loss.String()
+	}
+	return losses, nil
+}
+
+func (a *AlloraAdapter) IsLossFunctionNeverNegative(ctx context.Context, node lib.ReputerConfig, options map[string]string) (bool, error) {
+	return lib.IsNativeLossNeverNegative(options)
+}
+
+func (a *AlloraAdapter) CanInfer() bool {
+	return false
+}
+
+func (a *AlloraAdapter) CanForecast() bool {
+	return false
+}
+
+func (a *AlloraAdapter) CanSourceGroundTruthAndComputeLoss() bool {
+	return false
+}
+
+func NewAlloraAdapter() *AlloraAdapter {
+	return &AlloraAdapter{
+		name: lib.NATIVE_LOSS_ADAPTER_NAME,
+	}
+}
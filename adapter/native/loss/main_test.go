@@ -0,0 +1,78 @@
+package native_loss
+
+import (
+	"allora_offchain_node/lib"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLossFunction(t *testing.T) {
+	adapter := NewAlloraAdapter()
+	reputer := lib.ReputerConfig{}
+
+	tests := []struct {
+		name        string
+		groundTruth string
+		value       string
+		options     map[string]string
+		expected    string
+		expectedErr string
+	}{
+		{name: "squared error", groundTruth: "10", value: "7.5", options: map[string]string{"loss_method": "sqe"}, expected: "6.25"},
+		{name: "value equal to the ground truth", groundTruth: "10", value: "10", options: map[string]string{"loss_method": "abs"}, expected: "0"},
+		{name: "invalid ground truth", groundTruth: "ten", value: "10", options: map[string]string{"loss_method": "sqe"}, expectedErr: `invalid ground truth "ten"`},
+		{name: "invalid value", groundTruth: "10", value: "1,5", options: map[string]string{"loss_method": "sqe"}, expectedErr: `invalid value "1,5"`},
+		{name: "unknown loss method", groundTruth: "10", value: "10", options: map[string]string{"loss_method": "mse"}, expectedErr: `unknown loss_method "mse"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loss, err := adapter.LossFunction(context.Background(), reputer, tt.groundTruth, tt.value, tt.options)
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, loss)
+		})
+	}
+}
+
+func TestBatchLossFunction(t *testing.T) {
+	adapter := NewAlloraAdapter()
+	reputer := lib.ReputerConfig{}
+	options := map[string]string{"loss_method": "sqe"}
+	values := []string{"9", "10", "12.5"}
+
+	losses, err := adapter.BatchLossFunction(context.Background(), reputer, "10", values, options)
+	require.NoError(t, err)
+	require.Len(t, losses, len(values))
+	for i, value := range values {
+		loss, err := adapter.LossFunction(context.Background(), reputer, "10", value, options)
+		require.NoError(t, err)
+		require.Equal(t, loss, losses[i], "loss of value %d", i)
+	}
+
+	_, err = adapter.BatchLossFunction(context.Background(), reputer, "10", []string{"9", "nine"}, options)
+	require.ErrorContains(t, err, `invalid value 1 "nine"`)
+
+	_, err = adapter.BatchLossFunction(context.Background(), reputer, "0", []string{"1"}, map[string]string{"loss_method": "percentage"})
+	require.EqualError(t, err, "value 0: percentage loss: ground truth must not be 0")
+
+	losses, err = adapter.BatchLossFunction(context.Background(), reputer, "10", nil, options)
+	require.NoError(t, err)
+	require.Empty(t, losses)
+}
+
+func TestIsLossFunctionNeverNegative(t *testing.T) {
+	adapter := NewAlloraAdapter()
+
+	neverNegative, err := adapter.IsLossFunctionNeverNegative(context.Background(), lib.ReputerConfig{}, map[string]string{"loss_method": "logcosh"})
+	require.NoError(t, err)
+	require.True(t, neverNegative)
+
+	_, err = adapter.IsLossFunctionNeverNegative(context.Background(), lib.ReputerConfig{}, map[string]string{})
+	require.ErrorContains(t, err, "loss_method is required")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	api_worker_reputer "allora_offchain_node/adapter/api/worker-reputer"
+	native_loss "allora_offchain_node/adapter/native/loss"
 	lib "allora_offchain_node/lib"
 	"fmt"
 )
@@ -10,6 +11,8 @@ func NewAlloraAdapter(name string) (lib.AlloraAdapter, error) {
 	switch name {
 	case "api-worker-reputer":
 		return api_worker_reputer.NewAlloraAdapter(), nil
+	case lib.NATIVE_LOSS_ADAPTER_NAME:
+		return native_loss.NewAlloraAdapter(), nil
 	// Add other cases for different adapters here
 	default:
 		return nil, fmt.Errorf("unknown adapter name: %s", name)
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"allora_offchain_node/lib"
+	usecase "allora_offchain_node/usecase"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	cosmossdk_io_math "cosmossdk.io/math"
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+const ADMIN_USAGE = `Usage: allora_offchain_node [command] [flags]
+
+Without a command, runs the workers and reputers of the config.
+
+Commands, acting on the accounts of the workers and reputers of the config:
+  register      register the actors in their topics, and stake reputers up to their minStake
+  unregister    remove the registration of the actors in their topics, requires -topic
+  add-stake     add -amount to the stake of the reputers, requires -topic
+  remove-stake  start the removal of -amount from the stake of the reputers, requires -topic
+  balance       print the balances of the accounts
+  status        print the registration, stake and unfulfilled nonces of the actors per topic
+  run-once      build and sign the payload of the actor of -topic for the nonce at -height once, and print it.
+                Submitted only with -submit. Requires -role if the topic has both a worker and a reputer
+
+Flags:
+`
+
+// Options of an admin command
+type adminOptions struct {
+	command string
+	topicId emissionstypes.TopicId
+	role    lib.ActorRole
+	amount  cosmossdk_io_math.Int
+	height  lib.BlockHeight
+	submit  bool
+}
+
+// Commands which change the registration or stake of the actors, and so must name a topic
+var topicRequiredCommands = map[string]bool{"unregister": true, "add-stake": true, "remove-stake": true, "run-once": true}
+
+var adminCommands = map[string]bool{"register": true, "unregister": true, "add-stake": true, "remove-stake": true, "balance": true, "status": true, "run-once": true}
+
+func parseAdminOptions(args []string, output io.Writer) (adminOptions, error) {
+	flags := flag.NewFlagSet("allora_offchain_node", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.Usage = func() {
+		fmt.Fprint(output, ADMIN_USAGE)
+		flags.PrintDefaults()
+	}
+	topicId := flags.Uint64("topic", 0, "topic of the actors, all the topics of the config if 0")
+	role := flags.String("role", "", "role of the actors, worker or reputer, both if empty")
+	amount := flags.String("amount", "", "amount of stake to add or remove, in uallo")
+	height := flags.Int64("height", 0, "run-once: block height of the nonce, the latest open nonce if 0")
+	submit := flags.Bool("submit", false, "run-once: submit the payload to the chain")
+
+	switch {
+	case len(args) == 0 || args[0] == "help" || strings.HasPrefix(args[0], "-"):
+		flags.Usage()
+		return adminOptions{}, flag.ErrHelp
+	case !adminCommands[args[0]]:
+		flags.Usage()
+		return adminOptions{}, fmt.Errorf("unknown command %q", args[0])
+	}
+	options := adminOptions{command: args[0]}
+	if err := flags.Parse(args[1:]); err != nil {
+		return adminOptions{}, err
+	}
+	if flags.NArg() > 0 {
+		return adminOptions{}, fmt.Errorf("unexpected arguments %q", flags.Args())
+	}
+
+	options.topicId = *topicId
+	options.role = lib.ActorRole(*role)
+	if options.role != "" && options.role != lib.ROLE_WORKER && options.role != lib.ROLE_REPUTER {
+		return adminOptions{}, fmt.Errorf("invalid role %q, expected %s or %s", *role, lib.ROLE_WORKER, lib.ROLE_REPUTER)
+	}
+	if topicRequiredCommands[options.command] && options.topicId == 0 {
+		return adminOptions{}, fmt.Errorf("%s requires -topic", options.command)
+	}
+	switch options.command {
+	case "add-stake", "remove-stake":
+		if options.role == lib.ROLE_WORKER {
+			return adminOptions{}, fmt.Errorf("%s applies to reputers only", options.command)
+		}
+		options.role = lib.ROLE_REPUTER
+		parsed, ok := cosmossdk_io_math.NewIntFromString(*amount)
+		if !ok || !parsed.IsPositive() {
+			return adminOptions{}, fmt.Errorf("%s requires a positive -amount, got %q", options.command, *amount)
+		}
+		options.amount = parsed
+	default:
+		if *amount != "" {
+			return adminOptions{}, fmt.Errorf("-amount only applies to add-stake and remove-stake")
+		}
+	}
+	if options.command != "run-once" && (*height != 0 || *submit) {
+		return adminOptions{}, fmt.Errorf("-height and -submit only apply to run-once")
+	}
+	if *height < 0 {
+		return adminOptions{}, fmt.Errorf("-height must not be negative, got %d", *height)
+	}
+	options.height = *height
+	options.submit = *submit
+	return options, nil
+}
+
+// Runs the admin command of the args on the accounts of the config, printing its output to stdout
+func RunAdminCommand(ctx context.Context, args []string) error {
+	options, err := parseAdminOptions(args, os.Stderr)
+	if err != nil {
+		return err
+	}
+	userConfig, err := LoadUserConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if options.command == "run-once" {
+		// Submits only with -submit, and the keyring is checked for it on loading
+		userConfig.Wallet.SubmitTx = options.submit
+	}
+	suite, err := usecase.NewAdminSuite(userConfig)
+	if err != nil {
+		return err
+	}
+	defer suite.Close()
+
+	if options.command == "run-once" {
+		payload, err := suite.RunOnce(ctx, options.role, options.topicId, options.height, options.submit)
+		if payload != nil {
+			printSignedPayload(os.Stdout, payload, options.submit && err == nil)
+		}
+		return err
+	}
+	if options.command == "balance" {
+		balances, err := suite.Balances(ctx)
+		printBalances(os.Stdout, balances)
+		return err
+	}
+	targets := suite.AdminTargets(options.role, options.topicId)
+	if len(targets) == 0 {
+		return errors.New("no worker or reputer of the config matches the topic and role")
+	}
+	switch options.command {
+	case "register":
+		err = suite.RegisterTargets(ctx, targets)
+	case "unregister":
+		err = suite.UnregisterTargets(ctx, targets)
+	case "add-stake":
+		err = suite.AddStakeToTargets(ctx, targets, options.amount)
+	case "remove-stake":
+		err = suite.RemoveStakeFromTargets(ctx, targets, options.amount)
+	}
+	if err != nil {
+		return err
+	}
+	// Every command ends with the resulting status of its targets
+	statuses, err := suite.TargetStatuses(ctx, targets)
+	printStatuses(os.Stdout, statuses)
+	return err
+}
+
+func printBalances(output io.Writer, balances []usecase.AccountBalance) {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tADDRESS\tBALANCE")
+	for _, balance := range balances {
+		fmt.Fprintf(w, "%s\t%s\t%s%s\n", balance.KeyName, balance.Address, balance.Balance, lib.DEFAULT_BOND_DENOM)
+	}
+	w.Flush()
+}
+
+func printStatuses(output io.Writer, statuses []lib.ActorStatus) {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ROLE\tTOPIC\tADDRESS\tREGISTERED\tSTAKE\tPENDING REMOVAL\tUNFULFILLED NONCES")
+	for _, status := range statuses {
+		stake, removal := "-", "-"
+		if status.Stake != nil {
+			stake = status.Stake.String() + lib.DEFAULT_BOND_DENOM
+		}
+		if status.StakeRemoval != nil {
+			removal = fmt.Sprintf("%s%s at block %d", status.StakeRemoval.Amount, lib.DEFAULT_BOND_DENOM, status.StakeRemoval.BlockRemovalCompleted)
+		}
+		nonces := make([]string, len(status.OpenNonces))
+		for i, nonce := range status.OpenNonces {
+			nonces[i] = fmt.Sprint(nonce)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%t\t%s\t%s\t%s\n", status.Role, status.TopicId, status.Address, status.Registered, stake, removal, strings.Join(nonces, ","))
+	}
+	w.Flush()
+}
+
+func printSignedPayload(output io.Writer, payload *usecase.SignedPayload, submitted bool) {
+	bundle, err := json.MarshalIndent(payload.Bundle, "", "  ")
+	if err != nil {
+		bundle = []byte(err.Error())
+	}
+	var request bytes.Buffer
+	if err := json.Indent(&request, payload.RequestJSON, "", "  "); err != nil {
+		request.WriteString(err.Error())
+	}
+	fmt.Fprintf(output, "%s payload of topic %d for nonce %d\n\n", payload.Role, payload.TopicId, payload.Nonce)
+	fmt.Fprintf(output, "Bundle:\n%s\n\n", bundle)
+	fmt.Fprintf(output, "Signature: %s\nPubkey: %s\n\n", hex.EncodeToString(payload.Signature), payload.Pubkey)
+	fmt.Fprintf(output, "Msg %s:\n%s\n\n", sdktypes.MsgTypeURL(payload.Request), request.String())
+	if submitted {
+		fmt.Fprintln(output, "Submitted")
+	} else {
+		fmt.Fprintln(output, "Not submitted, run with -submit to submit it")
+	}
+}
@@ -2,11 +2,14 @@
 
 set -e
 
-if allorad keys --home=/data/.allorad --keyring-backend test show $NAME > /dev/null 2>&1 ; then
+# Same keyring backend as the node, the file backend prompting for the keyring passphrase
+keyringBackend=$(echo "$ALLORA_OFFCHAIN_NODE_CONFIG_JSON" | jq -r '.wallet.keyringBackend // "test"')
+
+if allorad keys --home=/data/.allorad --keyring-backend $keyringBackend show $NAME > /dev/null 2>&1 ; then
     echo "allora account: $NAME already imported"
 else
     echo "creating allora account: $NAME"
-    output=$(allorad keys add $NAME --home=/data/.allorad --keyring-backend test 2>&1)
+    output=$(allorad keys add $NAME --home=/data/.allorad --keyring-backend $keyringBackend 2>&1)
     address=$(echo "$output" | grep 'address:' | sed 's/.*address: //')
     mnemonic=$(echo "$output" | tail -n 1)
 
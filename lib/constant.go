@@ -1,10 +1,11 @@
 package lib
 
-const SECONDS_PER_BLOCK = 5   // each block is this many seconds
+const SECONDS_PER_BLOCK = 5   // expected block time, until the actual block time is measured
 const ADDRESS_PREFIX = "allo" // each address prefixed by this
 const DEFAULT_BOND_DENOM = "uallo"
 const ALLORA_OFFCHAIN_NODE_CONFIG_JSON = "ALLORA_OFFCHAIN_NODE_CONFIG_JSON"
 const ALLORA_OFFCHAIN_NODE_CONFIG_FILE_PATH = "ALLORA_OFFCHAIN_NODE_CONFIG_FILE_PATH"
+const SHUTDOWN_TIMEOUT_SECONDS = 10 // time given to the metrics server to finish serving its requests on shutdown
 
 const (
 	InferenceRequestCount       string = "allora_worker_inference_request_count"
@@ -14,6 +15,9 @@ const (
 	ReputerDataBuildCount       string = "allora_reputer_data_build_count"
 	WorkerChainSubmissionCount  string = "allora_worker_chain_submission_count"
 	ReputerChainSubmissionCount string = "allora_reputer_chain_submission_count"
+	TxIncludedCount             string = "allora_tx_included_count"
+	TxFailedAfterInclusionCount string = "allora_tx_failed_after_inclusion_count"
+	TxUnconfirmedCount          string = "allora_tx_unconfirmed_count"
 )
 
 // A struct that holds the name and help text for a prometheus counter
@@ -25,4 +29,16 @@ var COUNTER_DATA = []MetricsCounter{
 	{ReputerDataBuildCount, "The total number of times worker built data successfully"},
 	{WorkerChainSubmissionCount, "The total number of worker commits to the chain"},
 	{ReputerChainSubmissionCount, "The total number of reputer commits to the chain"},
+	{TxIncludedCount, "The total number of worker and reputer txs included in a block with success"},
+	{TxFailedAfterInclusionCount, "The total number of worker and reputer txs included in a block with an error code"},
+	{TxUnconfirmedCount, "The total number of worker and reputer txs broadcast but not found in a block in time"},
 }
+
+// Adapter computing the losses of reputers in-process with the native loss functions, without a LossFunctionService
+const NATIVE_LOSS_ADAPTER_NAME = "native-loss"
+const LOSS_METHOD_OPTION = "loss_method" // option of LossMethodOptions naming the loss function
+const HUBER_DELTA_OPTION = "delta"       // option of LossMethodOptions with the threshold of the huber loss, 1 by default
+const DEFAULT_LOSS_CONCURRENCY = 8       // losses of a bundle computed at once, unless set in LossFunctionParameters
+
+// Never negative loss of 0, as of a perfect value, replaced by this before its log10
+const MIN_NEVER_NEGATIVE_LOSS = "1e-18"
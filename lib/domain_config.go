@@ -1,37 +1,154 @@
 package lib
 
 import (
+	"os"
+	"path/filepath"
+
 	emissions "github.com/allora-network/allora-chain/x/emissions/types"
 	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
-	"github.com/ignite/cli/v28/ignite/pkg/cosmosaccount"
 	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
-	"github.com/rs/zerolog/log"
 )
 
 // Properties manually provided by the user as part of UserConfig
 type WalletConfig struct {
-	Address                   string // will be overwritten by the keystore. This is the 1 value that is auto-generated in this struct
-	AddressKeyName            string // load a address by key from the keystore
-	AddressRestoreMnemonic    string
-	AlloraHomeDir             string  // home directory for the allora keystore
-	Gas                       string  // gas to use for the allora client
-	GasAdjustment             float64 // gas adjustment to use for the allora client
-	NodeRpc                   string  // rpc node for allora chain
-	MaxRetries                int64   // retry to get data from chain up to this many times per query or tx
-	RetryDelay                int64   // number of seconds to wait between retries (general case)
-	AccountSequenceRetryDelay int64   // number of seconds to wait between retries in case of account sequence error
-	SubmitTx                  bool    // useful for dev/testing. set to false to run in dry-run processes without committing to the chain
+	Address                      string                   // will be overwritten by the keystore. This is the 1 value that is auto-generated in this struct
+	AddressKeyName               string                   // load a address by key from the keystore
+	AddressRestoreMnemonic       Secret                   // mnemonic to restore the key from, redacted when logged or marshalled
+	AddressRestoreMnemonicFile   string                   // file of the mnemonic, instead of AddressRestoreMnemonic. Must not be accessible by other users
+	AddressRestoreMnemonicSecret *SecretConfig            // secret provider of the mnemonic, instead of AddressRestoreMnemonic
+	AlloraHomeDir                string                   // home directory for the allora keystore
+	KeyringBackend               string                   // "test" (default, unencrypted), "file" or "os"
+	KeyringPassphrase            *SecretConfig            // source of the passphrase of the file and os keyring backends, see SecretConfig
+	AllowTestKeyring             bool                     // allow submitting txs with the test keyring backend on a chain whose id isn't a test one
+	RemoteSigner                 *RemoteSignerConfig      // sign with a key held by a remote signing service instead of the keyring, see RemoteSigner
+	Gas                          string                   // gas to use for the allora client. A fixed amount is per msg in batched txs
+	GasAdjustment                float64                  // gas adjustment to use for the allora client
+	NodeRpc                      string                   // rpc node for allora chain
+	NodeWebsocket                string                   // websocket endpoint of the rpc node. If empty, derived from NodeRpc
+	SubscribeToEvents            bool                     // react to new blocks over the node websocket instead of polling every LoopSeconds. Falls back to polling while the subscription is down
+	MaxRetries                   int64                    // retry to get data from chain up to this many times per query or tx
+	RetryDelay                   int64                    // number of seconds to wait between retries (general case)
+	AccountSequenceRetryDelay    int64                    // number of seconds to wait before retrying after repeated account sequence errors. The first one is retried right away after resyncing
+	TxBatchWindowMillis          int64                    // collect the msgs of the account's actors submitted within this many milliseconds into a single tx. 0 sends every msg in its own tx
+	TxBatchResubmitOthers        bool                     // when a msg fails a batch tx, resubmit the other msgs without it instead of failing them too
+	TxConfirmationTimeoutSeconds int64                    // how long to wait for a broadcast tx to be included in a block. Defaults to DEFAULT_TX_CONFIRMATION_TIMEOUT_SECONDS
+	Fees                         FeeConfig                // fee policy of the txs, no fees by default
+	TxErrorPolicies              map[string]TxErrorPolicy // overrides the policy of chain errors by "codespace:code", e.g. {"emissions:67": "fatal"}
+	LedgerPath                   string                   // file of the submission ledger. Defaults to LEDGER_FILE_NAME in AlloraHomeDir
+	GroundTruthCacheSeconds      int64                    // share the ground truths fetched by the reputers, by source and block height, for this many seconds. Stored in the submission ledger. 0 disables the cache
+	SubmitTx                     bool                     // useful for dev/testing. set to false to run in dry-run processes without committing to the chain
+}
+
+// Fee policy of the txs of each account. Amounts are coins, e.g. "10uallo"
+type FeeConfig struct {
+	GasPrice         string  // fixed gas price
+	QueryMinGasPrice bool    // use the node's minimum gas price instead of GasPrice when higher
+	EscalationFactor float64 // multiplies the gas price after each insufficient fee or mempool full error, until a tx goes through. 0 or 1 disables escalation
+	MaxFeePerTx      string  // the escalated fee is capped to this amount
+	MaxFeePerDay     string  // fees paid per UTC day, beyond which txs are not sent
+}
+
+// Source of a secret, e.g. the mnemonic of a wallet, by provider
+type SecretConfig struct {
+	Provider  string // "file", "env" or "vault"
+	Path      string // file: path of the file. vault: path of the secret after /v1/, e.g. "secret/data/allora" for a KV v2 engine mounted at "secret"
+	EnvVar    string // env: name of the environment variable
+	Address   string // vault: URL of the server, e.g. "https://vault:8200"
+	Key       string // vault: key of the secret in the data of the secret. Defaults to DEFAULT_VAULT_SECRET_KEY
+	TokenFile string // vault: file of the token. Defaults to the VAULT_TOKEN environment variable
+}
+
+// Remote signing service holding the key of the wallet
+type RemoteSignerConfig struct {
+	Url     string        // base URL of the service, e.g. "https://signer:9090"
+	KeyName string        // name of the key on the service. Defaults to AddressKeyName
+	Token   *SecretConfig // source of the bearer token of the service, optional
+}
+
+// Home directory of the allora keystore, ~/.allorad unless AlloraHomeDir is set
+func (wallet WalletConfig) HomeDir() string {
+	if wallet.AlloraHomeDir != "" {
+		return wallet.AlloraHomeDir
+	}
+	userHomeDir, _ := os.UserHomeDir()
+	return filepath.Join(userHomeDir, ".allorad")
+}
+
+// Returns the wallet with the fields set in the override replacing its own, for actors with their own wallet.
+// The key name, the source of the mnemonic and the remote signer are overridden together, so that an actor never restores the node's mnemonic,
+// or signs with the node's key, under another name.
+// The fee policy and the tx error policies are overridden as a whole.
+// Fields relating to the whole node rather than to an account (websocket, events, ledger, ground truth cache, SubmitTx) are always inherited.
+func (wallet WalletConfig) Override(override *WalletConfig) WalletConfig {
+	if override == nil {
+		return wallet
+	}
+	merged := wallet
+	if override.AddressKeyName != "" {
+		merged.Address = ""
+		merged.AddressKeyName = override.AddressKeyName
+		merged.AddressRestoreMnemonic = override.AddressRestoreMnemonic
+		merged.AddressRestoreMnemonicFile = override.AddressRestoreMnemonicFile
+		merged.AddressRestoreMnemonicSecret = override.AddressRestoreMnemonicSecret
+		merged.RemoteSigner = override.RemoteSigner
+	}
+	if override.AlloraHomeDir != "" {
+		merged.AlloraHomeDir = override.AlloraHomeDir
+	}
+	if override.KeyringBackend != "" {
+		merged.KeyringBackend = override.KeyringBackend
+		merged.KeyringPassphrase = override.KeyringPassphrase
+	}
+	if override.Gas != "" {
+		merged.Gas = override.Gas
+	}
+	if override.GasAdjustment != 0 {
+		merged.GasAdjustment = override.GasAdjustment
+	}
+	if override.NodeRpc != "" {
+		merged.NodeRpc = override.NodeRpc
+	}
+	if override.MaxRetries != 0 {
+		merged.MaxRetries = override.MaxRetries
+	}
+	if override.RetryDelay != 0 {
+		merged.RetryDelay = override.RetryDelay
+	}
+	if override.AccountSequenceRetryDelay != 0 {
+		merged.AccountSequenceRetryDelay = override.AccountSequenceRetryDelay
+	}
+	if override.TxBatchWindowMillis != 0 {
+		merged.TxBatchWindowMillis = override.TxBatchWindowMillis
+	}
+	if override.TxBatchResubmitOthers {
+		merged.TxBatchResubmitOthers = override.TxBatchResubmitOthers
+	}
+	if override.TxConfirmationTimeoutSeconds != 0 {
+		merged.TxConfirmationTimeoutSeconds = override.TxConfirmationTimeoutSeconds
+	}
+	if override.Fees != (FeeConfig{}) {
+		merged.Fees = override.Fees
+	}
+	if override.TxErrorPolicies != nil {
+		merged.TxErrorPolicies = override.TxErrorPolicies
+	}
+	return merged
 }
 
 // Properties auto-generated based on what the user has provided in WalletConfig fields of UserConfig
 type ChainConfig struct {
 	Address              string // will be auto-generated based on the keystore
-	Account              cosmosaccount.Account
+	Signer               Signer // signs the bundles and txs of the account
 	Client               *cosmosclient.Client
+	Sequencer            *AccountSequencer // hands out the account's sequences to the node's txs
+	Batcher              *TxBatcher        // batches the msgs of the account's actors, nil if batching is disabled
+	TxResults            *TxResultLog      // results of the account's recent txs included in a block
+	Fees                 *FeeManager       // prices the fees of the account's txs
 	EmissionsQueryClient emissions.QueryServiceClient
 	BankQueryClient      bank.QueryClient
 	DefaultBondDenom     string
 	AddressPrefix        string // prefix for the allora addresses
+	ChainId              string // id of the chain the client is connected to
 }
 
 type WorkerConfig struct {
@@ -42,6 +159,7 @@ type WorkerConfig struct {
 	ForecastEntrypoint      AlloraAdapter
 	LoopSeconds             int64             // seconds to wait between attempts to get next worker nonce
 	Parameters              map[string]string // Map for variable configuration values
+	Wallet                  *WalletConfig     // optional wallet to act with instead of the node's, see WalletConfig.Override
 }
 
 type ReputerConfig struct {
@@ -58,14 +176,24 @@ type ReputerConfig struct {
 	LoopSeconds            int64                  // seconds to wait between attempts to get next reptuer nonces
 	GroundTruthParameters  map[string]string      // Map for variable configuration values
 	LossFunctionParameters LossFunctionParameters // Map for variable configuration values
+	Wallet                 *WalletConfig          // optional wallet to act with instead of the node's, see WalletConfig.Override
 }
 
 type LossFunctionParameters struct {
 	LossFunctionService string
 	LossMethodOptions   map[string]string
+	MaxConcurrency      int   // maximum number of losses of a bundle computed at once, DEFAULT_LOSS_CONCURRENCY if 0
 	IsNeverNegative     *bool // Cached result of whether the loss function is never negative
 }
 
+// Maximum number of losses of a bundle computed at once
+func (parameters LossFunctionParameters) Concurrency() int {
+	if parameters.MaxConcurrency <= 0 {
+		return DEFAULT_LOSS_CONCURRENCY
+	}
+	return parameters.MaxConcurrency
+}
+
 type UserConfig struct {
 	Wallet  WalletConfig
 	Worker  []WorkerConfig
@@ -101,21 +229,40 @@ type ValueBundle struct {
 	OneInForecasterValues  []NodeValue `json:"oneInForecasterValues,omitempty"`
 }
 
-// Check that each assigned entrypoint in the user config actually can be used
-// for the intended purpose, else throw error
-func (c *UserConfig) ValidateConfigAdapters() {
-	for _, workerConfig := range c.Worker {
-		if workerConfig.InferenceEntrypoint != nil && !workerConfig.InferenceEntrypoint.CanInfer() {
-			log.Fatal().Interface("entrypoint", workerConfig.InferenceEntrypoint).Msg("Invalid inference entrypoint")
-		}
-		if workerConfig.ForecastEntrypoint != nil && !workerConfig.ForecastEntrypoint.CanForecast() {
-			log.Fatal().Interface("entrypoint", workerConfig.ForecastEntrypoint).Msg("Invalid forecast entrypoint")
+// An actor of the same role, topic and wallet as an actor before it in the config
+type duplicateActor struct {
+	role       ActorRole
+	index      int
+	firstIndex int
+	topicId    emissions.TopicId
+	keyName    string
+}
+
+func (c *UserConfig) duplicateActors() []duplicateActor {
+	type actorKey struct {
+		topicId emissions.TopicId
+		keyName string
+	}
+	duplicates := []duplicateActor{}
+
+	workers := make(map[actorKey]int)
+	for i, worker := range c.Worker {
+		key := actorKey{worker.TopicId, c.Wallet.Override(worker.Wallet).AddressKeyName}
+		if first, ok := workers[key]; ok {
+			duplicates = append(duplicates, duplicateActor{ROLE_WORKER, i, first, key.topicId, key.keyName})
+			continue
 		}
+		workers[key] = i
 	}
 
-	for _, reputerConfig := range c.Reputer {
-		if reputerConfig.GroundTruthEntrypoint != nil && !reputerConfig.GroundTruthEntrypoint.CanSourceGroundTruthAndComputeLoss() {
-			log.Fatal().Interface("entrypoint", reputerConfig.GroundTruthEntrypoint).Msg("Invalid loss entrypoint")
+	reputers := make(map[actorKey]int)
+	for i, reputer := range c.Reputer {
+		key := actorKey{reputer.TopicId, c.Wallet.Override(reputer.Wallet).AddressKeyName}
+		if first, ok := reputers[key]; ok {
+			duplicates = append(duplicates, duplicateActor{ROLE_REPUTER, i, first, key.topicId, key.keyName})
+			continue
 		}
+		reputers[key] = i
 	}
+	return duplicates
 }
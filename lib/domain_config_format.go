@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Format of a config file, chosen by its extension
+type ConfigFormat string
+
+const (
+	CONFIG_FORMAT_JSON ConfigFormat = "json"
+	CONFIG_FORMAT_YAML ConfigFormat = "yaml"
+	CONFIG_FORMAT_TOML ConfigFormat = "toml"
+)
+
+// "${VAR}" or "${VAR:-default}", or "$$" escaping a literal "$"
+var envVarRegex = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+func ConfigFormatOf(path string) (ConfigFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return CONFIG_FORMAT_JSON, nil
+	case ".yaml", ".yml":
+		return CONFIG_FORMAT_YAML, nil
+	case ".toml":
+		return CONFIG_FORMAT_TOML, nil
+	default:
+		return "", fmt.Errorf("unsupported config file extension %q, expected .json, .yaml, .yml or .toml", filepath.Ext(path))
+	}
+}
+
+// Reads a config file in the format of its extension, see ParseUserConfigAs
+func LoadUserConfigFile(path string) (UserConfig, error) {
+	format, err := ConfigFormatOf(path)
+	if err != nil {
+		return UserConfig{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return UserConfig{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return ParseUserConfigAs(data, format)
+}
+
+// Decodes a JSON config, see ParseUserConfigAs
+func ParseUserConfig(data []byte) (UserConfig, error) {
+	return ParseUserConfigAs(data, CONFIG_FORMAT_JSON)
+}
+
+// Decodes a config in the format, substituting the environment variables referenced by its string values,
+// rejecting fields which aren't part of it, e.g. misspelt ones, and validates it.
+// Every problem is reported at once, as ConfigErrors.
+func ParseUserConfigAs(data []byte, format ConfigFormat) (UserConfig, error) {
+	var raw any
+	var err error
+	switch format {
+	case CONFIG_FORMAT_JSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber() // re-encoded as is, without rounding large amounts to float64
+		err = decoder.Decode(&raw)
+	case CONFIG_FORMAT_YAML:
+		err = yaml.Unmarshal(data, &raw)
+	case CONFIG_FORMAT_TOML:
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return UserConfig{}, fmt.Errorf("unsupported config format %q", format)
+	}
+	if err != nil {
+		return UserConfig{}, fmt.Errorf("invalid %s config: %w", strings.ToUpper(string(format)), err)
+	}
+	errs := ConfigErrors{}
+	raw = interpolateEnv(raw, "", &errs)
+	return decodeUserConfig(raw, errs)
+}
+
+// Substitutes the environment variables referenced by the string values, recursively.
+// Maps with non-string keys, as YAML allows, are converted to string keys for the value to be encoded as JSON.
+func interpolateEnv(value any, path string, errs *ConfigErrors) any {
+	switch value := value.(type) {
+	case string:
+		return expandEnv(value, path, errs)
+	case map[string]any:
+		for _, key := range sortedKeys(value) {
+			value[key] = interpolateEnv(value[key], joinPath(path, key), errs)
+		}
+		return value
+	case map[any]any:
+		object := make(map[string]any, len(value))
+		for key, element := range value {
+			object[fmt.Sprint(key)] = element
+		}
+		return interpolateEnv(object, path, errs)
+	case []any:
+		for i, element := range value {
+			value[i] = interpolateEnv(element, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// Expands "${VAR}" and "${VAR:-default}" in the string, the default being used when the variable is unset or empty.
+// An unset variable without a default is a config error, rather than silently replaced with an empty string.
+func expandEnv(value string, path string, errs *ConfigErrors) string {
+	return envVarRegex.ReplaceAllStringFunc(value, func(reference string) string {
+		if reference == "$$" {
+			return "$"
+		}
+		match := envVarRegex.FindStringSubmatch(reference)
+		name, hasDefault, defaultValue := match[1], match[2] != "", match[3]
+		if envValue, ok := os.LookupEnv(name); ok && (envValue != "" || !hasDefault) {
+			return envValue
+		}
+		if hasDefault {
+			return defaultValue
+		}
+		errs.add(path, "environment variable %s is not set, set it or give a default with ${%s:-default}", name, name)
+		return ""
+	})
+}
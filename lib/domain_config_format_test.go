@@ -0,0 +1,187 @@
+package lib
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFormatOf(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected ConfigFormat
+		err      string
+	}{
+		{path: "config.json", expected: CONFIG_FORMAT_JSON},
+		{path: "/etc/allora/config.YAML", expected: CONFIG_FORMAT_YAML},
+		{path: "config.yml", expected: CONFIG_FORMAT_YAML},
+		{path: "config.toml", expected: CONFIG_FORMAT_TOML},
+		{path: "config.ini", err: `unsupported config file extension ".ini"`},
+		{path: "config", err: `unsupported config file extension ""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			format, err := ConfigFormatOf(tt.path)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+}
+
+func TestParseUserConfigFormats(t *testing.T) {
+	yamlConfig := `
+wallet:
+  addressKeyName: node
+  nodeRpc: http://localhost:26657
+  maxRetries: 3
+  retryDelay: 2
+worker:
+  - topicId: 1
+    inferenceEntrypointName: api-worker-reputer
+    loopSeconds: 5
+    parameters:
+      InferenceEndpoint: http://source:8000/inference/{Token}
+      Token: ETH
+reputer:
+  - topicId: 1
+    groundTruthEntrypointName: api-worker-reputer
+    lossFunctionEntrypointName: api-worker-reputer
+    loopSeconds: 30
+    minStake: 100
+    groundTruthParameters:
+      GroundTruthEndpoint: http://source:8888/gt/{Token}/{BlockHeight}
+      Token: ETHUSD
+    lossFunctionParameters:
+      LossFunctionService: http://localhost:5000
+      LossMethodOptions:
+        loss_method: sqe
+`
+	tomlConfig := `
+[wallet]
+addressKeyName = "node"
+nodeRpc = "http://localhost:26657"
+maxRetries = 3
+retryDelay = 2
+
+[[worker]]
+topicId = 1
+inferenceEntrypointName = "api-worker-reputer"
+loopSeconds = 5
+parameters = { InferenceEndpoint = "http://source:8000/inference/{Token}", Token = "ETH" }
+
+[[reputer]]
+topicId = 1
+groundTruthEntrypointName = "api-worker-reputer"
+lossFunctionEntrypointName = "api-worker-reputer"
+loopSeconds = 30
+minStake = 100
+groundTruthParameters = { GroundTruthEndpoint = "http://source:8888/gt/{Token}/{BlockHeight}", Token = "ETHUSD" }
+lossFunctionParameters = { LossFunctionService = "http://localhost:5000", LossMethodOptions = { loss_method = "sqe" } }
+`
+	expected, err := ParseUserConfig([]byte(validConfigJSON))
+	require.NoError(t, err)
+
+	config, err := ParseUserConfigAs([]byte(yamlConfig), CONFIG_FORMAT_YAML)
+	require.NoError(t, err)
+	assert.Equal(t, expected, config, "YAML")
+
+	config, err = ParseUserConfigAs([]byte(tomlConfig), CONFIG_FORMAT_TOML)
+	require.NoError(t, err)
+	assert.Equal(t, expected, config, "TOML")
+}
+
+func TestParseUserConfigFormatErrors(t *testing.T) {
+	_, err := ParseUserConfigAs([]byte("wallet: [unclosed"), CONFIG_FORMAT_YAML)
+	assert.ErrorContains(t, err, "invalid YAML config")
+
+	_, err = ParseUserConfigAs([]byte("[wallet"), CONFIG_FORMAT_TOML)
+	assert.ErrorContains(t, err, "invalid TOML config")
+
+	// Validation problems are reported with their paths whatever the format
+	_, err = ParseUserConfigAs([]byte("wallet:\n  addressKeyName: node\n  nodeRpc: http://localhost:26657\n  retryDelays: 2\n"), CONFIG_FORMAT_YAML)
+	var configErrs ConfigErrors
+	require.True(t, errors.As(err, &configErrs))
+	assert.Equal(t, ConfigErrors{{"wallet.retryDelays", `unknown field, did you mean "retryDelay"?`}}, configErrs)
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("CONFIG_TEST_TOKEN", "ETH")
+	t.Setenv("CONFIG_TEST_EMPTY", "")
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		err      string
+	}{
+		{name: "no reference", value: "http://source/{Token}", expected: "http://source/{Token}"},
+		{name: "set variable", value: "${CONFIG_TEST_TOKEN}", expected: "ETH"},
+		{name: "several references", value: "http://source/${CONFIG_TEST_TOKEN}/${CONFIG_TEST_TOKEN}", expected: "http://source/ETH/ETH"},
+		{name: "default unused", value: "${CONFIG_TEST_TOKEN:-BTC}", expected: "ETH"},
+		{name: "default of an unset variable", value: "${CONFIG_TEST_UNSET:-BTC}", expected: "BTC"},
+		{name: "default of an empty variable", value: "${CONFIG_TEST_EMPTY:-BTC}", expected: "BTC"},
+		{name: "empty default", value: "${CONFIG_TEST_UNSET:-}", expected: ""},
+		{name: "empty variable without default", value: "${CONFIG_TEST_EMPTY}", expected: ""},
+		{name: "escaped reference", value: "$${CONFIG_TEST_TOKEN}", expected: "${CONFIG_TEST_TOKEN}"},
+		{name: "unset variable", value: "${CONFIG_TEST_UNSET}", err: "environment variable CONFIG_TEST_UNSET is not set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ConfigErrors{}
+			value := expandEnv(tt.value, "wallet.nodeRpc", &errs)
+			if tt.err != "" {
+				require.Len(t, errs, 1)
+				assert.Equal(t, "wallet.nodeRpc", errs[0].Path)
+				assert.Contains(t, errs[0].Message, tt.err)
+				return
+			}
+			assert.Empty(t, errs)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestParseUserConfigInterpolation(t *testing.T) {
+	t.Setenv("CONFIG_TEST_MNEMONIC", "word word word")
+	config := `{
+		"wallet": {"addressKeyName": "node", "addressRestoreMnemonic": "${CONFIG_TEST_MNEMONIC}", "nodeRpc": "${CONFIG_TEST_RPC:-http://localhost:26657}"},
+		"worker": [{"topicId": 1, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5,
+			"parameters": {"InferenceEndpoint": "http://source/{Token}?apiKey=${CONFIG_TEST_API_KEY}", "Token": "ETH"}}]
+	}`
+	_, err := ParseUserConfig([]byte(config))
+	var configErrs ConfigErrors
+	require.True(t, errors.As(err, &configErrs))
+	assert.Equal(t, ConfigErrors{{
+		"worker[0].parameters.InferenceEndpoint",
+		"environment variable CONFIG_TEST_API_KEY is not set, set it or give a default with ${CONFIG_TEST_API_KEY:-default}",
+	}}, configErrs)
+
+	t.Setenv("CONFIG_TEST_API_KEY", "secret")
+	userConfig, err := ParseUserConfig([]byte(config))
+	require.NoError(t, err)
+	assert.Equal(t, "word word word", userConfig.Wallet.AddressRestoreMnemonic.Reveal())
+	assert.Equal(t, "http://localhost:26657", userConfig.Wallet.NodeRpc)
+	assert.Equal(t, "http://source/{Token}?apiKey=secret", userConfig.Worker[0].Parameters["InferenceEndpoint"])
+}
+
+func TestLoadUserConfigFileExamples(t *testing.T) {
+	t.Setenv("ALLORA_WALLET_MNEMONIC", "your mnemonic here")
+	expected, err := LoadUserConfigFile("../config.example.json")
+	require.NoError(t, err)
+	for _, path := range []string{"../config.example.yaml", "../config.example.toml"} {
+		config, err := LoadUserConfigFile(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, expected, config, path)
+	}
+
+	_, err = LoadUserConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
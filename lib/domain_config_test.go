@@ -0,0 +1,64 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalletOverride(t *testing.T) {
+	node := WalletConfig{
+		Address:                "allo1node",
+		AddressKeyName:         "node",
+		AddressRestoreMnemonic: "node mnemonic",
+		Gas:                    "auto",
+		GasAdjustment:          1.5,
+		NodeRpc:                "http://localhost:26657",
+		MaxRetries:             3,
+		RetryDelay:             2,
+		SubmitTx:               true,
+	}
+
+	assert.Equal(t, node, node.Override(nil))
+
+	// Keyring entry without mnemonic: the node's mnemonic must not be inherited
+	actor := node.Override(&WalletConfig{AddressKeyName: "challenger", MaxRetries: 5})
+	assert.Equal(t, "challenger", actor.AddressKeyName)
+	assert.Empty(t, actor.AddressRestoreMnemonic)
+	assert.Empty(t, actor.Address)
+	assert.Equal(t, int64(5), actor.MaxRetries)
+	assert.Equal(t, node.Gas, actor.Gas)
+	assert.Equal(t, node.NodeRpc, actor.NodeRpc)
+	assert.True(t, actor.SubmitTx, "SubmitTx is always inherited")
+
+	// Tx fields are per account
+	policies := map[string]TxErrorPolicy{"emissions:67": TX_ERROR_POLICY_FATAL}
+	actor = node.Override(&WalletConfig{
+		AddressKeyName:               "challenger",
+		Fees:                         FeeConfig{GasPrice: "10uallo"},
+		TxBatchWindowMillis:          500,
+		TxBatchResubmitOthers:        true,
+		TxConfirmationTimeoutSeconds: 30,
+		TxErrorPolicies:              policies,
+	})
+	assert.Equal(t, FeeConfig{GasPrice: "10uallo"}, actor.Fees)
+	assert.Equal(t, int64(500), actor.TxBatchWindowMillis)
+	assert.True(t, actor.TxBatchResubmitOthers)
+	assert.Equal(t, int64(30), actor.TxConfirmationTimeoutSeconds)
+	assert.Equal(t, policies, actor.TxErrorPolicies)
+
+	// Only non-key fields overridden: same account as the node
+	actor = node.Override(&WalletConfig{GasAdjustment: 2})
+	assert.Equal(t, "node", actor.AddressKeyName)
+	assert.Equal(t, "node mnemonic", actor.AddressRestoreMnemonic.Reveal())
+	assert.Equal(t, 2.0, actor.GasAdjustment)
+
+	// Remote signer: the node's signer is not inherited by an actor with its own key, nor the other way around
+	signer := &RemoteSignerConfig{Url: "https://signer:9090"}
+	actor = node.Override(&WalletConfig{AddressKeyName: "challenger", RemoteSigner: signer})
+	assert.Equal(t, signer, actor.RemoteSigner)
+	assert.Empty(t, actor.AddressRestoreMnemonic)
+	node.RemoteSigner = signer
+	actor = node.Override(&WalletConfig{AddressKeyName: "challenger"})
+	assert.Nil(t, actor.RemoteSigner)
+}
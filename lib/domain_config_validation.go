@@ -0,0 +1,510 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const MAX_CONFIG_RETRIES = 100               // above this, a typo is more likely than a deliberate value
+const MAX_CONFIG_DELAY_SECONDS = 60 * 60     // delays are in seconds, above an hour they were likely meant as milliseconds
+const MAX_CONFIG_BATCH_WINDOW_MILLIS = 60000 // a batch window beyond a minute would miss the submission windows
+const MAX_CONFIG_LOSS_CONCURRENCY = 256      // more concurrent requests would rather overwhelm a loss function service
+
+const MAX_CONFIG_GROUND_TRUTH_CACHE_SECONDS = 7 * 24 * 60 * 60 // reputer windows are shorter than a week
+
+// URL templates of the adapter parameters, required when the matching entrypoint is configured
+const (
+	INFERENCE_ENDPOINT_PARAMETER    = "InferenceEndpoint"
+	FORECAST_ENDPOINT_PARAMETER     = "ForecastEndpoint"
+	GROUND_TRUTH_ENDPOINT_PARAMETER = "GroundTruthEndpoint"
+)
+
+// Placeholders filled by the adapters in URL templates, besides the other parameters of the actor
+var builtinPlaceholders = []string{"BlockHeight", "TopicId"}
+
+var placeholderRegex = regexp.MustCompile(`\{(\w+)\}`)
+
+// A problem of the config, at the JSON path of the offending value, e.g. "worker[0].loopSeconds"
+type ConfigError struct {
+	Path    string
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Every problem found in a config
+type ConfigErrors []ConfigError
+
+func (errs ConfigErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = "\n  - " + err.Error()
+	}
+	return fmt.Sprintf("invalid config, %d problem(s):%s", len(errs), strings.Join(lines, ""))
+}
+
+func (errs *ConfigErrors) add(path string, format string, args ...any) {
+	*errs = append(*errs, ConfigError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Returns nil rather than an empty ConfigErrors, so that it can be returned as an error
+func (errs ConfigErrors) orNil() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Decodes a config from its generic value, rejecting fields which aren't part of it, and validates it.
+// The problems are reported along with the ones already found, as ConfigErrors.
+func decodeUserConfig(raw any, errs ConfigErrors) (UserConfig, error) {
+	// Unknown fields are removed, for the rest of the config to be validated as well
+	errs = append(errs, unknownFields(raw, reflect.TypeOf(UserConfig{}), "")...)
+	known, err := json.Marshal(raw)
+	if err != nil {
+		return UserConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+
+	config := UserConfig{}
+	if err := json.Unmarshal(known, &config); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return UserConfig{}, fmt.Errorf("invalid config: %w", err)
+		}
+		errs.add(indexedPath(typeErr.Field), "expected a %s, got a %s", typeErr.Type, typeErr.Value)
+		return UserConfig{}, errs
+	}
+	if err := config.Validate(); err != nil {
+		var configErrs ConfigErrors
+		if !errors.As(err, &configErrs) {
+			return UserConfig{}, err
+		}
+		errs = append(errs, configErrs...)
+	}
+	return config, errs.orNil()
+}
+
+// Path of a field as reported by encoding/json, e.g. "worker.0.topicId", with indices in brackets: "worker[0].topicId"
+func indexedPath(field string) string {
+	path := ""
+	for _, segment := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(segment); err == nil {
+			path += "[" + segment + "]"
+		} else {
+			path = joinPath(path, segment)
+		}
+	}
+	return path
+}
+
+// JSON name of a config field, as written in the examples
+func jsonFieldName(name string) string {
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(first)) + name[size:]
+}
+
+func joinPath(path string, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// Fields of the JSON value which the Go type doesn't have, removed from the value.
+// Field names match case-insensitively, as in encoding/json.
+func unknownFields(value any, t reflect.Type, path string) ConfigErrors {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	errs := ConfigErrors{}
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return errs
+		}
+		for _, key := range sortedKeys(object) {
+			field, ok := configField(t, key)
+			if !ok {
+				message := "unknown field"
+				if suggestion := closestConfigField(t, key); suggestion != "" {
+					message += fmt.Sprintf(", did you mean %q?", suggestion)
+				}
+				errs.add(joinPath(path, key), "%s", message)
+				delete(object, key)
+				continue
+			}
+			errs = append(errs, unknownFields(object[key], field.Type, joinPath(path, key))...)
+		}
+	case reflect.Slice:
+		array, ok := value.([]any)
+		if !ok {
+			return errs
+		}
+		for i, element := range array {
+			errs = append(errs, unknownFields(element, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return errs
+		}
+		for _, key := range sortedKeys(object) {
+			errs = append(errs, unknownFields(object[key], t.Elem(), joinPath(path, key))...)
+		}
+	}
+	return errs
+}
+
+// Keys of a JSON object in order, so that problems are always reported in the same order
+func sortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Fields which can be set from JSON: exported ones, except the adapter instances built from the entrypoint names
+func configFields(t reflect.Type) []reflect.StructField {
+	fields := []reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && field.Type.Kind() != reflect.Interface {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func configField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for _, field := range configFields(t) {
+		if strings.EqualFold(field.Name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// Name of the field closest to the misspelt key, if close enough to be a typo
+func closestConfigField(t reflect.Type, key string) string {
+	closest, closestDistance := "", len(key)/3+1
+	for _, field := range configFields(t) {
+		if distance := editDistance(strings.ToLower(key), strings.ToLower(field.Name)); distance <= closestDistance {
+			closest, closestDistance = jsonFieldName(field.Name), distance
+		}
+	}
+	return closest
+}
+
+// Levenshtein distance between two strings
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			substitution := previous[j-1]
+			if a[i-1] != b[j-1] {
+				substitution++
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, substitution)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// Checks the whole config: required fields per role, value ranges, URLs and their placeholders, adapters
+// and actors per topic. Returns every problem found as ConfigErrors.
+func (c *UserConfig) Validate() error {
+	errs := ConfigErrors{}
+	c.Wallet.validate("wallet", &errs, false)
+	for i, worker := range c.Worker {
+		worker.validate(fmt.Sprintf("worker[%d]", i), &errs)
+	}
+	for i, reputer := range c.Reputer {
+		reputer.validate(fmt.Sprintf("reputer[%d]", i), &errs)
+	}
+	for _, duplicate := range c.duplicateActors() {
+		errs.add(fmt.Sprintf("%s[%d]", duplicate.role, duplicate.index),
+			"shares a topic and a wallet with %s[%d], topic %d with wallet %q: give one of them its own wallet", duplicate.role, duplicate.firstIndex, duplicate.topicId, duplicate.keyName)
+	}
+	c.validateActorWallets(&errs)
+	return errs.orNil()
+}
+
+// Checks that the wallet overrides of the actors take effect: each wallet is loaded once, by key name, and shared
+// by its actors, so an override of the node's wallet, or differing configs of the same wallet, would be ignored
+func (c *UserConfig) validateActorWallets(errs *ConfigErrors) {
+	type actorWallet struct {
+		path   string
+		wallet WalletConfig
+	}
+	wallets := make(map[string]actorWallet)
+	validate := func(path string, wallet *WalletConfig) {
+		if wallet == nil {
+			return
+		}
+		merged := c.Wallet.Override(wallet)
+		if merged.AddressKeyName == c.Wallet.AddressKeyName {
+			// Key sources without a key name of their own are reported by WalletConfig.validate
+			overrides := *wallet
+			overrides.AddressKeyName = ""
+			overrides.AddressRestoreMnemonic, overrides.AddressRestoreMnemonicFile, overrides.AddressRestoreMnemonicSecret = "", "", nil
+			overrides.RemoteSigner = nil
+			if !reflect.DeepEqual(overrides, WalletConfig{}) {
+				errs.add(path, "overrides the node's wallet %q, which actors share with the node: set an addressKeyName of its own, or move the fields to wallet", merged.AddressKeyName)
+			}
+			return
+		}
+		if first, ok := wallets[merged.AddressKeyName]; ok {
+			if !reflect.DeepEqual(first.wallet, merged) {
+				errs.add(path, "configures wallet %q differently from %s: actors sharing a wallet must configure it alike", merged.AddressKeyName, first.path)
+			}
+			return
+		}
+		wallets[merged.AddressKeyName] = actorWallet{path, merged}
+	}
+	for i, worker := range c.Worker {
+		validate(fmt.Sprintf("worker[%d].wallet", i), worker.Wallet)
+	}
+	for i, reputer := range c.Reputer {
+		validate(fmt.Sprintf("reputer[%d].wallet", i), reputer.Wallet)
+	}
+}
+
+// Validates the wallet of the node, or the wallet of an actor, whose fields are all optional overrides
+func (wallet WalletConfig) validate(path string, errs *ConfigErrors, isOverride bool) {
+	if !isOverride && wallet.AddressKeyName == "" {
+		errs.add(joinPath(path, "addressKeyName"), "required, the name of the key in the keyring")
+	}
+	mnemonicSources := 0
+	for _, isSet := range []bool{wallet.AddressRestoreMnemonic != "", wallet.AddressRestoreMnemonicFile != "", wallet.AddressRestoreMnemonicSecret != nil} {
+		if isSet {
+			mnemonicSources++
+		}
+	}
+	if mnemonicSources > 1 {
+		errs.add(joinPath(path, "addressRestoreMnemonic"), "set only one of addressRestoreMnemonic, addressRestoreMnemonicFile and addressRestoreMnemonicSecret")
+	}
+	if isOverride && mnemonicSources > 0 && wallet.AddressKeyName == "" {
+		errs.add(joinPath(path, "addressKeyName"), "required with a mnemonic, or the node's key would be restored from another mnemonic")
+	}
+	if wallet.AddressRestoreMnemonicSecret != nil {
+		wallet.AddressRestoreMnemonicSecret.validate(joinPath(path, "addressRestoreMnemonicSecret"), errs)
+	}
+	if wallet.RemoteSigner != nil {
+		signerPath := joinPath(path, "remoteSigner")
+		validateURL(joinPath(signerPath, "url"), wallet.RemoteSigner.Url, errs, "http", "https")
+		if mnemonicSources > 0 {
+			errs.add(signerPath, "the key of a remote signer stays on the signing service, remove the mnemonic")
+		}
+		if isOverride && wallet.AddressKeyName == "" {
+			errs.add(joinPath(path, "addressKeyName"), "required with a remote signer, or the actor would act as the node's account")
+		}
+		if wallet.RemoteSigner.Token != nil {
+			wallet.RemoteSigner.Token.validate(joinPath(signerPath, "token"), errs)
+		}
+	}
+	switch wallet.KeyringBackend {
+	case "", KEYRING_BACKEND_TEST, KEYRING_BACKEND_OS:
+	case KEYRING_BACKEND_FILE:
+		if wallet.KeyringPassphrase == nil {
+			errs.add(joinPath(path, "keyringPassphrase"), "required with the file keyring backend, as the node can't prompt for it")
+		}
+	default:
+		errs.add(joinPath(path, "keyringBackend"), "expected %q, %q or %q, got %q", KEYRING_BACKEND_TEST, KEYRING_BACKEND_FILE, KEYRING_BACKEND_OS, wallet.KeyringBackend)
+	}
+	if wallet.KeyringPassphrase != nil {
+		if wallet.KeyringBackendOrDefault() == KEYRING_BACKEND_TEST {
+			errs.add(joinPath(path, "keyringPassphrase"), "only used by the file and os keyring backends, set keyringBackend")
+		}
+		wallet.KeyringPassphrase.validate(joinPath(path, "keyringPassphrase"), errs)
+	}
+	if !isOverride || wallet.NodeRpc != "" {
+		validateURL(joinPath(path, "nodeRpc"), wallet.NodeRpc, errs, "http", "https", "tcp")
+	}
+	if wallet.NodeWebsocket != "" {
+		validateURL(joinPath(path, "nodeWebsocket"), wallet.NodeWebsocket, errs, "ws", "wss")
+	}
+	if wallet.Gas != "" && wallet.Gas != "auto" {
+		if gas, err := strconv.ParseUint(wallet.Gas, 10, 64); err != nil || gas == 0 {
+			errs.add(joinPath(path, "gas"), "expected \"auto\" or a positive amount of gas, got %q", wallet.Gas)
+		}
+	}
+	if wallet.GasAdjustment < 0 {
+		errs.add(joinPath(path, "gasAdjustment"), "must not be negative, got %v", wallet.GasAdjustment)
+	}
+	if wallet.MaxRetries < 0 || wallet.MaxRetries > MAX_CONFIG_RETRIES {
+		errs.add(joinPath(path, "maxRetries"), "must be between 0 and %d, got %d", MAX_CONFIG_RETRIES, wallet.MaxRetries)
+	}
+	validateDelay(joinPath(path, "retryDelay"), wallet.RetryDelay, errs)
+	validateDelay(joinPath(path, "accountSequenceRetryDelay"), wallet.AccountSequenceRetryDelay, errs)
+	validateDelay(joinPath(path, "txConfirmationTimeoutSeconds"), wallet.TxConfirmationTimeoutSeconds, errs)
+	if wallet.TxBatchWindowMillis < 0 || wallet.TxBatchWindowMillis > MAX_CONFIG_BATCH_WINDOW_MILLIS {
+		errs.add(joinPath(path, "txBatchWindowMillis"), "must be between 0 and %d milliseconds, got %d", MAX_CONFIG_BATCH_WINDOW_MILLIS, wallet.TxBatchWindowMillis)
+	}
+	if wallet.GroundTruthCacheSeconds < 0 || wallet.GroundTruthCacheSeconds > MAX_CONFIG_GROUND_TRUTH_CACHE_SECONDS {
+		errs.add(joinPath(path, "groundTruthCacheSeconds"), "must be between 0 and %d seconds, got %d", MAX_CONFIG_GROUND_TRUTH_CACHE_SECONDS, wallet.GroundTruthCacheSeconds)
+	}
+	if _, err := NewFeeManager(wallet.Fees, DEFAULT_BOND_DENOM, nil); err != nil {
+		errs.add(joinPath(path, "fees"), "%s", err)
+	}
+	keys := make([]string, 0, len(wallet.TxErrorPolicies))
+	for key := range wallet.TxErrorPolicies {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := ValidateTxErrorPolicies(map[string]TxErrorPolicy{key: wallet.TxErrorPolicies[key]}); err != nil {
+			errs.add(joinPath(joinPath(path, "txErrorPolicies"), key), "%s", err)
+		}
+	}
+}
+
+func (secret SecretConfig) validate(path string, errs *ConfigErrors) {
+	switch secret.Provider {
+	case SECRET_PROVIDER_FILE:
+		if secret.Path == "" {
+			errs.add(joinPath(path, "path"), "required, the file of the secret")
+		}
+	case SECRET_PROVIDER_ENV:
+		if secret.EnvVar == "" {
+			errs.add(joinPath(path, "envVar"), "required, the environment variable of the secret")
+		}
+	case SECRET_PROVIDER_VAULT:
+		validateURL(joinPath(path, "address"), secret.Address, errs, "http", "https")
+		if secret.Path == "" {
+			errs.add(joinPath(path, "path"), "required, the path of the secret, e.g. \"secret/data/allora\"")
+		}
+	default:
+		errs.add(joinPath(path, "provider"), "expected %q, %q or %q, got %q", SECRET_PROVIDER_FILE, SECRET_PROVIDER_ENV, SECRET_PROVIDER_VAULT, secret.Provider)
+	}
+}
+
+func validateDelay(path string, seconds int64, errs *ConfigErrors) {
+	if seconds < 0 || seconds > MAX_CONFIG_DELAY_SECONDS {
+		errs.add(path, "must be between 0 and %d seconds, got %d", MAX_CONFIG_DELAY_SECONDS, seconds)
+	}
+}
+
+func validateURL(path string, value string, errs *ConfigErrors, schemes ...string) {
+	if value == "" {
+		errs.add(path, "required, a URL with scheme %s", strings.Join(schemes, " or "))
+		return
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		errs.add(path, "invalid URL %q", value)
+		return
+	}
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme {
+			return
+		}
+	}
+	errs.add(path, "invalid URL scheme %q, expected %s", parsed.Scheme, strings.Join(schemes, " or "))
+}
+
+// Validates the URL template in the parameter: every placeholder must be filled, by the block height,
+// the topic id or another parameter, and the URL must be valid once filled
+func validateURLTemplate(path string, parameters map[string]string, key string, errs *ConfigErrors) {
+	template, ok := parameters[key]
+	if !ok || template == "" {
+		errs.add(joinPath(path, key), "required, the URL the adapter requests")
+		return
+	}
+	filled := placeholderRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		for _, builtin := range builtinPlaceholders {
+			if name == builtin {
+				return "1"
+			}
+		}
+		if value, ok := parameters[name]; ok && name != key {
+			return url.PathEscape(value)
+		}
+		errs.add(joinPath(path, key), "placeholder %s is not filled, add a %q parameter", placeholder, name)
+		return "1"
+	})
+	validateURL(joinPath(path, key), filled, errs, "http", "https")
+}
+
+func validateActor(path string, topicId uint64, loopSeconds int64, wallet *WalletConfig, errs *ConfigErrors) {
+	if topicId == 0 {
+		errs.add(joinPath(path, "topicId"), "required, topic ids start at 1")
+	}
+	if loopSeconds <= 0 || loopSeconds > MAX_CONFIG_DELAY_SECONDS {
+		errs.add(joinPath(path, "loopSeconds"), "must be between 1 and %d seconds, got %d", MAX_CONFIG_DELAY_SECONDS, loopSeconds)
+	}
+	if wallet != nil {
+		wallet.validate(joinPath(path, "wallet"), errs, true)
+	}
+}
+
+func (worker WorkerConfig) validate(path string, errs *ConfigErrors) {
+	validateActor(path, worker.TopicId, worker.LoopSeconds, worker.Wallet, errs)
+	if worker.InferenceEntrypointName == "" && worker.ForecastEntrypointName == "" {
+		errs.add(path, "a worker needs an inferenceEntrypointName, a forecastEntrypointName, or both")
+	}
+	parametersPath := joinPath(path, "parameters")
+	if worker.InferenceEntrypointName != "" {
+		validateURLTemplate(parametersPath, worker.Parameters, INFERENCE_ENDPOINT_PARAMETER, errs)
+	}
+	if worker.ForecastEntrypointName != "" {
+		validateURLTemplate(parametersPath, worker.Parameters, FORECAST_ENDPOINT_PARAMETER, errs)
+	}
+	if worker.InferenceEntrypoint != nil && !worker.InferenceEntrypoint.CanInfer() {
+		errs.add(joinPath(path, "inferenceEntrypointName"), "adapter %s cannot infer", worker.InferenceEntrypoint.Name())
+	}
+	if worker.ForecastEntrypoint != nil && !worker.ForecastEntrypoint.CanForecast() {
+		errs.add(joinPath(path, "forecastEntrypointName"), "adapter %s cannot forecast", worker.ForecastEntrypoint.Name())
+	}
+}
+
+func (reputer ReputerConfig) validate(path string, errs *ConfigErrors) {
+	validateActor(path, reputer.TopicId, reputer.LoopSeconds, reputer.Wallet, errs)
+	if reputer.GroundTruthEntrypointName == "" {
+		errs.add(joinPath(path, "groundTruthEntrypointName"), "required for a reputer")
+	} else {
+		validateURLTemplate(joinPath(path, "groundTruthParameters"), reputer.GroundTruthParameters, GROUND_TRUTH_ENDPOINT_PARAMETER, errs)
+	}
+	if reputer.LossFunctionEntrypointName == "" {
+		errs.add(joinPath(path, "lossFunctionEntrypointName"), "required for a reputer")
+	}
+	if reputer.LossFunctionEntrypointName == NATIVE_LOSS_ADAPTER_NAME {
+		if err := ValidateNativeLossOptions(reputer.LossFunctionParameters.LossMethodOptions); err != nil {
+			errs.add(joinPath(path, "lossFunctionParameters.lossMethodOptions"), "%s", err)
+		}
+	} else {
+		validateURL(joinPath(path, "lossFunctionParameters.lossFunctionService"), reputer.LossFunctionParameters.LossFunctionService, errs, "http", "https")
+	}
+	if concurrency := reputer.LossFunctionParameters.MaxConcurrency; concurrency < 0 || concurrency > MAX_CONFIG_LOSS_CONCURRENCY {
+		errs.add(joinPath(path, "lossFunctionParameters.maxConcurrency"), "must be between 0 and %d, got %d", MAX_CONFIG_LOSS_CONCURRENCY, concurrency)
+	}
+	if reputer.MinStake < 0 {
+		errs.add(joinPath(path, "minStake"), "must not be negative, got %d", reputer.MinStake)
+	}
+	if reputer.GroundTruthEntrypoint != nil && !reputer.GroundTruthEntrypoint.CanSourceGroundTruthAndComputeLoss() {
+		errs.add(joinPath(path, "groundTruthEntrypointName"), "adapter %s cannot source ground truth", reputer.GroundTruthEntrypoint.Name())
+	}
+}
@@ -0,0 +1,263 @@
+package lib
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validConfigJSON = `{
+	"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657", "maxRetries": 3, "retryDelay": 2},
+	"worker": [{
+		"topicId": 1, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5,
+		"parameters": {"InferenceEndpoint": "http://source:8000/inference/{Token}", "Token": "ETH"}
+	}],
+	"reputer": [{
+		"topicId": 1, "groundTruthEntrypointName": "api-worker-reputer", "lossFunctionEntrypointName": "api-worker-reputer",
+		"loopSeconds": 30, "minStake": 100,
+		"groundTruthParameters": {"GroundTruthEndpoint": "http://source:8888/gt/{Token}/{BlockHeight}", "Token": "ETHUSD"},
+		"lossFunctionParameters": {"LossFunctionService": "http://localhost:5000", "LossMethodOptions": {"loss_method": "sqe"}}
+	}]
+}`
+
+func TestParseUserConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected []ConfigError // nil if the config is valid
+	}{
+		{
+			name:   "valid config",
+			config: validConfigJSON,
+		},
+		{
+			name: "misspelt fields",
+			config: `{
+				"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657", "retryDelays": 2},
+				"worker": [{"topicId": 1, "inferenceEntrypoint_name": "api-worker-reputer", "loopSeconds": 5,
+					"parameters": {"InferenceEndpoint": "http://source/1"}}]
+			}`,
+			expected: []ConfigError{
+				{"wallet.retryDelays", `unknown field, did you mean "retryDelay"?`},
+				{"worker[0].inferenceEntrypoint_name", `unknown field, did you mean "inferenceEntrypointName"?`},
+				{"worker[0]", "a worker needs an inferenceEntrypointName, a forecastEntrypointName, or both"},
+			},
+		},
+		{
+			name:   "adapter instances cannot be set",
+			config: `{"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657"}, "worker": [{"inferenceEntrypoint": {}}]}`,
+			expected: []ConfigError{
+				{"worker[0].inferenceEntrypoint", `unknown field, did you mean "inferenceEntrypointName"?`},
+				{"worker[0].topicId", "required, topic ids start at 1"},
+				{"worker[0].loopSeconds", "must be between 1 and 3600 seconds, got 0"},
+				{"worker[0]", "a worker needs an inferenceEntrypointName, a forecastEntrypointName, or both"},
+			},
+		},
+		{
+			name: "required fields of a reputer",
+			config: `{
+				"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657"},
+				"reputer": [{"topicId": 1, "loopSeconds": 30, "minStake": -1, "lossFunctionParameters": {"MaxConcurrency": 1000}}]
+			}`,
+			expected: []ConfigError{
+				{"reputer[0].groundTruthEntrypointName", "required for a reputer"},
+				{"reputer[0].lossFunctionEntrypointName", "required for a reputer"},
+				{"reputer[0].lossFunctionParameters.lossFunctionService", "required, a URL with scheme http or https"},
+				{"reputer[0].lossFunctionParameters.maxConcurrency", "must be between 0 and 256, got 1000"},
+				{"reputer[0].minStake", "must not be negative, got -1"},
+			},
+		},
+		{
+			name: "placeholders and URLs",
+			config: `{
+				"wallet": {"addressKeyName": "node", "nodeRpc": "localhost:26657"},
+				"worker": [{"topicId": 1, "inferenceEntrypointName": "api-worker-reputer", "forecastEntrypointName": "api-worker-reputer", "loopSeconds": 5,
+					"parameters": {"InferenceEndpoint": "http://source/{Token}/{BlockHeight}"}}],
+				"reputer": [{"topicId": 1, "groundTruthEntrypointName": "api-worker-reputer", "lossFunctionEntrypointName": "api-worker-reputer", "loopSeconds": 30,
+					"groundTruthParameters": {"GroundTruthEndpoint": "source/{TopicId}"},
+					"lossFunctionParameters": {"LossFunctionService": "ftp://localhost:5000"}}]
+			}`,
+			expected: []ConfigError{
+				{"wallet.nodeRpc", `invalid URL "localhost:26657"`},
+				{"worker[0].parameters.InferenceEndpoint", `placeholder {Token} is not filled, add a "Token" parameter`},
+				{"worker[0].parameters.ForecastEndpoint", "required, the URL the adapter requests"},
+				{"reputer[0].groundTruthParameters.GroundTruthEndpoint", `invalid URL "source/1"`},
+				{"reputer[0].lossFunctionParameters.lossFunctionService", `invalid URL scheme "ftp", expected http or https`},
+			},
+		},
+		{
+			name: "native loss",
+			config: `{
+				"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657"},
+				"reputer": [
+					{"topicId": 1, "groundTruthEntrypointName": "api-worker-reputer", "lossFunctionEntrypointName": "native-loss", "loopSeconds": 30,
+						"groundTruthParameters": {"GroundTruthEndpoint": "http://source/1"},
+						"lossFunctionParameters": {"LossMethodOptions": {"loss_method": "huber", "delta": "0.5"}}},
+					{"topicId": 2, "groundTruthEntrypointName": "api-worker-reputer", "lossFunctionEntrypointName": "native-loss", "loopSeconds": 30,
+						"groundTruthParameters": {"GroundTruthEndpoint": "http://source/2"},
+						"lossFunctionParameters": {"LossMethodOptions": {"loss_method": "mse"}}},
+					{"topicId": 3, "groundTruthEntrypointName": "api-worker-reputer", "lossFunctionEntrypointName": "native-loss", "loopSeconds": 30,
+						"groundTruthParameters": {"GroundTruthEndpoint": "http://source/3"},
+						"lossFunctionParameters": {"LossMethodOptions": {"loss_method": "huber", "delta": "-1"}}}
+				]
+			}`,
+			expected: []ConfigError{
+				{"reputer[1].lossFunctionParameters.lossMethodOptions", `unknown loss_method "mse", expected one of abs, huber, log, logcosh, percentage, sqe`},
+				{"reputer[2].lossFunctionParameters.lossMethodOptions", `delta must be a positive decimal, got "-1"`},
+			},
+		},
+		{
+			name: "retries and delays",
+			config: `{
+				"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657", "gas": "lots", "maxRetries": -1, "retryDelay": 3000000,
+					"txBatchWindowMillis": 120000, "groundTruthCacheSeconds": -1, "fees": {"gasPrice": "ten"}, "txErrorPolicies": {"emissions:67": "sometimes"}},
+				"worker": [{"topicId": 1, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5,
+					"parameters": {"InferenceEndpoint": "http://source/1"}, "wallet": {"addressKeyName": "challenger", "accountSequenceRetryDelay": -5}}]
+			}`,
+			expected: []ConfigError{
+				{"wallet.gas", `expected "auto" or a positive amount of gas, got "lots"`},
+				{"wallet.maxRetries", "must be between 0 and 100, got -1"},
+				{"wallet.retryDelay", "must be between 0 and 3600 seconds, got 3000000"},
+				{"wallet.txBatchWindowMillis", "must be between 0 and 60000 milliseconds, got 120000"},
+				{"wallet.groundTruthCacheSeconds", "must be between 0 and 604800 seconds, got -1"},
+				{"wallet.fees", "invalid gas price: invalid decimal coin expression: ten"},
+				{"wallet.txErrorPolicies.emissions:67", `invalid policy "sometimes" for tx error "emissions:67"`},
+				{"worker[0].wallet.accountSequenceRetryDelay", "must be between 0 and 3600 seconds, got -5"},
+			},
+		},
+		{
+			name: "mnemonic sources",
+			config: `{
+				"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657", "addressRestoreMnemonic": "words", "addressRestoreMnemonicFile": "/run/secrets/mnemonic"},
+				"worker": [{"topicId": 1, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5, "parameters": {"InferenceEndpoint": "http://source/1"},
+					"wallet": {"addressRestoreMnemonicSecret": {"provider": "vault", "address": "vault:8200"}}}],
+				"reputer": [{"topicId": 1, "groundTruthEntrypointName": "api-worker-reputer", "lossFunctionEntrypointName": "api-worker-reputer", "loopSeconds": 30,
+					"groundTruthParameters": {"GroundTruthEndpoint": "http://source/1"}, "lossFunctionParameters": {"LossFunctionService": "http://localhost:5000"},
+					"wallet": {"addressKeyName": "reputer", "addressRestoreMnemonicSecret": {"provider": "aws"}}}]
+			}`,
+			expected: []ConfigError{
+				{"wallet.addressRestoreMnemonic", "set only one of addressRestoreMnemonic, addressRestoreMnemonicFile and addressRestoreMnemonicSecret"},
+				{"worker[0].wallet.addressKeyName", "required with a mnemonic, or the node's key would be restored from another mnemonic"},
+				{"worker[0].wallet.addressRestoreMnemonicSecret.address", `invalid URL "vault:8200"`},
+				{"worker[0].wallet.addressRestoreMnemonicSecret.path", `required, the path of the secret, e.g. "secret/data/allora"`},
+				{"reputer[0].wallet.addressRestoreMnemonicSecret.provider", `expected "file", "env" or "vault", got "aws"`},
+			},
+		},
+		{
+			name: "keyring backends",
+			config: `{
+				"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657", "keyringBackend": "file"},
+				"worker": [{"topicId": 1, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5, "parameters": {"InferenceEndpoint": "http://source/1"},
+					"wallet": {"addressKeyName": "worker", "keyringBackend": "kwallet"}}],
+				"reputer": [{"topicId": 1, "groundTruthEntrypointName": "api-worker-reputer", "lossFunctionEntrypointName": "api-worker-reputer", "loopSeconds": 30,
+					"groundTruthParameters": {"GroundTruthEndpoint": "http://source/1"}, "lossFunctionParameters": {"LossFunctionService": "http://localhost:5000"},
+					"wallet": {"addressKeyName": "reputer", "keyringBackend": "test", "keyringPassphrase": {"provider": "env"}}}]
+			}`,
+			expected: []ConfigError{
+				{"wallet.keyringPassphrase", "required with the file keyring backend, as the node can't prompt for it"},
+				{"worker[0].wallet.keyringBackend", `expected "test", "file" or "os", got "kwallet"`},
+				{"reputer[0].wallet.keyringPassphrase", "only used by the file and os keyring backends, set keyringBackend"},
+				{"reputer[0].wallet.keyringPassphrase.envVar", "required, the environment variable of the secret"},
+			},
+		},
+		{
+			name: "remote signers",
+			config: `{
+				"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657", "addressRestoreMnemonic": "words", "remoteSigner": {"url": "grpc://signer:9090"}},
+				"worker": [{"topicId": 1, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5, "parameters": {"InferenceEndpoint": "http://source/1"},
+					"wallet": {"remoteSigner": {"url": "https://signer:9090"}}}],
+				"reputer": [{"topicId": 1, "groundTruthEntrypointName": "api-worker-reputer", "lossFunctionEntrypointName": "api-worker-reputer", "loopSeconds": 30,
+					"groundTruthParameters": {"GroundTruthEndpoint": "http://source/1"}, "lossFunctionParameters": {"LossFunctionService": "http://localhost:5000"},
+					"wallet": {"addressKeyName": "reputer", "remoteSigner": {"url": "https://signer:9090", "keyName": "reputer-key", "token": {"provider": "file"}}}}]
+			}`,
+			expected: []ConfigError{
+				{"wallet.remoteSigner.url", `invalid URL scheme "grpc", expected http or https`},
+				{"wallet.remoteSigner", "the key of a remote signer stays on the signing service, remove the mnemonic"},
+				{"worker[0].wallet.addressKeyName", "required with a remote signer, or the actor would act as the node's account"},
+				{"reputer[0].wallet.remoteSigner.token.path", "required, the file of the secret"},
+			},
+		},
+		{
+			name: "duplicate actors",
+			config: `{
+				"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657"},
+				"worker": [
+					{"topicId": 1, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5, "parameters": {"InferenceEndpoint": "http://source/1"}},
+					{"topicId": 1, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5, "parameters": {"InferenceEndpoint": "http://source/2"}}
+				]
+			}`,
+			expected: []ConfigError{
+				{"worker[1]", `shares a topic and a wallet with worker[0], topic 1 with wallet "node": give one of them its own wallet`},
+			},
+		},
+		{
+			name: "actors on a topic with their own wallets",
+			config: `{
+				"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657"},
+				"worker": [
+					{"topicId": 1, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5, "parameters": {"InferenceEndpoint": "http://source/1"}},
+					{"topicId": 1, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5, "parameters": {"InferenceEndpoint": "http://source/2"}, "wallet": {"addressKeyName": "challenger"}}
+				]
+			}`,
+		},
+		{
+			name: "ignored wallet overrides",
+			config: `{
+				"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657"},
+				"worker": [
+					{"topicId": 1, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5, "parameters": {"InferenceEndpoint": "http://source/1"}, "wallet": {"gas": "500000"}},
+					{"topicId": 2, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5, "parameters": {"InferenceEndpoint": "http://source/2"}, "wallet": {"addressKeyName": "node"}},
+					{"topicId": 3, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5, "parameters": {"InferenceEndpoint": "http://source/3"}, "wallet": {"addressKeyName": "challenger", "txBatchWindowMillis": 500}},
+					{"topicId": 4, "inferenceEntrypointName": "api-worker-reputer", "loopSeconds": 5, "parameters": {"InferenceEndpoint": "http://source/4"}, "wallet": {"addressKeyName": "challenger"}}
+				],
+				"reputer": [{
+					"topicId": 3, "groundTruthEntrypointName": "api-worker-reputer", "lossFunctionEntrypointName": "api-worker-reputer", "loopSeconds": 30, "minStake": 100,
+					"groundTruthParameters": {"GroundTruthEndpoint": "http://source:8888/gt"}, "lossFunctionParameters": {"LossFunctionService": "http://localhost:5000"},
+					"wallet": {"addressKeyName": "challenger", "txBatchWindowMillis": 500}
+				}]
+			}`,
+			expected: []ConfigError{
+				{"worker[0].wallet", `overrides the node's wallet "node", which actors share with the node: set an addressKeyName of its own, or move the fields to wallet`},
+				{"worker[3].wallet", `configures wallet "challenger" differently from worker[2].wallet: actors sharing a wallet must configure it alike`},
+			},
+		},
+		{
+			name:     "wrong type",
+			config:   `{"wallet": {"addressKeyName": "node", "nodeRpc": "http://localhost:26657"}, "worker": [{"topicId": "one"}]}`,
+			expected: []ConfigError{{"worker[0].topicId", "expected a uint64, got a string"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseUserConfig([]byte(tt.config))
+			if tt.expected == nil {
+				require.NoError(t, err)
+				return
+			}
+			var configErrs ConfigErrors
+			require.True(t, errors.As(err, &configErrs), "expected ConfigErrors, got %v", err)
+			assert.Equal(t, ConfigErrors(tt.expected), configErrs)
+		})
+	}
+}
+
+func TestParseUserConfigInvalidJSON(t *testing.T) {
+	_, err := ParseUserConfig([]byte(`{"wallet": {`))
+	assert.ErrorContains(t, err, "invalid JSON config")
+}
+
+func TestParseUserConfigExample(t *testing.T) {
+	data, err := os.ReadFile("../config.example.json")
+	require.NoError(t, err)
+	config, err := ParseUserConfig(data)
+	require.NoError(t, err)
+	assert.Equal(t, "ETH", config.Worker[0].Parameters["Token"])
+}
+
+func TestConfigErrorsMessage(t *testing.T) {
+	err := ConfigErrors{{"wallet.nodeRpc", "required"}, {"worker[0].topicId", "required"}}
+	assert.Equal(t, "invalid config, 2 problem(s):\n  - wallet.nodeRpc: required\n  - worker[0].topicId: required", err.Error())
+}
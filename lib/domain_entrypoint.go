@@ -1,19 +1,40 @@
 package lib
 
+import (
+	"context"
+	"errors"
+)
+
 type Truth = string
 
 type AlloraAdapter interface {
 	Name() string
-	CalcInference(WorkerConfig, int64) (string, error)
-	CalcForecast(WorkerConfig, int64) ([]NodeValue, error)
-	GroundTruth(ReputerConfig, int64) (Truth, error)
-	LossFunction(ReputerConfig, string, string, map[string]string) (string, error)
-	IsLossFunctionNeverNegative(ReputerConfig, map[string]string) (bool, error)
+	CalcInference(context.Context, WorkerConfig, int64) (string, error)
+	CalcForecast(context.Context, WorkerConfig, int64) ([]NodeValue, error)
+	GroundTruth(context.Context, ReputerConfig, int64) (Truth, error)
+	LossFunction(context.Context, ReputerConfig, string, string, map[string]string) (string, error)
+	IsLossFunctionNeverNegative(context.Context, ReputerConfig, map[string]string) (bool, error)
 	CanInfer() bool
 	CanForecast() bool
 	CanSourceGroundTruthAndComputeLoss() bool
 }
 
+// Optionally implemented by an AlloraAdapter computing the losses of many values against one ground truth at once,
+// which the node then calls instead of LossFunction for every value. The losses are in the order of the values.
+type BatchLossFunctionAdapter interface {
+	BatchLossFunction(ctx context.Context, node ReputerConfig, groundTruth string, values []string, options map[string]string) ([]string, error)
+}
+
+// Returned by BatchLossFunction when it cannot compute losses in batch, e.g. as the loss function service has no batch endpoint,
+// for the node to fall back on LossFunction
+var ErrBatchLossFunctionUnsupported = errors.New("batch loss function not supported")
+
+// Optionally implemented by an AlloraAdapter to identify where the ground truth of a reputer at a block height comes from,
+// e.g. the resolved URL of its endpoint, so that the reputers of topics sharing a source share the ground truth it fetched
+type GroundTruthSourceAdapter interface {
+	GroundTruthSource(node ReputerConfig, blockHeight int64) string
+}
+
 type NodeValue struct {
 	Worker string `json:"worker,omitempty"`
 	Value  string `json:"value,omitempty"`
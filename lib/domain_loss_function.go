@@ -0,0 +1,213 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	alloraMath "github.com/allora-network/allora-chain/math"
+)
+
+// Loss of a value against the ground truth, given the options of the loss method
+type lossFunction func(groundTruth, value alloraMath.Dec, options map[string]string) (alloraMath.Dec, error)
+
+// Native loss functions by loss_method. All of them are never negative.
+var nativeLossFunctions = map[string]lossFunction{
+	"sqe":        squaredError,
+	"abs":        absoluteError,
+	"huber":      huberLoss,
+	"logcosh":    logCoshLoss,
+	"log":        squaredLogarithmicError,
+	"percentage": absolutePercentageError,
+}
+
+// Beyond this absolute error, ln(1 + exp(-2|e|)) is below the precision of the log-cosh loss
+var logCoshCutoff = alloraMath.NewDecFromInt64(50)
+
+// Computes the loss of the value against the ground truth with the native loss function of the loss_method of the options
+func NativeLoss(groundTruth, value alloraMath.Dec, options map[string]string) (alloraMath.Dec, error) {
+	if err := ValidateNativeLossOptions(options); err != nil {
+		return alloraMath.Dec{}, err
+	}
+	loss, err := nativeLossFunctions[options[LOSS_METHOD_OPTION]](groundTruth, value, options)
+	if err != nil {
+		return alloraMath.Dec{}, fmt.Errorf("%s loss: %w", options[LOSS_METHOD_OPTION], err)
+	}
+	return loss, nil
+}
+
+// Whether the native loss function of the options is never negative, which all of them are
+func IsNativeLossNeverNegative(options map[string]string) (bool, error) {
+	if err := ValidateNativeLossOptions(options); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Checks that the options name a native loss function and hold valid parameters for it
+func ValidateNativeLossOptions(options map[string]string) error {
+	method, ok := options[LOSS_METHOD_OPTION]
+	if !ok || method == "" {
+		return fmt.Errorf("%s is required, one of %s", LOSS_METHOD_OPTION, nativeLossMethods())
+	}
+	if _, ok := nativeLossFunctions[method]; !ok {
+		return fmt.Errorf("unknown %s %q, expected one of %s", LOSS_METHOD_OPTION, method, nativeLossMethods())
+	}
+	if method == "huber" {
+		if _, err := huberDelta(options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func nativeLossMethods() string {
+	methods := make([]string, 0, len(nativeLossFunctions))
+	for method := range nativeLossFunctions {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// (value - groundTruth)^2
+func squaredError(groundTruth, value alloraMath.Dec, _ map[string]string) (alloraMath.Dec, error) {
+	diff, err := value.Sub(groundTruth)
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	return diff.Mul(diff)
+}
+
+// |value - groundTruth|
+func absoluteError(groundTruth, value alloraMath.Dec, _ map[string]string) (alloraMath.Dec, error) {
+	diff, err := value.Sub(groundTruth)
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	return diff.Abs()
+}
+
+func huberDelta(options map[string]string) (alloraMath.Dec, error) {
+	deltaStr, ok := options[HUBER_DELTA_OPTION]
+	if !ok {
+		return alloraMath.OneDec(), nil
+	}
+	delta, err := alloraMath.NewPositiveDecFromString(deltaStr)
+	if err != nil {
+		return alloraMath.Dec{}, fmt.Errorf("%s must be a positive decimal, got %q", HUBER_DELTA_OPTION, deltaStr)
+	}
+	return delta, nil
+}
+
+// e^2 / 2 if |e| <= delta, else delta * (|e| - delta / 2), with e = value - groundTruth
+func huberLoss(groundTruth, value alloraMath.Dec, options map[string]string) (alloraMath.Dec, error) {
+	delta, err := huberDelta(options)
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	absErr, err := absoluteError(groundTruth, value, options)
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	two := alloraMath.NewDecFromInt64(2)
+	if absErr.Lte(delta) {
+		squared, err := absErr.Mul(absErr)
+		if err != nil {
+			return alloraMath.Dec{}, err
+		}
+		return squared.Quo(two)
+	}
+	halfDelta, err := delta.Quo(two)
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	linear, err := absErr.Sub(halfDelta)
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	return delta.Mul(linear)
+}
+
+// ln(cosh(e)), with e = value - groundTruth, computed as |e| + ln(1 + exp(-2|e|)) - ln(2) so that large errors do not overflow
+func logCoshLoss(groundTruth, value alloraMath.Dec, options map[string]string) (alloraMath.Dec, error) {
+	absErr, err := absoluteError(groundTruth, value, options)
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	two := alloraMath.NewDecFromInt64(2)
+	ln2, err := alloraMath.Ln(two)
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	loss, err := absErr.Sub(ln2)
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	if absErr.Lt(logCoshCutoff) {
+		exponent, err := absErr.Mul(two)
+		if err != nil {
+			return alloraMath.Dec{}, err
+		}
+		if exponent, err = exponent.Neg(); err != nil {
+			return alloraMath.Dec{}, err
+		}
+		exp, err := alloraMath.Exp(exponent)
+		if err != nil {
+			return alloraMath.Dec{}, err
+		}
+		onePlusExp, err := exp.Add(alloraMath.OneDec())
+		if err != nil {
+			return alloraMath.Dec{}, err
+		}
+		ln, err := alloraMath.Ln(onePlusExp)
+		if err != nil {
+			return alloraMath.Dec{}, err
+		}
+		if loss, err = loss.Add(ln); err != nil {
+			return alloraMath.Dec{}, err
+		}
+	}
+	// Rounding must not turn a loss of 0 negative
+	return alloraMath.Max(loss, alloraMath.ZeroDec())
+}
+
+// (ln(1 + value) - ln(1 + groundTruth))^2, for values and ground truths above -1
+func squaredLogarithmicError(groundTruth, value alloraMath.Dec, _ map[string]string) (alloraMath.Dec, error) {
+	lnOnePlus := func(x alloraMath.Dec, name string) (alloraMath.Dec, error) {
+		onePlus, err := x.Add(alloraMath.OneDec())
+		if err != nil {
+			return alloraMath.Dec{}, err
+		}
+		if !onePlus.IsPositive() {
+			return alloraMath.Dec{}, fmt.Errorf("%s must be above -1, got %s", name, x)
+		}
+		return alloraMath.Ln(onePlus)
+	}
+	lnValue, err := lnOnePlus(value, "value")
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	lnGroundTruth, err := lnOnePlus(groundTruth, "ground truth")
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	return squaredError(lnGroundTruth, lnValue, nil)
+}
+
+// |value - groundTruth| / |groundTruth|, for a non-zero ground truth
+func absolutePercentageError(groundTruth, value alloraMath.Dec, options map[string]string) (alloraMath.Dec, error) {
+	if groundTruth.IsZero() {
+		return alloraMath.Dec{}, errors.New("ground truth must not be 0")
+	}
+	absErr, err := absoluteError(groundTruth, value, options)
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	absGroundTruth, err := groundTruth.Abs()
+	if err != nil {
+		return alloraMath.Dec{}, err
+	}
+	return absErr.Quo(absGroundTruth)
+}
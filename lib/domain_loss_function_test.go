@@ -0,0 +1,52 @@
+package lib
+
+import (
+	"testing"
+
+	alloraMath "github.com/allora-network/allora-chain/math"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNativeLoss(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     map[string]string
+		groundTruth string
+		value       string
+		expected    string // expected loss, to 1e-12
+		expectedErr string
+	}{
+		{name: "squared error", options: map[string]string{"loss_method": "sqe"}, groundTruth: "10", value: "7.5", expected: "6.25"},
+		{name: "absolute error", options: map[string]string{"loss_method": "abs"}, groundTruth: "10", value: "7.5", expected: "2.5"},
+		{name: "huber within delta", options: map[string]string{"loss_method": "huber"}, groundTruth: "10", value: "10.5", expected: "0.125"},
+		{name: "huber beyond delta", options: map[string]string{"loss_method": "huber", "delta": "2"}, groundTruth: "10", value: "5", expected: "8"},
+		{name: "log-cosh", options: map[string]string{"loss_method": "logcosh"}, groundTruth: "1", value: "2", expected: "0.433780830483027"},
+		{name: "log-cosh of no error", options: map[string]string{"loss_method": "logcosh"}, groundTruth: "3", value: "3", expected: "0"},
+		{name: "log-cosh of a large error", options: map[string]string{"loss_method": "logcosh"}, groundTruth: "0", value: "-1000", expected: "999.306852819440055"},
+		{name: "squared logarithmic error", options: map[string]string{"loss_method": "log"}, groundTruth: "0", value: "1.718281828459045", expected: "1"},
+		{name: "percentage error", options: map[string]string{"loss_method": "percentage"}, groundTruth: "-200", value: "-150", expected: "0.25"},
+		{name: "percentage error of a ground truth of 0", options: map[string]string{"loss_method": "percentage"}, groundTruth: "0", value: "1", expectedErr: "percentage loss: ground truth must not be 0"},
+		{name: "logarithmic error of a value of -1", options: map[string]string{"loss_method": "log"}, groundTruth: "1", value: "-1", expectedErr: "log loss: value must be above -1, got -1"},
+		{name: "missing loss method", options: map[string]string{}, groundTruth: "1", value: "1", expectedErr: "loss_method is required, one of abs, huber, log, logcosh, percentage, sqe"},
+		{name: "invalid huber delta", options: map[string]string{"loss_method": "huber", "delta": "0"}, groundTruth: "1", value: "1", expectedErr: `delta must be a positive decimal, got "0"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loss, err := NativeLoss(alloraMath.MustNewDecFromString(tt.groundTruth), alloraMath.MustNewDecFromString(tt.value), tt.options)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.False(t, loss.IsNegative())
+			inDelta, err := alloraMath.InDelta(alloraMath.MustNewDecFromString(tt.expected), loss, alloraMath.MustNewDecFromString("1e-12"))
+			require.NoError(t, err)
+			require.True(t, inDelta, "expected %s, got %s", tt.expected, loss)
+
+			neverNegative, err := IsNativeLossNeverNegative(tt.options)
+			require.NoError(t, err)
+			require.True(t, neverNegative)
+		})
+	}
+}
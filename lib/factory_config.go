@@ -4,12 +4,13 @@ import (
 	"context"
 	"errors"
 	"os"
-	"path/filepath"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
 	errorsmod "cosmossdk.io/errors"
 	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	"github.com/ignite/cli/v28/ignite/pkg/cosmosaccount"
 	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
@@ -18,11 +19,7 @@ import (
 func getAlloraClient(config *UserConfig) (*cosmosclient.Client, error) {
 	// create a allora client instance
 	ctx := context.Background()
-	userHomeDir, _ := os.UserHomeDir()
-	alloraClientHome := filepath.Join(userHomeDir, ".allorad")
-	if config.Wallet.AlloraHomeDir != "" {
-		alloraClientHome = config.Wallet.AlloraHomeDir
-	}
+	alloraClientHome := config.Wallet.HomeDir()
 
 	// Check that the given home folder exists
 	if _, err := os.Stat(alloraClientHome); errors.Is(err, os.ErrNotExist) {
@@ -41,23 +38,34 @@ func getAlloraClient(config *UserConfig) (*cosmosclient.Client, error) {
 		cosmosclient.WithHome(alloraClientHome),
 		cosmosclient.WithGas(config.Wallet.Gas),
 		cosmosclient.WithGasAdjustment(config.Wallet.GasAdjustment),
+		cosmosclient.WithKeyringBackend(cosmosaccount.KeyringBackend(config.Wallet.KeyringBackendOrDefault())),
+		cosmosclient.WithKeyringServiceName(KEYRING_SERVICE_NAME),
 	)
 	if err != nil {
 		config.Wallet.SubmitTx = false
 		return nil, err
 	}
+	if err := config.Wallet.openKeyringWithPassphrase(&client, alloraClientHome); err != nil {
+		config.Wallet.SubmitTx = false
+		return nil, err
+	}
 	return &client, nil
 }
 
-func (config *UserConfig) GenerateNodeConfig() (*NodeConfig, error) {
-	client, err := getAlloraClient(config)
+// Loads the key of the wallet from the keyring, restoring it from the mnemonic if one is set
+func (config *UserConfig) loadKeyringSigner(client *cosmosclient.Client) (Signer, error) {
+	if err := config.Wallet.CheckKeyringForChain(client.Context().ChainID); err != nil {
+		return nil, err
+	}
+	// The BIP39 passphrase of the mnemonic is left empty, as in allorad, for the key to have the same address
+	mnemonic, err := config.Wallet.RestoreMnemonic(context.Background())
 	if err != nil {
 		config.Wallet.SubmitTx = false
 		return nil, err
 	}
 	var account *cosmosaccount.Account
 	// if we're giving a keyring ring name, with no mnemonic restore
-	if config.Wallet.AddressRestoreMnemonic == "" && config.Wallet.AddressKeyName != "" {
+	if mnemonic == "" && config.Wallet.AddressKeyName != "" {
 		// get account from the keyring
 		acc, err := client.Account(config.Wallet.AddressKeyName)
 		if err != nil {
@@ -66,9 +74,9 @@ func (config *UserConfig) GenerateNodeConfig() (*NodeConfig, error) {
 		} else {
 			account = &acc
 		}
-	} else if config.Wallet.AddressRestoreMnemonic != "" && config.Wallet.AddressKeyName != "" {
+	} else if mnemonic != "" && config.Wallet.AddressKeyName != "" {
 		// restore from mnemonic
-		acc, err := client.AccountRegistry.Import(config.Wallet.AddressKeyName, config.Wallet.AddressRestoreMnemonic, "")
+		acc, err := client.AccountRegistry.Import(config.Wallet.AddressKeyName, mnemonic.Reveal(), "")
 		if err != nil {
 			if err.Error() == "account already exists" {
 				acc, err = client.Account(config.Wallet.AddressKeyName)
@@ -90,8 +98,31 @@ func (config *UserConfig) GenerateNodeConfig() (*NodeConfig, error) {
 	if account == nil {
 		return nil, errors.New("no allora account was loaded")
 	}
+	return NewKeyringSigner(client.AccountRegistry.Keyring, account.Name)
+}
+
+func (config *UserConfig) GenerateNodeConfig() (*NodeConfig, error) {
+	client, err := getAlloraClient(config)
+	if err != nil {
+		config.Wallet.SubmitTx = false
+		return nil, err
+	}
+	var signer Signer
+	if config.Wallet.RemoteSigner != nil {
+		// The key is held by the signing service, the keyring is left untouched
+		signer, err = NewRemoteSigner(context.Background(), *config.Wallet.RemoteSigner, config.Wallet.AddressKeyName)
+		if err != nil {
+			config.Wallet.SubmitTx = false
+			return nil, errorsmod.Wrap(err, "cannot connect to remote signer")
+		}
+	} else {
+		signer, err = config.loadKeyringSigner(client)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	address, err := account.Address(ADDRESS_PREFIX)
+	address, err := sdktypes.Bech32ifyAddressBytes(ADDRESS_PREFIX, SignerAddress(signer))
 	if err != nil {
 		config.Wallet.SubmitTx = false
 		log.Err(err).Msg("could not retrieve allora blockchain address, transactions will not be submitted to chain")
@@ -119,8 +150,11 @@ func (config *UserConfig) GenerateNodeConfig() (*NodeConfig, error) {
 		Address:              address,
 		AddressPrefix:        ADDRESS_PREFIX,
 		DefaultBondDenom:     DEFAULT_BOND_DENOM,
-		Account:              *account,
+		ChainId:              client.Context().ChainID,
+		Signer:               signer,
 		Client:               client,
+		Sequencer:            NewAccountSequencer(),
+		TxResults:            NewTxResultLog(TX_RESULT_LOG_SIZE),
 		EmissionsQueryClient: queryClient,
 		BankQueryClient:      bankClient,
 	}
@@ -131,6 +165,36 @@ func (config *UserConfig) GenerateNodeConfig() (*NodeConfig, error) {
 		Worker:  config.Worker,
 		Reputer: config.Reputer,
 	}
+	fees, err := NewFeeManager(config.Wallet.Fees, DEFAULT_BOND_DENOM, Node.QueryMinGasPrice)
+	if err != nil {
+		return nil, errorsmod.Wrap(err, "invalid fee config")
+	}
+	Node.Chain.Fees = fees
+	if config.Wallet.TxBatchWindowMillis > 0 {
+		Node.Chain.Batcher = NewTxBatcher(
+			time.Duration(config.Wallet.TxBatchWindowMillis)*time.Millisecond,
+			config.Wallet.TxBatchResubmitOthers,
+			config.Wallet.TxErrorPolicies,
+			Node.SendMsgs,
+		)
+	}
 
 	return &Node, nil
 }
+
+// Generates the node config of actors acting with their own wallet instead of the node's
+func (node *NodeConfig) GenerateActorNodeConfig(wallet *WalletConfig) (*NodeConfig, error) {
+	actorConfig := UserConfig{
+		Wallet:  node.Wallet.Override(wallet),
+		Worker:  node.Worker,
+		Reputer: node.Reputer,
+	}
+	actorNode, err := actorConfig.GenerateNodeConfig()
+	if err != nil {
+		return nil, errorsmod.Wrapf(err, "cannot load actor wallet %s", actorConfig.Wallet.AddressKeyName)
+	}
+	if actorNode == nil {
+		return nil, errors.New("cannot connect to the chain for actor wallet " + actorConfig.Wallet.AddressKeyName)
+	}
+	return actorNode, nil
+}
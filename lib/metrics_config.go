@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -43,21 +44,29 @@ func (metrics *Metrics) RegisterMetricsCounters() {
 	}
 }
 
-func (metrics Metrics) StartMetricsServer(port string) {
+// Serves the metrics, and any other handler registered on the default mux, until the server is shut down
+func (metrics Metrics) StartMetricsServer(port string) *http.Server {
 	http.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{Addr: port}
 	go func() {
 		log.Info().Msgf("Starting metrics server on %s", port)
-		if err := http.ListenAndServe(port, nil); err != nil {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Error().Err(err).Msg("Could not start metric server")
 			return
 		}
 
 		log.Info().Msg("Metrics server stopped")
 	}()
+	return server
 }
 
+// Increments the counter, unless it isn't registered, e.g. when running an admin command
 func (metrics *Metrics) IncrementMetricsCounter(counterName string, address string, topic uint64) {
-	counter := metrics.CounterMap[counterName].WithLabelValues(address, strconv.FormatUint(topic, 10))
+	counterVec, ok := metrics.CounterMap[counterName]
+	if !ok {
+		return
+	}
+	counter := counterVec.WithLabelValues(address, strconv.FormatUint(topic, 10))
 	counter.Inc()
 	log.Debug().Msgf("Incremented counter %s for address %s and topic %d", counterName, address, topic)
 }
@@ -0,0 +1,433 @@
+package lib
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"github.com/rs/zerolog/log"
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/sync/singleflight"
+)
+
+var groundTruthBucket = []byte("groundTruths")
+
+// A ground truth fetched for several reputers outlives the cancellation of the reputer which started fetching it, up to this long
+const GROUND_TRUTH_FETCH_TIMEOUT = 2 * time.Minute
+
+// Ground truth fetched from a source at a block height, shared by the reputers of every topic with the same source
+type CachedGroundTruth struct {
+	Source      string // source of the ground truth, with the values of its query redacted
+	BlockHeight BlockHeight
+	Truth       Truth
+	TopicId     emissionstypes.TopicId // topic of the reputer which fetched it
+	FetchedAt   time.Time
+	ExpiresAt   time.Time
+	Hits        int                      // times it was reused instead of fetched again
+	HitTopicIds []emissionstypes.TopicId // topics of the reputers which reused it
+}
+
+// Ground truths by source and block height, stored in the submission ledger so that they outlive restarts.
+// Concurrent fetches of the same ground truth are made once.
+type GroundTruthCache struct {
+	db      *bolt.DB
+	ttl     time.Duration
+	now     func() time.Time
+	fetches singleflight.Group
+
+	// Hits are counted in memory and stored along with the next ground truth fetched, or on Close,
+	// so that reusing a ground truth doesn't write to the ledger
+	mu   sync.Mutex
+	hits map[groundTruthKey]*groundTruthHits
+}
+
+type groundTruthKey struct {
+	source      string // see groundTruthSourceKey
+	blockHeight BlockHeight
+}
+
+type groundTruthHits struct {
+	count    int
+	topicIds []emissionstypes.TopicId
+}
+
+func (h *groundTruthHits) addTo(entry *CachedGroundTruth) {
+	entry.Hits += h.count
+	for _, topicId := range h.topicIds {
+		if !slices.Contains(entry.HitTopicIds, topicId) {
+			entry.HitTopicIds = append(entry.HitTopicIds, topicId)
+		}
+	}
+}
+
+// Opens the cache of ground truths in the ledger, dropping the expired ones.
+// Nil, caching nothing, without a ledger or with a ttl of 0.
+func NewGroundTruthCache(ledger *SubmissionLedger, ttl time.Duration) (*GroundTruthCache, error) {
+	if ledger == nil || ttl <= 0 {
+		return nil, nil
+	}
+	cache := &GroundTruthCache{db: ledger.db, ttl: ttl, now: time.Now, hits: make(map[groundTruthKey]*groundTruthHits)}
+	err := cache.db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(groundTruthBucket)
+		if err != nil {
+			return err
+		}
+		return cache.prune(root)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not open ground truth cache: %w", err)
+	}
+	return cache, nil
+}
+
+// Source of the ground truth of the reputer at the block height: the one its adapter tells, see GroundTruthSourceAdapter,
+// or else the adapter with the topic and parameters of the reputer, shared only by the reputers of the topic.
+func GroundTruthSource(reputer ReputerConfig, blockHeight BlockHeight) string {
+	if adapter, ok := reputer.GroundTruthEntrypoint.(GroundTruthSourceAdapter); ok {
+		return adapter.GroundTruthSource(reputer, blockHeight)
+	}
+	parameters := url.Values{}
+	for key, value := range reputer.GroundTruthParameters {
+		parameters.Set(key, value)
+	}
+	return fmt.Sprintf("adapter://%s/topic/%d?%s", reputer.GroundTruthEntrypoint.Name(), reputer.TopicId, parameters.Encode())
+}
+
+// Sources are stored by hash, as their URLs may hold credentials
+func groundTruthSourceKey(source string) []byte {
+	hash := sha256.Sum256([]byte(source))
+	return hash[:]
+}
+
+// Source with its password and the values of its query redacted, for audits
+func redactGroundTruthSource(source string) string {
+	parsed, err := url.Parse(source)
+	if err != nil || parsed.Scheme == "" {
+		return source
+	}
+	keys := make([]string, 0, len(parsed.Query()))
+	for key := range parsed.Query() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		keys[i] = key + "=" + REDACTED
+	}
+	parsed.RawQuery = ""
+	redacted := parsed.Redacted()
+	if len(keys) > 0 {
+		redacted += "?" + strings.Join(keys, "&")
+	}
+	return redacted
+}
+
+// Ground truth of the source at the block height, fetched and cached unless cached and unexpired.
+// Also returns whether this call fetched it. A fetch shared with other reputers isn't cancelled with the context,
+// but bounded by GROUND_TRUTH_FETCH_TIMEOUT, and the call returns once its context is done.
+// Nil-safe: always fetches without a cache.
+func (c *GroundTruthCache) GetOrFetch(ctx context.Context, source string, blockHeight BlockHeight, topicId emissionstypes.TopicId, fetch func(ctx context.Context) (Truth, error)) (Truth, bool, error) {
+	if c == nil {
+		truth, err := fetch(ctx)
+		return truth, true, err
+	}
+	key := groundTruthSourceKey(source)
+	cached, err := c.get(key, blockHeight)
+	if err != nil {
+		log.Warn().Err(err).Str("source", redactGroundTruthSource(source)).Msg("Could not read ground truth cache, fetching the ground truth")
+	} else if cached != nil {
+		c.hit(key, blockHeight, topicId)
+		return cached.Truth, false, nil
+	}
+
+	fetched := false
+	results := c.fetches.DoChan(fmt.Sprintf("%x@%d", key, blockHeight), func() (any, error) {
+		fetched = true
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), GROUND_TRUTH_FETCH_TIMEOUT)
+		defer cancel()
+		truth, err := fetch(fetchCtx)
+		if err != nil {
+			return nil, err
+		}
+		now := c.now().UTC()
+		entry := CachedGroundTruth{
+			Source:      redactGroundTruthSource(source),
+			BlockHeight: blockHeight,
+			Truth:       truth,
+			TopicId:     topicId,
+			FetchedAt:   now,
+			ExpiresAt:   now.Add(c.ttl),
+		}
+		if err := c.put(key, entry); err != nil {
+			log.Warn().Err(err).Str("source", entry.Source).Msg("Could not cache ground truth")
+		}
+		return truth, nil
+	})
+	select {
+	case result := <-results:
+		if result.Err != nil {
+			return "", fetched, result.Err
+		}
+		if !fetched {
+			// Fetched concurrently for another reputer
+			c.hit(key, blockHeight, topicId)
+		}
+		return result.Val.(Truth), fetched, nil
+	case <-ctx.Done():
+		return "", false, ctx.Err()
+	}
+}
+
+// Unexpired ground truth of the source at the block height, nil if none
+func (c *GroundTruthCache) get(key []byte, blockHeight BlockHeight) (*CachedGroundTruth, error) {
+	var cached *CachedGroundTruth
+	err := c.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(groundTruthBucket).Bucket(key)
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get(nonceKey(blockHeight))
+		if value == nil {
+			return nil
+		}
+		var entry CachedGroundTruth
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return errors.Join(errors.New("corrupt ground truth cache entry"), err)
+		}
+		if !c.now().Before(entry.ExpiresAt) {
+			return nil
+		}
+		cached = &entry
+		return nil
+	})
+	return cached, err
+}
+
+// Counts the reuse of the ground truth by the reputer of the topic, to be stored later
+func (c *GroundTruthCache) hit(key []byte, blockHeight BlockHeight, topicId emissionstypes.TopicId) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hitKey := groundTruthKey{string(key), blockHeight}
+	hits, ok := c.hits[hitKey]
+	if !ok {
+		hits = &groundTruthHits{}
+		c.hits[hitKey] = hits
+	}
+	hits.count++
+	if !slices.Contains(hits.topicIds, topicId) {
+		hits.topicIds = append(hits.topicIds, topicId)
+	}
+}
+
+// Takes the hits counted since they were last stored
+func (c *GroundTruthCache) takeHits() map[groundTruthKey]*groundTruthHits {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hits := c.hits
+	c.hits = make(map[groundTruthKey]*groundTruthHits)
+	return hits
+}
+
+// Counts the hits again, when they could not be stored
+func (c *GroundTruthCache) restoreHits(hits map[groundTruthKey]*groundTruthHits) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for hitKey, restored := range hits {
+		if current, ok := c.hits[hitKey]; ok {
+			restored.count += current.count
+			for _, topicId := range current.topicIds {
+				if !slices.Contains(restored.topicIds, topicId) {
+					restored.topicIds = append(restored.topicIds, topicId)
+				}
+			}
+		}
+		c.hits[hitKey] = restored
+	}
+}
+
+// Adds the hits to their ground truths, if still cached
+func storeHits(root *bolt.Bucket, hits map[groundTruthKey]*groundTruthHits) error {
+	for hitKey, entryHits := range hits {
+		bucket := root.Bucket([]byte(hitKey.source))
+		if bucket == nil {
+			continue
+		}
+		value := bucket.Get(nonceKey(hitKey.blockHeight))
+		if value == nil {
+			continue
+		}
+		var entry CachedGroundTruth
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return errors.Join(errors.New("corrupt ground truth cache entry"), err)
+		}
+		entryHits.addTo(&entry)
+		value, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(nonceKey(hitKey.blockHeight), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stores the hits counted in memory. The ledger is closed by its owner. Nil-safe.
+func (c *GroundTruthCache) Close() error {
+	if c == nil {
+		return nil
+	}
+	hits := c.takeHits()
+	if len(hits) == 0 {
+		return nil
+	}
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		return storeHits(tx.Bucket(groundTruthBucket), hits)
+	})
+	if err != nil {
+		c.restoreHits(hits)
+	}
+	return err
+}
+
+// Stores the ground truth along with the hits counted so far, dropping the expired ones
+func (c *GroundTruthCache) put(key []byte, entry CachedGroundTruth) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	hits := c.takeHits()
+	err = c.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(groundTruthBucket)
+		if err := storeHits(root, hits); err != nil {
+			return err
+		}
+		if err := c.prune(root); err != nil {
+			return err
+		}
+		bucket, err := root.CreateBucketIfNotExists(key)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(nonceKey(entry.BlockHeight), value)
+	})
+	if err != nil {
+		c.restoreHits(hits)
+	}
+	return err
+}
+
+func (c *GroundTruthCache) prune(root *bolt.Bucket) error {
+	now := c.now()
+	var emptySources [][]byte
+	err := root.ForEachBucket(func(source []byte) error {
+		bucket := root.Bucket(source)
+		var expired [][]byte
+		count := 0
+		err := bucket.ForEach(func(key, value []byte) error {
+			count++
+			var entry CachedGroundTruth
+			if err := json.Unmarshal(value, &entry); err != nil || !now.Before(entry.ExpiresAt) {
+				expired = append(expired, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		if count == len(expired) {
+			emptySources = append(emptySources, source)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, source := range emptySources {
+		if err := root.DeleteBucket(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cached ground truths of the block height, and fetched or reused by the reputers of the topic, zero values matching any.
+// Ordered by source, then by block height.
+func (c *GroundTruthCache) List(blockHeight BlockHeight, topicId emissionstypes.TopicId) ([]CachedGroundTruth, error) {
+	entries := []CachedGroundTruth{}
+	if c == nil {
+		return entries, nil
+	}
+	// Hits not stored yet are counted too
+	c.mu.Lock()
+	pending := make(map[groundTruthKey]groundTruthHits, len(c.hits))
+	for hitKey, hits := range c.hits {
+		pending[hitKey] = groundTruthHits{hits.count, slices.Clone(hits.topicIds)}
+	}
+	c.mu.Unlock()
+	err := c.db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket(groundTruthBucket)
+		return root.ForEachBucket(func(source []byte) error {
+			return root.Bucket(source).ForEach(func(key, value []byte) error {
+				var entry CachedGroundTruth
+				if err := json.Unmarshal(value, &entry); err != nil {
+					return errors.Join(errors.New("corrupt ground truth cache entry"), err)
+				}
+				if hits, ok := pending[groundTruthKey{string(source), entry.BlockHeight}]; ok {
+					hits.addTo(&entry)
+				}
+				if (blockHeight == 0 || entry.BlockHeight == blockHeight) &&
+					(topicId == 0 || entry.TopicId == topicId || slices.Contains(entry.HitTopicIds, topicId)) {
+					entries = append(entries, entry)
+				}
+				return nil
+			})
+		})
+	})
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Source < entries[j].Source })
+	return entries, err
+}
+
+// Serves the cached ground truths of the query's blockHeight and topicId as JSON, for audits
+func (c *GroundTruthCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var blockHeight, topicId uint64
+	var err error
+	if value := query.Get("blockHeight"); value != "" {
+		if blockHeight, err = strconv.ParseUint(value, 10, 63); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("topicId"); value != "" {
+		if topicId, err = strconv.ParseUint(value, 10, 63); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := c.List(BlockHeight(blockHeight), topicId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		log.Error().Err(err).Msg("Could not write cached ground truths")
+	}
+}
@@ -0,0 +1,99 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+)
+
+const (
+	KEYRING_BACKEND_TEST = keyring.BackendTest // unencrypted, for development only
+	KEYRING_BACKEND_FILE = keyring.BackendFile // encrypted with the keyring passphrase
+	KEYRING_BACKEND_OS   = keyring.BackendOS   // keychain of the OS
+)
+
+const KEYRING_SERVICE_NAME = "allora" // same as allorad, to share the keys of the os backend
+const KEYRING_MIN_PASSPHRASE_LENGTH = 8
+
+// Substrings of the ids of the chains where the test keyring backend is acceptable
+var testChainIdMarkers = []string{"test", "dev", "local"}
+
+// Keyring backend of the wallet, KEYRING_BACKEND_TEST unless set
+func (wallet WalletConfig) KeyringBackendOrDefault() string {
+	if wallet.KeyringBackend == "" {
+		return KEYRING_BACKEND_TEST
+	}
+	return wallet.KeyringBackend
+}
+
+func IsTestChainId(chainId string) bool {
+	chainId = strings.ToLower(chainId)
+	for _, marker := range testChainIdMarkers {
+		if strings.Contains(chainId, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Refuses to submit txs signed with keys stored unencrypted by the test backend on a chain other than a test one,
+// unless explicitly allowed, in which case a warning is logged
+func (wallet WalletConfig) CheckKeyringForChain(chainId string) error {
+	if wallet.KeyringBackendOrDefault() != KEYRING_BACKEND_TEST || !wallet.SubmitTx || IsTestChainId(chainId) {
+		return nil
+	}
+	if !wallet.AllowTestKeyring {
+		return fmt.Errorf("refusing to submit txs on chain %s with the unencrypted test keyring backend, use the file or os keyring backend, or set allowTestKeyring", chainId)
+	}
+	log.Warn().Str("chainId", chainId).Msg("Submitting txs with keys stored unencrypted by the test keyring backend")
+	return nil
+}
+
+// Answers every passphrase prompt of the keyring with the passphrase.
+// Each read returns exactly one line, as the keyring reads every answer through a new buffered reader.
+type passphraseReader struct {
+	line []byte
+}
+
+func (r passphraseReader) Read(p []byte) (int, error) {
+	return copy(p, r.line), nil
+}
+
+// Opens the keyring of the client with the passphrase of the wallet, in place of the keyring opened by the client,
+// which can only prompt for the passphrase on stdin. Used by the file and os backends, the test one needs no passphrase.
+// When the node runs in an interactive terminal, the keyring prompts for the passphrase there instead.
+func (wallet WalletConfig) openKeyringWithPassphrase(client *cosmosclient.Client, keyringDir string) error {
+	if wallet.KeyringBackendOrDefault() == KEYRING_BACKEND_TEST || wallet.KeyringPassphrase == nil {
+		return nil
+	}
+	provider, err := NewSecretProvider(*wallet.KeyringPassphrase)
+	if err != nil {
+		return err
+	}
+	passphrase, err := provider.GetSecret(context.Background())
+	if err != nil {
+		return fmt.Errorf("cannot get keyring passphrase: %w", err)
+	}
+	if len(passphrase) < KEYRING_MIN_PASSPHRASE_LENGTH {
+		return fmt.Errorf("keyring passphrase must be at least %d characters", KEYRING_MIN_PASSPHRASE_LENGTH)
+	}
+
+	interfaceRegistry := codectypes.NewInterfaceRegistry()
+	cryptocodec.RegisterInterfaces(interfaceRegistry)
+	reader := passphraseReader{line: []byte(passphrase.Reveal() + "\n")}
+	kr, err := keyring.New(KEYRING_SERVICE_NAME, wallet.KeyringBackendOrDefault(), keyringDir, reader, codec.NewProtoCodec(interfaceRegistry))
+	if err != nil {
+		return fmt.Errorf("cannot open %s keyring: %w", wallet.KeyringBackendOrDefault(), err)
+	}
+	client.AccountRegistry.Keyring = kr
+	client.TxFactory = client.TxFactory.WithKeybase(kr)
+	return nil
+}
@@ -0,0 +1,99 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const keyringTestMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art"
+
+func TestIsTestChainId(t *testing.T) {
+	tests := []struct {
+		chainId  string
+		expected bool
+	}{
+		{"allora-testnet-1", true},
+		{"allora-devnet", true},
+		{"localnet", true},
+		{"allora-mainnet-1", false},
+		{"allora-1", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, IsTestChainId(tt.chainId), tt.chainId)
+	}
+}
+
+func TestCheckKeyringForChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		wallet  WalletConfig
+		chainId string
+		err     string
+	}{
+		{name: "test backend on a testnet", wallet: WalletConfig{SubmitTx: true}, chainId: "allora-testnet-1"},
+		{name: "test backend on a mainnet", wallet: WalletConfig{SubmitTx: true}, chainId: "allora-mainnet-1", err: "refusing to submit txs on chain allora-mainnet-1"},
+		{name: "explicit test backend on a mainnet", wallet: WalletConfig{KeyringBackend: KEYRING_BACKEND_TEST, SubmitTx: true}, chainId: "allora-mainnet-1", err: "unencrypted test keyring backend"},
+		{name: "test backend allowed on a mainnet", wallet: WalletConfig{SubmitTx: true, AllowTestKeyring: true}, chainId: "allora-mainnet-1"},
+		{name: "test backend in dry run on a mainnet", wallet: WalletConfig{}, chainId: "allora-mainnet-1"},
+		{name: "file backend on a mainnet", wallet: WalletConfig{KeyringBackend: KEYRING_BACKEND_FILE, SubmitTx: true}, chainId: "allora-mainnet-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.wallet.CheckKeyringForChain(tt.chainId)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestOpenKeyringWithPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	passphraseFile := writeSecretFile(t, "correct horse battery staple\n", 0o600)
+	wallet := WalletConfig{
+		KeyringBackend:    KEYRING_BACKEND_FILE,
+		KeyringPassphrase: &SecretConfig{Provider: SECRET_PROVIDER_FILE, Path: passphraseFile},
+	}
+
+	// The passphrase is set when the first key is added, answering both the prompt and its confirmation
+	client := &cosmosclient.Client{}
+	require.NoError(t, wallet.openKeyringWithPassphrase(client, dir))
+	record, err := client.AccountRegistry.Keyring.NewAccount("node", keyringTestMnemonic, "", hd.CreateHDPath(118, 0, 0).String(), hd.Secp256k1)
+	require.NoError(t, err)
+	assert.Equal(t, client.AccountRegistry.Keyring, client.TxFactory.Keybase(), "txs are signed with the same keyring")
+
+	// Reopened with the same passphrase, the key can sign
+	client = &cosmosclient.Client{}
+	require.NoError(t, wallet.openKeyringWithPassphrase(client, dir))
+	_, pubKey, err := client.AccountRegistry.Keyring.Sign("node", []byte("payload"), signing.SignMode_SIGN_MODE_DIRECT)
+	require.NoError(t, err)
+	expectedPubKey, err := record.GetPubKey()
+	require.NoError(t, err)
+	assert.Equal(t, expectedPubKey, pubKey)
+
+	// With another passphrase, the key can't be unlocked
+	t.Setenv("KEYRING_TEST_PASSPHRASE", "wrong passphrase")
+	wallet.KeyringPassphrase = &SecretConfig{Provider: SECRET_PROVIDER_ENV, EnvVar: "KEYRING_TEST_PASSPHRASE"}
+	client = &cosmosclient.Client{}
+	require.NoError(t, wallet.openKeyringWithPassphrase(client, dir))
+	_, _, err = client.AccountRegistry.Keyring.Sign("node", []byte("payload"), signing.SignMode_SIGN_MODE_DIRECT)
+	assert.ErrorIs(t, err, keyring.ErrMaxPassPhraseAttempts)
+
+	t.Setenv("KEYRING_TEST_PASSPHRASE", "short")
+	assert.ErrorContains(t, wallet.openKeyringWithPassphrase(&cosmosclient.Client{}, dir), "at least 8 characters")
+}
+
+func TestOpenKeyringWithPassphraseTestBackend(t *testing.T) {
+	client := &cosmosclient.Client{}
+	require.NoError(t, WalletConfig{}.openKeyringWithPassphrase(client, t.TempDir()))
+	assert.Nil(t, client.AccountRegistry.Keyring, "the keyring opened by the client is kept")
+}
@@ -0,0 +1,281 @@
+package lib
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"github.com/rs/zerolog/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+const LEDGER_FILE_NAME = "offchain-node-ledger.db" // created in the allora home dir unless LedgerPath is set
+const LEDGER_OPEN_TIMEOUT = 5 * time.Second        // another node holding the ledger makes opening it fail after this delay
+
+var ledgerBucket = []byte("submissions")
+
+type ActorRole string
+
+const (
+	ROLE_WORKER  ActorRole = "worker"
+	ROLE_REPUTER ActorRole = "reputer"
+)
+
+// Outcome of a payload submission
+type SubmissionOutcome string
+
+const (
+	SUBMISSION_PENDING           SubmissionOutcome = "pending"           // payload built and about to be sent
+	SUBMISSION_INCLUDED          SubmissionOutcome = "included"          // tx included in a block with success
+	SUBMISSION_ALREADY_SUBMITTED SubmissionOutcome = "already-submitted" // the chain already had data for the nonce
+	SUBMISSION_DRY_RUN           SubmissionOutcome = "dry-run"           // not sent, SubmitTx is false
+	SUBMISSION_FAILED            SubmissionOutcome = "failed"
+)
+
+// True if the nonce needs no further submission. A dry run sent nothing, so the nonce is still to submit once SubmitTx is on.
+func (o SubmissionOutcome) IsDone() bool {
+	return o == SUBMISSION_INCLUDED || o == SUBMISSION_ALREADY_SUBMITTED
+}
+
+// Payload submitted by an actor for a topic's nonce, and what became of it
+type Submission struct {
+	Role      ActorRole
+	Actor     string // address of the actor
+	TopicId   emissionstypes.TopicId
+	Nonce     BlockHeight
+	Payload   json.RawMessage // msg sent to the chain
+	Signature []byte          // signature of the payload bundle
+	TxHash    string
+	Height    int64 // height of the block including the tx
+	Fee       string
+	Outcome   SubmissionOutcome
+	Error     string
+	Attempts  int
+	UpdatedAt time.Time
+}
+
+// Selects submissions, zero fields matching any
+type SubmissionFilter struct {
+	Role      ActorRole
+	Actor     string
+	TopicId   emissionstypes.TopicId
+	FromNonce BlockHeight
+	ToNonce   BlockHeight
+	Outcome   SubmissionOutcome
+}
+
+func (f SubmissionFilter) matches(s *Submission) bool {
+	return (f.Role == "" || f.Role == s.Role) &&
+		(f.Actor == "" || f.Actor == s.Actor) &&
+		(f.TopicId == 0 || f.TopicId == s.TopicId) &&
+		(f.FromNonce == 0 || s.Nonce >= f.FromNonce) &&
+		(f.ToNonce == 0 || s.Nonce <= f.ToNonce) &&
+		(f.Outcome == "" || f.Outcome == s.Outcome)
+}
+
+// On-disk record of every payload submission per actor, topic and nonce,
+// so that actors resume where they left off after a restart and submissions can be audited
+type SubmissionLedger struct {
+	db *bolt.DB
+}
+
+// Default location of the ledger, in the allora home dir
+func (wallet WalletConfig) LedgerFilePath() string {
+	if wallet.LedgerPath != "" {
+		return wallet.LedgerPath
+	}
+	return filepath.Join(wallet.HomeDir(), LEDGER_FILE_NAME)
+}
+
+func OpenSubmissionLedger(path string, readOnly bool) (*SubmissionLedger, error) {
+	if !readOnly {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: LEDGER_OPEN_TIMEOUT, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("could not open submission ledger %s: %w", path, err)
+	}
+	if !readOnly {
+		err = db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(ledgerBucket)
+			return err
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &SubmissionLedger{db: db}, nil
+}
+
+func (l *SubmissionLedger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.db.Close()
+}
+
+// Submissions are grouped by role, actor and topic, and ordered by nonce
+func actorTopicKey(role ActorRole, actor string, topicId emissionstypes.TopicId) []byte {
+	return []byte(string(role) + "/" + actor + "/" + strconv.FormatUint(topicId, 10))
+}
+
+func nonceKey(nonce BlockHeight) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(nonce))
+	return key
+}
+
+// Records the submission, replacing the previous record of the same nonce. Nil-safe: ignored without a ledger.
+func (l *SubmissionLedger) Record(submission Submission) error {
+	if l == nil {
+		return nil
+	}
+	if submission.UpdatedAt.IsZero() {
+		submission.UpdatedAt = time.Now().UTC()
+	}
+	value, err := json.Marshal(submission)
+	if err != nil {
+		return err
+	}
+	return l.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(ledgerBucket).CreateBucketIfNotExists(actorTopicKey(submission.Role, submission.Actor, submission.TopicId))
+		if err != nil {
+			return err
+		}
+		return bucket.Put(nonceKey(submission.Nonce), value)
+	})
+}
+
+// Submission of the nonce, nil if none was recorded
+func (l *SubmissionLedger) Get(role ActorRole, actor string, topicId emissionstypes.TopicId, nonce BlockHeight) (*Submission, error) {
+	if l == nil {
+		return nil, nil
+	}
+	var submission *Submission
+	err := l.db.View(func(tx *bolt.Tx) error {
+		bucket := l.actorTopicBucket(tx, role, actor, topicId)
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get(nonceKey(nonce))
+		if value == nil {
+			return nil
+		}
+		submission = &Submission{}
+		return json.Unmarshal(value, submission)
+	})
+	return submission, err
+}
+
+func (l *SubmissionLedger) actorTopicBucket(tx *bolt.Tx, role ActorRole, actor string, topicId emissionstypes.TopicId) *bolt.Bucket {
+	root := tx.Bucket(ledgerBucket)
+	if root == nil {
+		return nil
+	}
+	return root.Bucket(actorTopicKey(role, actor, topicId))
+}
+
+// Highest nonce of the actor and topic needing no further submission, 0 if none
+func (l *SubmissionLedger) LatestDoneNonce(role ActorRole, actor string, topicId emissionstypes.TopicId) (BlockHeight, error) {
+	if l == nil {
+		return 0, nil
+	}
+	var latest BlockHeight
+	err := l.db.View(func(tx *bolt.Tx) error {
+		bucket := l.actorTopicBucket(tx, role, actor, topicId)
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		for key, value := cursor.Last(); key != nil; key, value = cursor.Prev() {
+			var submission Submission
+			if err := json.Unmarshal(value, &submission); err != nil {
+				return err
+			}
+			if submission.Outcome.IsDone() {
+				latest = submission.Nonce
+				return nil
+			}
+		}
+		return nil
+	})
+	return latest, err
+}
+
+// Submissions matching the filter, by role, actor and topic, then by nonce
+func (l *SubmissionLedger) List(filter SubmissionFilter) ([]Submission, error) {
+	submissions := []Submission{}
+	if l == nil {
+		return submissions, nil
+	}
+	err := l.db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket(ledgerBucket)
+		if root == nil {
+			return nil
+		}
+		return root.ForEachBucket(func(name []byte) error {
+			if filter.Role != "" && !strings.HasPrefix(string(name), string(filter.Role)+"/") {
+				return nil
+			}
+			return root.Bucket(name).ForEach(func(key, value []byte) error {
+				var submission Submission
+				if err := json.Unmarshal(value, &submission); err != nil {
+					return errors.Join(fmt.Errorf("corrupt ledger entry in %s", name), err)
+				}
+				if filter.matches(&submission) {
+					submissions = append(submissions, submission)
+				}
+				return nil
+			})
+		})
+	})
+	return submissions, err
+}
+
+// Serves the submissions matching the query's role, actor, topicId, fromNonce, toNonce and outcome as JSON, for audits
+func (l *SubmissionLedger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := SubmissionFilter{
+		Role:    ActorRole(query.Get("role")),
+		Actor:   query.Get("actor"),
+		Outcome: SubmissionOutcome(query.Get("outcome")),
+	}
+	var err error
+	parseUint := func(name string) uint64 {
+		value := query.Get(name)
+		if value == "" || err != nil {
+			return 0
+		}
+		var parsed uint64
+		parsed, err = strconv.ParseUint(value, 10, 63)
+		return parsed
+	}
+	filter.TopicId = parseUint("topicId")
+	filter.FromNonce = BlockHeight(parseUint("fromNonce"))
+	filter.ToNonce = BlockHeight(parseUint("toNonce"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	submissions, err := l.List(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(submissions); err != nil {
+		log.Error().Err(err).Msg("Could not write submissions")
+	}
+}
@@ -0,0 +1,140 @@
+package lib
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ledgerTestActor = "allo1actor"
+
+func openTestLedger(t *testing.T) (*SubmissionLedger, string) {
+	path := filepath.Join(t.TempDir(), "ledger", LEDGER_FILE_NAME)
+	ledger, err := OpenSubmissionLedger(path, false)
+	require.NoError(t, err)
+	t.Cleanup(func() { ledger.Close() })
+	return ledger, path
+}
+
+func TestSubmissionLedger(t *testing.T) {
+	ledger, path := openTestLedger(t)
+
+	submissions := []Submission{
+		{Role: ROLE_WORKER, Actor: ledgerTestActor, TopicId: 1, Nonce: 100, Outcome: SUBMISSION_INCLUDED, TxHash: "AA", Height: 101},
+		{Role: ROLE_WORKER, Actor: ledgerTestActor, TopicId: 1, Nonce: 200, Outcome: SUBMISSION_ALREADY_SUBMITTED},
+		{Role: ROLE_WORKER, Actor: ledgerTestActor, TopicId: 1, Nonce: 300, Outcome: SUBMISSION_FAILED, Error: "out of gas"},
+		{Role: ROLE_WORKER, Actor: ledgerTestActor, TopicId: 2, Nonce: 150, Outcome: SUBMISSION_PENDING},
+		{Role: ROLE_REPUTER, Actor: ledgerTestActor, TopicId: 1, Nonce: 90, Outcome: SUBMISSION_INCLUDED, Payload: json.RawMessage(`{"sender":"allo1actor"}`), Signature: []byte{1, 2}},
+	}
+	for _, submission := range submissions {
+		require.NoError(t, ledger.Record(submission))
+	}
+
+	got, err := ledger.Get(ROLE_REPUTER, ledgerTestActor, 1, 90)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.JSONEq(t, `{"sender":"allo1actor"}`, string(got.Payload))
+	assert.Equal(t, []byte{1, 2}, got.Signature)
+	assert.False(t, got.UpdatedAt.IsZero())
+
+	missing, err := ledger.Get(ROLE_WORKER, ledgerTestActor, 1, 90)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	latest, err := ledger.LatestDoneNonce(ROLE_WORKER, ledgerTestActor, 1)
+	require.NoError(t, err)
+	assert.Equal(t, BlockHeight(200), latest, "failed submissions are not done")
+	latest, err = ledger.LatestDoneNonce(ROLE_WORKER, ledgerTestActor, 2)
+	require.NoError(t, err)
+	assert.Equal(t, BlockHeight(0), latest, "pending submissions are not done")
+
+	// A later attempt replaces the record of the nonce
+	require.NoError(t, ledger.Record(Submission{Role: ROLE_WORKER, Actor: ledgerTestActor, TopicId: 1, Nonce: 300, Outcome: SUBMISSION_INCLUDED, Attempts: 2}))
+	latest, err = ledger.LatestDoneNonce(ROLE_WORKER, ledgerTestActor, 1)
+	require.NoError(t, err)
+	assert.Equal(t, BlockHeight(300), latest)
+
+	// Submissions survive a restart
+	require.NoError(t, ledger.Close())
+	reopened, err := OpenSubmissionLedger(path, false)
+	require.NoError(t, err)
+	defer reopened.Close()
+	got, err = reopened.Get(ROLE_WORKER, ledgerTestActor, 1, 300)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, 2, got.Attempts)
+}
+
+func TestSubmissionLedgerList(t *testing.T) {
+	ledger, _ := openTestLedger(t)
+	for _, submission := range []Submission{
+		{Role: ROLE_WORKER, Actor: ledgerTestActor, TopicId: 1, Nonce: 100, Outcome: SUBMISSION_INCLUDED},
+		{Role: ROLE_WORKER, Actor: ledgerTestActor, TopicId: 1, Nonce: 200, Outcome: SUBMISSION_FAILED},
+		{Role: ROLE_WORKER, Actor: "allo1other", TopicId: 1, Nonce: 100, Outcome: SUBMISSION_INCLUDED},
+		{Role: ROLE_REPUTER, Actor: ledgerTestActor, TopicId: 2, Nonce: 150, Outcome: SUBMISSION_INCLUDED},
+	} {
+		require.NoError(t, ledger.Record(submission))
+	}
+
+	tests := []struct {
+		name   string
+		filter SubmissionFilter
+		nonces []BlockHeight
+	}{
+		{name: "all", filter: SubmissionFilter{}, nonces: []BlockHeight{150, 100, 200, 100}},
+		{name: "role", filter: SubmissionFilter{Role: ROLE_REPUTER}, nonces: []BlockHeight{150}},
+		{name: "actor", filter: SubmissionFilter{Actor: ledgerTestActor}, nonces: []BlockHeight{150, 100, 200}},
+		{name: "topic", filter: SubmissionFilter{TopicId: 1}, nonces: []BlockHeight{100, 200, 100}},
+		{name: "nonce range", filter: SubmissionFilter{FromNonce: 120, ToNonce: 200}, nonces: []BlockHeight{150, 200}},
+		{name: "outcome", filter: SubmissionFilter{Outcome: SUBMISSION_FAILED}, nonces: []BlockHeight{200}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			submissions, err := ledger.List(tt.filter)
+			require.NoError(t, err)
+			nonces := []BlockHeight{}
+			for _, submission := range submissions {
+				nonces = append(nonces, submission.Nonce)
+			}
+			assert.Equal(t, tt.nonces, nonces)
+		})
+	}
+}
+
+func TestSubmissionLedgerServeHTTP(t *testing.T) {
+	ledger, _ := openTestLedger(t)
+	require.NoError(t, ledger.Record(Submission{Role: ROLE_WORKER, Actor: ledgerTestActor, TopicId: 1, Nonce: 100, Outcome: SUBMISSION_INCLUDED, TxHash: "AA"}))
+	require.NoError(t, ledger.Record(Submission{Role: ROLE_WORKER, Actor: ledgerTestActor, TopicId: 2, Nonce: 100, Outcome: SUBMISSION_INCLUDED, TxHash: "BB"}))
+
+	recorder := httptest.NewRecorder()
+	ledger.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/submissions?topicId=2&role=worker", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var submissions []Submission
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &submissions))
+	require.Len(t, submissions, 1)
+	assert.Equal(t, "BB", submissions[0].TxHash)
+
+	recorder = httptest.NewRecorder()
+	ledger.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/submissions?topicId=two", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestSubmissionLedgerNil(t *testing.T) {
+	var ledger *SubmissionLedger
+	assert.NoError(t, ledger.Record(Submission{Role: ROLE_WORKER, Nonce: 1}))
+	got, err := ledger.Get(ROLE_WORKER, ledgerTestActor, 1, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	latest, err := ledger.LatestDoneNonce(ROLE_WORKER, ledgerTestActor, 1)
+	assert.NoError(t, err)
+	assert.Zero(t, latest)
+	submissions, err := ledger.List(SubmissionFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, submissions)
+	assert.NoError(t, ledger.Close())
+}
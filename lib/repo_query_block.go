@@ -3,14 +3,13 @@ package lib
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
 	"github.com/rs/zerolog/log"
 )
 
-func (node *NodeConfig) GetReputerValuesAtBlock(topicId emissionstypes.TopicId, nonce BlockHeight) (*emissionstypes.ValueBundle, error) {
-	ctx := context.Background()
-
+func (node *NodeConfig) GetReputerValuesAtBlock(ctx context.Context, topicId emissionstypes.TopicId, nonce BlockHeight) (*emissionstypes.ValueBundle, error) {
 	req := &emissionstypes.GetNetworkInferencesAtBlockRequest{
 		TopicId:                  topicId,
 		BlockHeightLastInference: nonce,
@@ -29,3 +28,21 @@ func (node *NodeConfig) GetReputerValuesAtBlock(topicId emissionstypes.TopicId,
 
 	return res.NetworkInferences, nil
 }
+
+// Returns the height and time of the latest block known to the node
+func (node *NodeConfig) GetLatestBlock(ctx context.Context) (BlockHeight, time.Time, error) {
+	status, err := node.Chain.Client.RPC.Status(ctx)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return status.SyncInfo.LatestBlockHeight, status.SyncInfo.LatestBlockTime, nil
+}
+
+// Returns the time of the block at the given height
+func (node *NodeConfig) GetBlockTime(ctx context.Context, height BlockHeight) (time.Time, error) {
+	res, err := node.Chain.Client.RPC.Header(ctx, &height)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return res.Header.Time, nil
+}
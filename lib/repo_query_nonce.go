@@ -2,13 +2,12 @@ package lib
 
 import (
 	"context"
+	"slices"
 
 	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
 )
 
-func (node *NodeConfig) GetLatestOpenWorkerNonceByTopicId(topicId emissionstypes.TopicId) (*emissionstypes.Nonce, error) {
-	ctx := context.Background()
-
+func (node *NodeConfig) GetLatestOpenWorkerNonceByTopicId(ctx context.Context, topicId emissionstypes.TopicId) (*emissionstypes.Nonce, error) {
 	res, err := node.Chain.EmissionsQueryClient.GetUnfulfilledWorkerNonces(
 		ctx,
 		&emissionstypes.GetUnfulfilledWorkerNoncesRequest{TopicId: topicId},
@@ -24,20 +23,58 @@ func (node *NodeConfig) GetLatestOpenWorkerNonceByTopicId(topicId emissionstypes
 	return res.Nonces.Nonces[0], nil
 }
 
-func (node *NodeConfig) GetOldestReputerNonceByTopicId(topicId emissionstypes.TopicId) (BlockHeight, error) {
-	ctx := context.Background()
+// Returns the block heights of all unfulfilled worker nonces of the topic, oldest first
+func (node *NodeConfig) GetOpenWorkerNoncesByTopicId(ctx context.Context, topicId emissionstypes.TopicId) ([]BlockHeight, error) {
+	res, err := node.Chain.EmissionsQueryClient.GetUnfulfilledWorkerNonces(
+		ctx,
+		&emissionstypes.GetUnfulfilledWorkerNoncesRequest{TopicId: topicId},
+	)
+	if err != nil {
+		return nil, err
+	}
 
+	nonces := make([]BlockHeight, 0, len(res.Nonces.Nonces))
+	for _, nonce := range res.Nonces.Nonces {
+		if nonce == nil {
+			continue
+		}
+		nonces = append(nonces, nonce.BlockHeight)
+	}
+	slices.Sort(nonces)
+	return nonces, nil
+}
+
+// Returns the block heights of all unfulfilled reputer nonces of the topic, oldest first
+func (node *NodeConfig) GetOpenReputerNoncesByTopicId(ctx context.Context, topicId emissionstypes.TopicId) ([]BlockHeight, error) {
 	res, err := node.Chain.EmissionsQueryClient.GetUnfulfilledReputerNonces(
 		ctx,
 		&emissionstypes.GetUnfulfilledReputerNoncesRequest{TopicId: topicId},
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	nonces := make([]BlockHeight, 0, len(res.Nonces.Nonces))
+	for _, nonce := range res.Nonces.Nonces {
+		if nonce == nil || nonce.ReputerNonce == nil {
+			continue
+		}
+		nonces = append(nonces, nonce.ReputerNonce.BlockHeight)
+	}
+	// Per `AddReputerNonce()` in `allora-chain/x/emissions/keeper.go`, the oldest nonce is last,
+	// but sort anyway rather than rely on the storage order
+	slices.Sort(nonces)
+	return nonces, nil
+}
+
+func (node *NodeConfig) GetOldestReputerNonceByTopicId(ctx context.Context, topicId emissionstypes.TopicId) (BlockHeight, error) {
+	nonces, err := node.GetOpenReputerNoncesByTopicId(ctx, topicId)
 	if err != nil {
 		return 0, err
 	}
 
-	if len(res.Nonces.Nonces) == 0 {
+	if len(nonces) == 0 {
 		return 0, nil
 	}
-	// Per `AddWorkerNonce()` in `allora-chain/x/emissions/keeper.go`, the oldest nonce is last
-	return res.Nonces.Nonces[len(res.Nonces.Nonces)-1].ReputerNonce.BlockHeight, nil
+	return nonces[0], nil
 }
@@ -5,13 +5,15 @@ import (
 
 	cosmossdk_io_math "cosmossdk.io/math"
 	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (node *NodeConfig) GetReputerStakeInTopic(
+	ctx context.Context,
 	topicId emissionstypes.TopicId,
 	reputer Address,
 ) (cosmossdk_io_math.Int, error) {
-	ctx := context.Background()
 	resp, err := node.Chain.EmissionsQueryClient.GetStakeFromReputerInTopicInSelf(ctx, &emissionstypes.GetStakeFromReputerInTopicInSelfRequest{
 		ReputerAddress: reputer,
 		TopicId:        topicId,
@@ -21,3 +23,21 @@ func (node *NodeConfig) GetReputerStakeInTopic(
 	}
 	return resp.Amount, nil
 }
+
+// Returns the pending removal of stake of the reputer in the topic, nil if there is none
+func (node *NodeConfig) GetStakeRemoval(ctx context.Context, topicId emissionstypes.TopicId, reputer Address) (*emissionstypes.StakeRemovalInfo, error) {
+	resp, err := node.Chain.EmissionsQueryClient.GetStakeRemovalForReputerAndTopicId(ctx, &emissionstypes.GetStakeRemovalForReputerAndTopicIdRequest{
+		Reputer: reputer,
+		TopicId: topicId,
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.StakeRemovalInfo == nil || resp.StakeRemovalInfo.Amount.IsNil() || resp.StakeRemovalInfo.Amount.IsZero() {
+		return nil, nil
+	}
+	return resp.StakeRemovalInfo, nil
+}
@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	cosmossdk_io_math "cosmossdk.io/math"
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+)
+
+// Standing of an actor in its topic, as reported by the chain
+type ActorStatus struct {
+	Role         ActorRole
+	TopicId      emissionstypes.TopicId
+	Address      Address
+	Registered   bool
+	Stake        *cosmossdk_io_math.Int           // stake of a reputer in the topic, nil for a worker
+	StakeRemoval *emissionstypes.StakeRemovalInfo // pending removal of stake of a reputer, nil if none
+	OpenNonces   []BlockHeight                    // unfulfilled nonces of the role in the topic, oldest first
+}
+
+// Queries the registration, stake and unfulfilled nonces of the node's account as a worker or reputer in the topic
+func (node *NodeConfig) GetActorStatus(ctx context.Context, role ActorRole, topicId emissionstypes.TopicId) (ActorStatus, error) {
+	status := ActorStatus{Role: role, TopicId: topicId, Address: node.Chain.Address}
+	var err error
+	switch role {
+	case ROLE_WORKER:
+		if status.Registered, err = node.IsWorkerRegistered(ctx, topicId); err != nil {
+			return status, fmt.Errorf("cannot get worker registration: %w", err)
+		}
+		if status.OpenNonces, err = node.GetOpenWorkerNoncesByTopicId(ctx, topicId); err != nil {
+			return status, fmt.Errorf("cannot get unfulfilled worker nonces: %w", err)
+		}
+	case ROLE_REPUTER:
+		if status.Registered, err = node.IsReputerRegistered(ctx, topicId); err != nil {
+			return status, fmt.Errorf("cannot get reputer registration: %w", err)
+		}
+		stake, err := node.GetReputerStakeInTopic(ctx, topicId, node.Chain.Address)
+		if err != nil {
+			return status, fmt.Errorf("cannot get reputer stake: %w", err)
+		}
+		status.Stake = &stake
+		if status.StakeRemoval, err = node.GetStakeRemoval(ctx, topicId, node.Chain.Address); err != nil {
+			return status, fmt.Errorf("cannot get pending stake removal: %w", err)
+		}
+		if status.OpenNonces, err = node.GetOpenReputerNoncesByTopicId(ctx, topicId); err != nil {
+			return status, fmt.Errorf("cannot get unfulfilled reputer nonces: %w", err)
+		}
+	default:
+		return status, fmt.Errorf("unknown role %q", role)
+	}
+	return status, nil
+}
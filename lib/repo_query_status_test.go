@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"context"
+	"testing"
+
+	cosmossdk_io_math "cosmossdk.io/math"
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Emissions queries of a chain where allo1worker is a worker and allo1reputer a reputer of topic 1
+type fakeEmissionsQueryClient struct {
+	emissionstypes.QueryServiceClient
+	stakeRemoval *emissionstypes.StakeRemovalInfo
+}
+
+func (c fakeEmissionsQueryClient) IsWorkerRegisteredInTopicId(ctx context.Context, req *emissionstypes.IsWorkerRegisteredInTopicIdRequest, opts ...grpc.CallOption) (*emissionstypes.IsWorkerRegisteredInTopicIdResponse, error) {
+	return &emissionstypes.IsWorkerRegisteredInTopicIdResponse{IsRegistered: req.TopicId == 1 && req.Address == "allo1worker"}, nil
+}
+
+func (c fakeEmissionsQueryClient) IsReputerRegisteredInTopicId(ctx context.Context, req *emissionstypes.IsReputerRegisteredInTopicIdRequest, opts ...grpc.CallOption) (*emissionstypes.IsReputerRegisteredInTopicIdResponse, error) {
+	return &emissionstypes.IsReputerRegisteredInTopicIdResponse{IsRegistered: req.TopicId == 1 && req.Address == "allo1reputer"}, nil
+}
+
+func (c fakeEmissionsQueryClient) GetStakeFromReputerInTopicInSelf(ctx context.Context, req *emissionstypes.GetStakeFromReputerInTopicInSelfRequest, opts ...grpc.CallOption) (*emissionstypes.GetStakeFromReputerInTopicInSelfResponse, error) {
+	return &emissionstypes.GetStakeFromReputerInTopicInSelfResponse{Amount: cosmossdk_io_math.NewInt(5000)}, nil
+}
+
+func (c fakeEmissionsQueryClient) GetStakeRemovalForReputerAndTopicId(ctx context.Context, req *emissionstypes.GetStakeRemovalForReputerAndTopicIdRequest, opts ...grpc.CallOption) (*emissionstypes.GetStakeRemovalForReputerAndTopicIdResponse, error) {
+	if c.stakeRemoval == nil {
+		return nil, status.Error(codes.NotFound, "stake removal not found")
+	}
+	return &emissionstypes.GetStakeRemovalForReputerAndTopicIdResponse{StakeRemovalInfo: c.stakeRemoval}, nil
+}
+
+func (c fakeEmissionsQueryClient) GetUnfulfilledWorkerNonces(ctx context.Context, req *emissionstypes.GetUnfulfilledWorkerNoncesRequest, opts ...grpc.CallOption) (*emissionstypes.GetUnfulfilledWorkerNoncesResponse, error) {
+	// Latest first, as stored by the chain
+	return &emissionstypes.GetUnfulfilledWorkerNoncesResponse{Nonces: &emissionstypes.Nonces{Nonces: []*emissionstypes.Nonce{{BlockHeight: 300}, {BlockHeight: 200}}}}, nil
+}
+
+func (c fakeEmissionsQueryClient) GetUnfulfilledReputerNonces(ctx context.Context, req *emissionstypes.GetUnfulfilledReputerNoncesRequest, opts ...grpc.CallOption) (*emissionstypes.GetUnfulfilledReputerNoncesResponse, error) {
+	return &emissionstypes.GetUnfulfilledReputerNoncesResponse{Nonces: &emissionstypes.ReputerRequestNonces{Nonces: []*emissionstypes.ReputerRequestNonce{
+		{ReputerNonce: &emissionstypes.Nonce{BlockHeight: 100}},
+	}}}, nil
+}
+
+func TestGetActorStatus(t *testing.T) {
+	newNode := func(address string, queries fakeEmissionsQueryClient) *NodeConfig {
+		return &NodeConfig{
+			Chain:   ChainConfig{Address: address, EmissionsQueryClient: queries},
+			Wallet:  WalletConfig{Address: address},
+			Worker:  []WorkerConfig{{TopicId: 1}},
+			Reputer: []ReputerConfig{{TopicId: 1}},
+		}
+	}
+	ctx := context.Background()
+
+	status, err := newNode("allo1worker", fakeEmissionsQueryClient{}).GetActorStatus(ctx, ROLE_WORKER, 1)
+	require.NoError(t, err)
+	assert.Equal(t, ActorStatus{Role: ROLE_WORKER, TopicId: 1, Address: "allo1worker", Registered: true, OpenNonces: []BlockHeight{200, 300}}, status)
+
+	status, err = newNode("allo1reputer", fakeEmissionsQueryClient{}).GetActorStatus(ctx, ROLE_REPUTER, 1)
+	require.NoError(t, err)
+	assert.True(t, status.Registered)
+	require.NotNil(t, status.Stake)
+	assert.Equal(t, "5000", status.Stake.String())
+	assert.Nil(t, status.StakeRemoval, "no pending removal")
+	assert.Equal(t, []BlockHeight{100}, status.OpenNonces)
+
+	removal := &emissionstypes.StakeRemovalInfo{TopicId: 1, Reputer: "allo1reputer", Amount: cosmossdk_io_math.NewInt(1000), BlockRemovalCompleted: 500}
+	status, err = newNode("allo1reputer", fakeEmissionsQueryClient{stakeRemoval: removal}).GetActorStatus(ctx, ROLE_REPUTER, 1)
+	require.NoError(t, err)
+	assert.Equal(t, removal, status.StakeRemoval)
+
+	status, err = newNode("allo1reputer", fakeEmissionsQueryClient{}).GetActorStatus(ctx, ROLE_WORKER, 1)
+	require.NoError(t, err)
+	assert.False(t, status.Registered, "registered as a reputer only")
+
+	_, err = newNode("allo1worker", fakeEmissionsQueryClient{}).GetActorStatus(ctx, "validator", 1)
+	assert.ErrorContains(t, err, `unknown role "validator"`)
+}
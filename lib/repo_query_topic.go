@@ -0,0 +1,19 @@
+package lib
+
+import (
+	"context"
+	"errors"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+)
+
+func (node *NodeConfig) GetTopicInfo(ctx context.Context, topicId emissionstypes.TopicId) (*emissionstypes.Topic, error) {
+	res, err := node.Chain.EmissionsQueryClient.GetTopic(ctx, &emissionstypes.GetTopicRequest{TopicId: topicId})
+	if err != nil {
+		return nil, err
+	}
+	if res.Topic == nil {
+		return nil, errors.New("topic not found")
+	}
+	return res.Topic, nil
+}
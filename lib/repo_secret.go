@@ -0,0 +1,198 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	SECRET_PROVIDER_FILE  = "file"
+	SECRET_PROVIDER_ENV   = "env"
+	SECRET_PROVIDER_VAULT = "vault"
+)
+
+const DEFAULT_VAULT_SECRET_KEY = "mnemonic"
+const VAULT_TOKEN_ENV_VAR = "VAULT_TOKEN"
+const SECRET_REQUEST_TIMEOUT_SECONDS = 10
+
+const REDACTED = "[REDACTED]"
+
+// A sensitive value of the config, e.g. a mnemonic, redacted when printed, logged or marshalled.
+// Reveal returns the actual value.
+type Secret string
+
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return REDACTED
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("%q", s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Source of a secret
+type SecretProvider interface {
+	GetSecret(ctx context.Context) (Secret, error)
+}
+
+func NewSecretProvider(config SecretConfig) (SecretProvider, error) {
+	switch config.Provider {
+	case SECRET_PROVIDER_FILE:
+		return FileSecretProvider{Path: config.Path}, nil
+	case SECRET_PROVIDER_ENV:
+		return EnvSecretProvider{EnvVar: config.EnvVar}, nil
+	case SECRET_PROVIDER_VAULT:
+		key := config.Key
+		if key == "" {
+			key = DEFAULT_VAULT_SECRET_KEY
+		}
+		return VaultSecretProvider{
+			Address:   strings.TrimSuffix(config.Address, "/"),
+			Path:      strings.Trim(config.Path, "/"),
+			Key:       key,
+			TokenFile: config.TokenFile,
+			Client:    &http.Client{Timeout: SECRET_REQUEST_TIMEOUT_SECONDS * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown secret provider %q, expected %s, %s or %s", config.Provider, SECRET_PROVIDER_FILE, SECRET_PROVIDER_ENV, SECRET_PROVIDER_VAULT)
+	}
+}
+
+// Reads a secret from a file which only its owner can access, trimming the surrounding whitespace
+func ReadSecretFile(path string) (Secret, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read secret file: %w", err)
+	}
+	if mode := info.Mode().Perm(); mode&0o077 != 0 {
+		return "", fmt.Errorf("secret file %s is accessible by other users (mode %04o), restrict it with chmod 600", path, mode)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read secret file: %w", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return Secret(secret), nil
+}
+
+// Secret read from a file, see ReadSecretFile
+type FileSecretProvider struct {
+	Path string
+}
+
+func (p FileSecretProvider) GetSecret(ctx context.Context) (Secret, error) {
+	return ReadSecretFile(p.Path)
+}
+
+// Secret read from an environment variable
+type EnvSecretProvider struct {
+	EnvVar string
+}
+
+func (p EnvSecretProvider) GetSecret(ctx context.Context) (Secret, error) {
+	value, ok := os.LookupEnv(p.EnvVar)
+	if !ok || value == "" {
+		return "", fmt.Errorf("environment variable %s of the secret is not set", p.EnvVar)
+	}
+	return Secret(value), nil
+}
+
+// Secret read from a key-value secrets engine of Vault, or any server with the same API.
+// Both versions of the engine are supported: the data of a KV v2 secret is nested under "data".
+type VaultSecretProvider struct {
+	Address   string
+	Path      string
+	Key       string
+	TokenFile string // the token is read from VAULT_TOKEN_ENV_VAR if empty
+	Client    *http.Client
+}
+
+func (p VaultSecretProvider) token() (Secret, error) {
+	if p.TokenFile != "" {
+		return ReadSecretFile(p.TokenFile)
+	}
+	return EnvSecretProvider{EnvVar: VAULT_TOKEN_ENV_VAR}.GetSecret(context.Background())
+}
+
+func (p VaultSecretProvider) GetSecret(ctx context.Context) (Secret, error) {
+	token, err := p.token()
+	if err != nil {
+		return "", fmt.Errorf("no vault token: %w", err)
+	}
+	url := fmt.Sprintf("%s/v1/%s", p.Address, p.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid vault secret url: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token.Reveal())
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault secret request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// The body is left out, as the errors of Vault may echo the request
+		return "", fmt.Errorf("vault secret request to %s failed with status %d", url, resp.StatusCode)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid vault secret response: %w", err)
+	}
+	data := body.Data
+	if nested, ok := data["data"]; ok && data["metadata"] != nil {
+		data = nil
+		if err := json.Unmarshal(nested, &data); err != nil {
+			return "", fmt.Errorf("invalid vault KV v2 secret data: %w", err)
+		}
+	}
+	var secret string
+	if raw, ok := data[p.Key]; !ok || json.Unmarshal(raw, &secret) != nil || secret == "" {
+		return "", fmt.Errorf("vault secret %s has no string value at key %q", p.Path, p.Key)
+	}
+	return Secret(secret), nil
+}
+
+// Mnemonic to restore the key of the wallet from, read from whichever of AddressRestoreMnemonic,
+// AddressRestoreMnemonicFile and AddressRestoreMnemonicSecret is set.
+// Empty if none is, for the key to be loaded from the keyring as is.
+func (wallet WalletConfig) RestoreMnemonic(ctx context.Context) (Secret, error) {
+	switch {
+	case wallet.AddressRestoreMnemonic != "":
+		return wallet.AddressRestoreMnemonic, nil
+	case wallet.AddressRestoreMnemonicFile != "":
+		return ReadSecretFile(wallet.AddressRestoreMnemonicFile)
+	case wallet.AddressRestoreMnemonicSecret != nil:
+		provider, err := NewSecretProvider(*wallet.AddressRestoreMnemonicSecret)
+		if err != nil {
+			return "", err
+		}
+		mnemonic, err := provider.GetSecret(ctx)
+		if err != nil {
+			return "", fmt.Errorf("cannot get mnemonic from secret provider: %w", err)
+		}
+		return mnemonic, nil
+	default:
+		return "", nil
+	}
+}
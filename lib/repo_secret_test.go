@@ -0,0 +1,199 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const secretTestMnemonic = "mnemonic words which must never be logged"
+
+func writeSecretFile(t *testing.T, content string, mode os.FileMode) string {
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte(content), mode))
+	require.NoError(t, os.Chmod(path, mode)) // not subject to the umask
+	return path
+}
+
+func TestSecretRedacted(t *testing.T) {
+	wallet := WalletConfig{AddressKeyName: "node", AddressRestoreMnemonic: secretTestMnemonic}
+	config := UserConfig{Wallet: wallet, Worker: []WorkerConfig{{TopicId: 1, Wallet: &wallet}}}
+
+	marshalled, err := json.Marshal(config)
+	require.NoError(t, err)
+	var logged bytes.Buffer
+	logger := zerolog.New(&logged)
+	logger.Info().Interface("config", config).Str("wallet", fmt.Sprint(wallet)).Msg("config")
+
+	for name, output := range map[string]string{
+		"json":    string(marshalled),
+		"log":     logged.String(),
+		"%v":      fmt.Sprintf("%v", config),
+		"%+v":     fmt.Sprintf("%+v", config),
+		"%#v":     fmt.Sprintf("%#v", config),
+		"%s":      fmt.Sprintf("%s", wallet.AddressRestoreMnemonic),
+		"pointer": fmt.Sprintf("%+v", *config.Worker[0].Wallet),
+	} {
+		assert.NotContains(t, output, "never be logged", name)
+		assert.Contains(t, output, REDACTED, name)
+	}
+	assert.Equal(t, secretTestMnemonic, wallet.AddressRestoreMnemonic.Reveal())
+	assert.Equal(t, "", Secret("").String(), "an unset secret isn't reported as set")
+}
+
+func TestReadSecretFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		mode    os.FileMode
+		err     string
+	}{
+		{name: "owner only", content: secretTestMnemonic + "\n", mode: 0o600},
+		{name: "read only", content: "  " + secretTestMnemonic, mode: 0o400},
+		{name: "readable by the group", content: secretTestMnemonic, mode: 0o640, err: "accessible by other users (mode 0640)"},
+		{name: "readable by everyone", content: secretTestMnemonic, mode: 0o644, err: "restrict it with chmod 600"},
+		{name: "empty", content: "\n", mode: 0o600, err: "is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, err := ReadSecretFile(writeSecretFile(t, tt.content, tt.mode))
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				assert.NotContains(t, err.Error(), "never be logged")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, secretTestMnemonic, secret.Reveal())
+		})
+	}
+
+	_, err := ReadSecretFile(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestEnvSecretProvider(t *testing.T) {
+	t.Setenv("SECRET_TEST_MNEMONIC", secretTestMnemonic)
+	t.Setenv("SECRET_TEST_EMPTY", "")
+
+	secret, err := EnvSecretProvider{EnvVar: "SECRET_TEST_MNEMONIC"}.GetSecret(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, secretTestMnemonic, secret.Reveal())
+
+	_, err = EnvSecretProvider{EnvVar: "SECRET_TEST_EMPTY"}.GetSecret(context.Background())
+	assert.ErrorContains(t, err, "environment variable SECRET_TEST_EMPTY of the secret is not set")
+}
+
+// Stand-in for a Vault server, serving a KV v2 secret at secret/data/allora and a KV v1 secret at kv/allora
+func newVaultStandIn(t *testing.T, token string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/allora":
+			fmt.Fprintf(w, `{"data":{"data":{"mnemonic":%q,"other":"value"},"metadata":{"version":3}}}`, secretTestMnemonic)
+		case "/v1/kv/allora":
+			fmt.Fprintf(w, `{"data":{"wallet":%q}}`, secretTestMnemonic)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":[]}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVaultSecretProvider(t *testing.T) {
+	server := newVaultStandIn(t, "root-token")
+	tokenFile := writeSecretFile(t, "root-token\n", 0o600)
+
+	tests := []struct {
+		name     string
+		config   SecretConfig
+		envToken string // token in VAULT_TOKEN_ENV_VAR
+		err      string
+	}{
+		{name: "KV v2 with the default key", config: SecretConfig{Path: "secret/data/allora"}, envToken: "root-token"},
+		{name: "KV v1 with a key", config: SecretConfig{Path: "/kv/allora", Key: "wallet"}, envToken: "root-token"},
+		{name: "token file", config: SecretConfig{Path: "secret/data/allora", TokenFile: tokenFile}, envToken: "wrong-token"},
+		{name: "wrong token", config: SecretConfig{Path: "secret/data/allora"}, envToken: "wrong-token", err: "failed with status 403"},
+		{name: "no token", config: SecretConfig{Path: "secret/data/allora"}, err: "no vault token"},
+		{name: "missing secret", config: SecretConfig{Path: "secret/data/other"}, envToken: "root-token", err: "failed with status 404"},
+		{name: "missing key", config: SecretConfig{Path: "secret/data/allora", Key: "wallet"}, envToken: "root-token", err: `has no string value at key "wallet"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(VAULT_TOKEN_ENV_VAR, tt.envToken)
+			tt.config.Provider = SECRET_PROVIDER_VAULT
+			tt.config.Address = server.URL + "/"
+			provider, err := NewSecretProvider(tt.config)
+			require.NoError(t, err)
+			secret, err := provider.GetSecret(context.Background())
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, secretTestMnemonic, secret.Reveal())
+		})
+	}
+}
+
+func TestRestoreMnemonic(t *testing.T) {
+	t.Setenv("SECRET_TEST_MNEMONIC", secretTestMnemonic)
+	file := writeSecretFile(t, secretTestMnemonic, 0o600)
+
+	tests := []struct {
+		name     string
+		wallet   WalletConfig
+		expected string
+		err      string
+	}{
+		{name: "keyring only", wallet: WalletConfig{}},
+		{name: "plain mnemonic", wallet: WalletConfig{AddressRestoreMnemonic: secretTestMnemonic}, expected: secretTestMnemonic},
+		{name: "mnemonic file", wallet: WalletConfig{AddressRestoreMnemonicFile: file}, expected: secretTestMnemonic},
+		{
+			name:     "env provider",
+			wallet:   WalletConfig{AddressRestoreMnemonicSecret: &SecretConfig{Provider: SECRET_PROVIDER_ENV, EnvVar: "SECRET_TEST_MNEMONIC"}},
+			expected: secretTestMnemonic,
+		},
+		{
+			name:     "file provider",
+			wallet:   WalletConfig{AddressRestoreMnemonicSecret: &SecretConfig{Provider: SECRET_PROVIDER_FILE, Path: file}},
+			expected: secretTestMnemonic,
+		},
+		{
+			name:   "failing provider",
+			wallet: WalletConfig{AddressRestoreMnemonicSecret: &SecretConfig{Provider: SECRET_PROVIDER_ENV, EnvVar: "SECRET_TEST_UNSET"}},
+			err:    "cannot get mnemonic from secret provider",
+		},
+		{
+			name:   "unknown provider",
+			wallet: WalletConfig{AddressRestoreMnemonicSecret: &SecretConfig{Provider: "aws"}},
+			err:    `unknown secret provider "aws"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mnemonic, err := tt.wallet.RestoreMnemonic(context.Background())
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, mnemonic.Reveal())
+		})
+	}
+}
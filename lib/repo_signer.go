@@ -0,0 +1,277 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+const SIGNER_REQUEST_TIMEOUT_SECONDS = 10
+const SIGNER_MAX_REQUEST_BYTES = 1 << 20
+
+// Signs the bundles and txs of an account. The bytes are signed as is, as in the direct sign mode,
+// and the signature must verify against PubKey.
+type Signer interface {
+	PubKey() cryptotypes.PubKey
+	Sign(ctx context.Context, signBytes []byte) ([]byte, error)
+}
+
+// Signs with a key of a local keyring
+type KeyringSigner struct {
+	Keyring keyring.Keyring
+	KeyName string
+	pubKey  cryptotypes.PubKey
+}
+
+func NewKeyringSigner(kr keyring.Keyring, keyName string) (*KeyringSigner, error) {
+	record, err := kr.Key(keyName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get key %s from the keyring: %w", keyName, err)
+	}
+	pubKey, err := record.GetPubKey()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get public key of key %s: %w", keyName, err)
+	}
+	return &KeyringSigner{Keyring: kr, KeyName: keyName, pubKey: pubKey}, nil
+}
+
+func (s *KeyringSigner) PubKey() cryptotypes.PubKey {
+	return s.pubKey
+}
+
+func (s *KeyringSigner) Sign(ctx context.Context, signBytes []byte) ([]byte, error) {
+	sig, _, err := s.Keyring.Sign(s.KeyName, signBytes, signing.SignMode_SIGN_MODE_DIRECT)
+	return sig, err
+}
+
+// Body of the responses of the signing service to GET /keys/{name}
+type signerKeyResponse struct {
+	Name   string `json:"name"`
+	PubKey []byte `json:"pubKey"` // compressed secp256k1 public key
+}
+
+// Body of the requests to POST /keys/{name}/sign
+type signerSignRequest struct {
+	SignBytes []byte `json:"signBytes"`
+}
+
+// Body of the responses to POST /keys/{name}/sign
+type signerSignResponse struct {
+	Signature []byte `json:"signature"`
+}
+
+// Body of the error responses of the signing service
+type signerErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Signs with a key held by a remote signing service, so that the key never is on the node's host.
+// The service exposes a JSON API over HTTP, served by SigningService:
+//   - GET {url}/keys/{name} returns the public key of the key
+//   - POST {url}/keys/{name}/sign signs the bytes of the request with the key
+//
+// Byte fields are base64 encoded. Requests carry the token as a bearer token if one is set.
+type RemoteSigner struct {
+	Url     string
+	KeyName string
+	Token   Secret
+	Client  *http.Client
+	pubKey  cryptotypes.PubKey
+}
+
+// Connects to the signing service of the config and fetches the public key of the key,
+// named after the key name of the wallet unless the config names another one
+func NewRemoteSigner(ctx context.Context, config RemoteSignerConfig, keyName string) (*RemoteSigner, error) {
+	if config.KeyName != "" {
+		keyName = config.KeyName
+	}
+	signer := &RemoteSigner{
+		Url:     strings.TrimSuffix(config.Url, "/"),
+		KeyName: keyName,
+		Client:  &http.Client{Timeout: SIGNER_REQUEST_TIMEOUT_SECONDS * time.Second},
+	}
+	if config.Token != nil {
+		provider, err := NewSecretProvider(*config.Token)
+		if err != nil {
+			return nil, err
+		}
+		signer.Token, err = provider.GetSecret(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get remote signer token: %w", err)
+		}
+	}
+
+	var key signerKeyResponse
+	if err := signer.call(ctx, http.MethodGet, "", nil, &key); err != nil {
+		return nil, err
+	}
+	if len(key.PubKey) != secp256k1.PubKeySize {
+		return nil, fmt.Errorf("remote signer returned a public key of %d bytes for key %s, expected a compressed secp256k1 key", len(key.PubKey), keyName)
+	}
+	signer.pubKey = &secp256k1.PubKey{Key: key.PubKey}
+	return signer, nil
+}
+
+func (s *RemoteSigner) PubKey() cryptotypes.PubKey {
+	return s.pubKey
+}
+
+func (s *RemoteSigner) Sign(ctx context.Context, signBytes []byte) ([]byte, error) {
+	var res signerSignResponse
+	if err := s.call(ctx, http.MethodPost, "/sign", signerSignRequest{SignBytes: signBytes}, &res); err != nil {
+		return nil, err
+	}
+	return res.Signature, nil
+}
+
+// Calls the endpoint of the key of the signer at the path, decoding the response into res
+func (s *RemoteSigner) call(ctx context.Context, method string, path string, body any, res any) error {
+	requestUrl := fmt.Sprintf("%s/keys/%s%s", s.Url, url.PathEscape(s.KeyName), path)
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, requestUrl, &reqBody)
+	if err != nil {
+		return fmt.Errorf("invalid remote signer url: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token.Reveal())
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("remote signer request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var errRes signerErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errRes)
+		return fmt.Errorf("remote signer request to %s failed with status %d: %s", requestUrl, resp.StatusCode, errRes.Error)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return fmt.Errorf("invalid remote signer response: %w", err)
+	}
+	return nil
+}
+
+// Signing service serving the API of RemoteSigner with local signers by key name,
+// e.g. as a stand-in for the actual service in tests, or to sign on another host than the node's.
+// Requests must carry the token as a bearer token if one is set.
+type SigningService struct {
+	Signers map[string]Signer
+	Token   Secret
+}
+
+func (s SigningService) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /keys/{name}", func(w http.ResponseWriter, r *http.Request) {
+		signer, ok := s.authorize(w, r)
+		if !ok {
+			return
+		}
+		writeSignerResponse(w, http.StatusOK, signerKeyResponse{Name: r.PathValue("name"), PubKey: signer.PubKey().Bytes()})
+	})
+	mux.HandleFunc("POST /keys/{name}/sign", func(w http.ResponseWriter, r *http.Request) {
+		signer, ok := s.authorize(w, r)
+		if !ok {
+			return
+		}
+		var req signerSignRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, SIGNER_MAX_REQUEST_BYTES)).Decode(&req); err != nil || len(req.SignBytes) == 0 {
+			writeSignerResponse(w, http.StatusBadRequest, signerErrorResponse{Error: "expected the bytes to sign in signBytes"})
+			return
+		}
+		sig, err := signer.Sign(r.Context(), req.SignBytes)
+		if err != nil {
+			writeSignerResponse(w, http.StatusInternalServerError, signerErrorResponse{Error: err.Error()})
+			return
+		}
+		writeSignerResponse(w, http.StatusOK, signerSignResponse{Signature: sig})
+	})
+	return mux
+}
+
+// Checks the token of the request and returns the signer of the key it names
+func (s SigningService) authorize(w http.ResponseWriter, r *http.Request) (Signer, bool) {
+	if s.Token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.Token.Reveal())) != 1 {
+		writeSignerResponse(w, http.StatusUnauthorized, signerErrorResponse{Error: "invalid token"})
+		return nil, false
+	}
+	signer, ok := s.Signers[r.PathValue("name")]
+	if !ok {
+		writeSignerResponse(w, http.StatusNotFound, signerErrorResponse{Error: fmt.Sprintf("unknown key %q", r.PathValue("name"))})
+		return nil, false
+	}
+	return signer, true
+}
+
+func writeSignerResponse(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// Signs the bytes and verifies the signature against the public key of the signer,
+// so that a faulty signer is caught before anything signed by it is broadcast
+func SignAndVerify(ctx context.Context, signer Signer, signBytes []byte) ([]byte, error) {
+	sig, err := signer.Sign(ctx, signBytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign: %w", err)
+	}
+	if !signer.PubKey().VerifySignature(signBytes, sig) {
+		return nil, errors.New("signature does not verify against the public key of the signer")
+	}
+	return sig, nil
+}
+
+// Address of the account of the signer
+func SignerAddress(signer Signer) sdktypes.AccAddress {
+	return sdktypes.AccAddress(signer.PubKey().Address())
+}
+
+// Signs the tx in the direct sign mode with the signer, as tx.Sign does with a key of the keyring,
+// replacing its signatures
+func signTx(ctx context.Context, txConfig client.TxConfig, txf tx.Factory, signer Signer, txBuilder client.TxBuilder) error {
+	pubKey := signer.PubKey()
+	signerData := authsigning.SignerData{
+		ChainID:       txf.ChainID(),
+		AccountNumber: txf.AccountNumber(),
+		Sequence:      txf.Sequence(),
+		PubKey:        pubKey,
+		Address:       SignerAddress(signer).String(),
+	}
+	// The signer infos are part of the sign bytes, so they're set first with an empty signature
+	sigData := signing.SingleSignatureData{SignMode: signing.SignMode_SIGN_MODE_DIRECT}
+	sig := signing.SignatureV2{PubKey: pubKey, Data: &sigData, Sequence: txf.Sequence()}
+	if err := txBuilder.SetSignatures(sig); err != nil {
+		return err
+	}
+	signBytes, err := authsigning.GetSignBytesAdapter(ctx, txConfig.SignModeHandler(), signing.SignMode_SIGN_MODE_DIRECT, signerData, txBuilder.GetTx())
+	if err != nil {
+		return err
+	}
+	sigData.Signature, err = SignAndVerify(ctx, signer, signBytes)
+	if err != nil {
+		return err
+	}
+	return txBuilder.SetSignatures(sig)
+}
@@ -0,0 +1,153 @@
+package lib
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	sdkmath "cosmossdk.io/math"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSignerTestKeyring(t *testing.T) (keyring.Keyring, codec.Codec) {
+	interfaceRegistry := codectypes.NewInterfaceRegistry()
+	cryptocodec.RegisterInterfaces(interfaceRegistry)
+	banktypes.RegisterInterfaces(interfaceRegistry)
+	cdc := codec.NewProtoCodec(interfaceRegistry)
+	kr := keyring.NewInMemory(cdc)
+	_, err := kr.NewAccount("node", keyringTestMnemonic, "", hd.CreateHDPath(118, 0, 0).String(), hd.Secp256k1)
+	require.NoError(t, err)
+	return kr, cdc
+}
+
+// Remote signer connected to a stand-in signing service signing with the key "node" of the keyring
+func newRemoteSignerStandIn(t *testing.T, kr keyring.Keyring) *RemoteSigner {
+	local, err := NewKeyringSigner(kr, "node")
+	require.NoError(t, err)
+	server := httptest.NewServer(SigningService{Signers: map[string]Signer{"signer-node": local}, Token: "service-token"}.Handler())
+	t.Cleanup(server.Close)
+
+	t.Setenv("SIGNER_TEST_TOKEN", "service-token")
+	config := RemoteSignerConfig{Url: server.URL + "/", KeyName: "signer-node", Token: &SecretConfig{Provider: SECRET_PROVIDER_ENV, EnvVar: "SIGNER_TEST_TOKEN"}}
+	remote, err := NewRemoteSigner(context.Background(), config, "node")
+	require.NoError(t, err)
+	return remote
+}
+
+// Signer returning signatures of other bytes than the ones it's given
+type faultySigner struct {
+	Signer
+}
+
+func (s faultySigner) Sign(ctx context.Context, signBytes []byte) ([]byte, error) {
+	return s.Signer.Sign(ctx, append(signBytes, 0))
+}
+
+func TestRemoteSigner(t *testing.T) {
+	kr, _ := newSignerTestKeyring(t)
+	remote := newRemoteSignerStandIn(t, kr)
+	local, err := NewKeyringSigner(kr, "node")
+	require.NoError(t, err)
+	assert.Equal(t, local.PubKey(), remote.PubKey())
+
+	sig, err := SignAndVerify(context.Background(), remote, []byte("bundle"))
+	require.NoError(t, err)
+	expected, err := local.Sign(context.Background(), []byte("bundle"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, sig, "the remote signer signs as the keyring does")
+
+	_, err = SignAndVerify(context.Background(), faultySigner{remote}, []byte("bundle"))
+	assert.ErrorContains(t, err, "signature does not verify")
+
+	tests := []struct {
+		name   string
+		config RemoteSignerConfig
+		err    string
+	}{
+		{name: "unknown key", config: RemoteSignerConfig{KeyName: "other", Token: &SecretConfig{Provider: SECRET_PROVIDER_ENV, EnvVar: "SIGNER_TEST_TOKEN"}}, err: `failed with status 404: unknown key "other"`},
+		{name: "no token", config: RemoteSignerConfig{KeyName: "signer-node"}, err: "failed with status 401: invalid token"},
+		{name: "wrong token", config: RemoteSignerConfig{KeyName: "signer-node", Token: &SecretConfig{Provider: SECRET_PROVIDER_ENV, EnvVar: "SIGNER_TEST_WRONG_TOKEN"}}, err: "failed with status 401"},
+		{name: "unset token", config: RemoteSignerConfig{KeyName: "signer-node", Token: &SecretConfig{Provider: SECRET_PROVIDER_ENV, EnvVar: "SIGNER_TEST_UNSET"}}, err: "cannot get remote signer token"},
+		{name: "unreachable", config: RemoteSignerConfig{Url: "http://127.0.0.1:1"}, err: "remote signer request failed"},
+	}
+	t.Setenv("SIGNER_TEST_WRONG_TOKEN", "wrong-token")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.config.Url == "" {
+				tt.config.Url = remote.Url
+			}
+			_, err := NewRemoteSigner(context.Background(), tt.config, "node")
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestRemoteSignerEscapesKeyName(t *testing.T) {
+	kr, _ := newSignerTestKeyring(t)
+	local, err := NewKeyringSigner(kr, "node")
+	require.NoError(t, err)
+	keyName := "team/node?sign#1"
+	server := httptest.NewServer(SigningService{Signers: map[string]Signer{keyName: local}}.Handler())
+	defer server.Close()
+
+	remote, err := NewRemoteSigner(context.Background(), RemoteSignerConfig{Url: server.URL, KeyName: keyName}, "node")
+	require.NoError(t, err)
+	assert.Equal(t, local.PubKey(), remote.PubKey())
+	_, err = SignAndVerify(context.Background(), remote, []byte("bundle"))
+	require.NoError(t, err)
+}
+
+func TestSignTx(t *testing.T) {
+	kr, cdc := newSignerTestKeyring(t)
+	remote := newRemoteSignerStandIn(t, kr)
+	txConfig := authtx.NewTxConfig(cdc, authtx.DefaultSignModes)
+	txf := tx.Factory{}.
+		WithTxConfig(txConfig).
+		WithKeybase(kr).
+		WithChainID("allora-testnet-1").
+		WithAccountNumber(7).
+		WithSequence(3).
+		WithGas(200000)
+	from := SignerAddress(remote)
+	msg := banktypes.NewMsgSend(from, from, sdktypes.NewCoins(sdktypes.NewCoin(DEFAULT_BOND_DENOM, sdkmath.NewInt(1))))
+
+	// Signed with the remote signer, the tx is the same as signed by the keyring
+	txBuilder, err := txf.BuildUnsignedTx(msg)
+	require.NoError(t, err)
+	require.NoError(t, signTx(context.Background(), txConfig, txf, remote, txBuilder))
+	signed, err := txConfig.TxEncoder()(txBuilder.GetTx())
+	require.NoError(t, err)
+
+	expectedBuilder, err := txf.BuildUnsignedTx(msg)
+	require.NoError(t, err)
+	require.NoError(t, tx.Sign(context.Background(), txf.WithSignMode(signing.SignMode_SIGN_MODE_DIRECT), "node", expectedBuilder, true))
+	expected, err := txConfig.TxEncoder()(expectedBuilder.GetTx())
+	require.NoError(t, err)
+	assert.Equal(t, expected, signed)
+
+	// The signature verifies as the chain verifies it
+	sigs, err := txBuilder.GetTx().GetSignaturesV2()
+	require.NoError(t, err)
+	require.Len(t, sigs, 1)
+	signerData := authsigning.SignerData{ChainID: "allora-testnet-1", AccountNumber: 7, Sequence: 3, PubKey: remote.PubKey(), Address: from.String()}
+	signBytes, err := authsigning.GetSignBytesAdapter(context.Background(), txConfig.SignModeHandler(), signing.SignMode_SIGN_MODE_DIRECT, signerData, txBuilder.GetTx())
+	require.NoError(t, err)
+	assert.True(t, remote.PubKey().VerifySignature(signBytes, sigs[0].Data.(*signing.SingleSignatureData).Signature))
+
+	// A faulty signer is caught before the tx is broadcast
+	txBuilder, err = txf.BuildUnsignedTx(msg)
+	require.NoError(t, err)
+	assert.ErrorContains(t, signTx(context.Background(), txConfig, txf, faultySigner{remote}, txBuilder), "signature does not verify")
+}
@@ -0,0 +1,222 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	errorsmod "cosmossdk.io/errors"
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+const NEW_BLOCK_EVENTS_QUERY = "tm.event='NewBlockEvents'"
+
+// Emitted by the emissions module when a worker nonce is closed. Closing a worker nonce
+// opens the reputer nonce at the same height for that topic.
+const EVENT_WORKER_LAST_COMMIT_SET = "emissions.v4.EventWorkerLastCommitSet"
+
+// A subscription is dropped when nothing, not even a pong, was read from the node for this long,
+// so that a half-open connection is detected. The node is pinged often enough to keep it alive between blocks.
+var (
+	websocketReadTimeout  = 60 * time.Second
+	websocketPingInterval = 20 * time.Second
+)
+
+// Chain events relevant to the actor loops, decoded from a single CometBFT NewBlockEvents event
+type BlockEvent struct {
+	Height BlockHeight
+	// Topics for which a reputer nonce was opened in this block
+	ReputerNonceOpenedTopicIds []emissionstypes.TopicId
+}
+
+type jsonRpcRequest struct {
+	JsonRpc string            `json:"jsonrpc"`
+	Id      int64             `json:"id"`
+	Method  string            `json:"method"`
+	Params  map[string]string `json:"params"`
+}
+
+type jsonRpcResponse struct {
+	Id     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	} `json:"error"`
+}
+
+type newBlockEventsResult struct {
+	Data struct {
+		Type  string `json:"type"`
+		Value struct {
+			Height string `json:"height"`
+			Events []struct {
+				Type       string `json:"type"`
+				Attributes []struct {
+					Key   string `json:"key"`
+					Value string `json:"value"`
+				} `json:"attributes"`
+			} `json:"events"`
+		} `json:"value"`
+	} `json:"data"`
+}
+
+// Returns the websocket endpoint of the node, either as configured or derived from the RPC address
+func (wallet *WalletConfig) GetNodeWebsocket() (string, error) {
+	if wallet.NodeWebsocket != "" {
+		return wallet.NodeWebsocket, nil
+	}
+	rpcUrl, err := url.Parse(wallet.NodeRpc)
+	if err != nil {
+		return "", errorsmod.Wrap(err, "cannot derive websocket endpoint from node rpc")
+	}
+	switch rpcUrl.Scheme {
+	case "http", "tcp":
+		rpcUrl.Scheme = "ws"
+	case "https":
+		rpcUrl.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return "", fmt.Errorf("cannot derive websocket endpoint from node rpc scheme %q", rpcUrl.Scheme)
+	}
+	rpcUrl.Path = strings.TrimSuffix(rpcUrl.Path, "/") + "/websocket"
+	return rpcUrl.String(), nil
+}
+
+// SubscribeNewBlockEvents subscribes to NewBlockEvents over the node's websocket RPC and sends
+// every decoded block on events. onSubscribed is called once the node has acknowledged the subscription.
+// Blocks until the context is cancelled or the subscription drops, including when the node stops answering pings,
+// and always returns a non-nil error.
+func (node *NodeConfig) SubscribeNewBlockEvents(ctx context.Context, events chan<- BlockEvent, onSubscribed func()) error {
+	endpoint, err := node.Wallet.GetNodeWebsocket()
+	if err != nil {
+		return err
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, endpoint, nil)
+	if err != nil {
+		return errorsmod.Wrapf(err, "cannot connect to node websocket %s", endpoint)
+	}
+	defer conn.Close()
+
+	// Unblock the read loop below when the context is cancelled
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	err = conn.WriteJSON(jsonRpcRequest{
+		JsonRpc: "2.0",
+		Id:      1,
+		Method:  "subscribe",
+		Params:  map[string]string{"query": NEW_BLOCK_EVENTS_QUERY},
+	})
+	if err != nil {
+		return errorsmod.Wrap(err, "cannot send subscribe request")
+	}
+
+	// Every message and pong pushes back the read deadline, and pings are answered by pongs even between blocks
+	if err := conn.SetReadDeadline(time.Now().Add(websocketReadTimeout)); err != nil {
+		return errorsmod.Wrap(err, "cannot set read deadline")
+	}
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(websocketReadTimeout))
+	})
+	pingInterval := websocketPingInterval
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(pingInterval)); err != nil {
+					log.Debug().Err(err).Msg("Could not ping node websocket")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	subscribed := false
+	for {
+		var res jsonRpcResponse
+		if err := conn.ReadJSON(&res); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errorsmod.Wrap(err, "node websocket subscription dropped")
+		}
+		if err := conn.SetReadDeadline(time.Now().Add(websocketReadTimeout)); err != nil {
+			return errorsmod.Wrap(err, "cannot set read deadline")
+		}
+		if res.Error != nil {
+			return fmt.Errorf("node rejected subscription: %s %s", res.Error.Message, res.Error.Data)
+		}
+
+		if !subscribed {
+			// The first reply is the acknowledgement of the subscribe request, with an empty result
+			subscribed = true
+			log.Info().Str("endpoint", endpoint).Str("query", NEW_BLOCK_EVENTS_QUERY).Msg("Subscribed to node events")
+			if onSubscribed != nil {
+				onSubscribed()
+			}
+			continue
+		}
+
+		blockEvent, err := parseNewBlockEvents(res.Result)
+		if err != nil {
+			log.Warn().Err(err).Msg("Could not decode node event, ignoring")
+			continue
+		}
+
+		select {
+		case events <- blockEvent:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func parseNewBlockEvents(raw json.RawMessage) (BlockEvent, error) {
+	var result newBlockEventsResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return BlockEvent{}, err
+	}
+	if result.Data.Value.Height == "" {
+		return BlockEvent{}, errors.New("event has no block height")
+	}
+	height, err := strconv.ParseInt(result.Data.Value.Height, 10, 64)
+	if err != nil {
+		return BlockEvent{}, errorsmod.Wrap(err, "invalid block height")
+	}
+
+	blockEvent := BlockEvent{Height: height}
+	for _, event := range result.Data.Value.Events {
+		if event.Type != EVENT_WORKER_LAST_COMMIT_SET {
+			continue
+		}
+		for _, attribute := range event.Attributes {
+			if attribute.Key != "topic_id" {
+				continue
+			}
+			// Typed event attributes are JSON encoded, and 64-bit integers are quoted
+			topicId, err := strconv.ParseUint(strings.Trim(attribute.Value, "\""), 10, 64)
+			if err != nil {
+				return BlockEvent{}, errorsmod.Wrapf(err, "invalid topic_id in %s", event.Type)
+			}
+			blockEvent.ReputerNonceOpenedTopicIds = append(blockEvent.ReputerNonceOpenedTopicIds, topicId)
+		}
+	}
+	return blockEvent, nil
+}
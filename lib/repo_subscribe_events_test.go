@@ -0,0 +1,172 @@
+package lib
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Events as recorded from a node's websocket after subscribing to NewBlockEvents
+var recordedNodeEvents = []string{
+	`{"jsonrpc":"2.0","id":1,"result":{}}`,
+	`{"jsonrpc":"2.0","id":1,"result":{"query":"tm.event='NewBlockEvents'","data":{"type":"tendermint/event/NewBlockEvents","value":{"height":"1200","events":[{"type":"coin_spent","attributes":[{"key":"spender","value":"allo1m3h30wlvsf8llruxtpukdvsy0km2kum8al86ug","index":true}]}],"num_txs":"0"}},"events":{"tm.event":["NewBlockEvents"]}}}`,
+	`{"jsonrpc":"2.0","id":1,"result":{"query":"tm.event='NewBlockEvents'","data":{"type":"tendermint/event/NewBlockEvents","value":{"height":"1201","events":[{"type":"emissions.v4.EventWorkerLastCommitSet","attributes":[{"key":"block_height","value":"\"1201\"","index":true},{"key":"nonce","value":"{\"block_height\":\"1140\"}","index":true},{"key":"topic_id","value":"\"1\"","index":true},{"key":"mode","value":"EndBlock","index":true}]},{"type":"emissions.v4.EventWorkerLastCommitSet","attributes":[{"key":"block_height","value":"\"1201\"","index":true},{"key":"nonce","value":"{\"block_height\":\"1141\"}","index":true},{"key":"topic_id","value":"\"7\"","index":true},{"key":"mode","value":"EndBlock","index":true}]}],"num_txs":"2"}},"events":{"tm.event":["NewBlockEvents"]}}}`,
+}
+
+// Stands in for the node websocket: replays the given messages after the subscribe request, then drops the connection
+func newStandInNodeServer(t *testing.T, messages []string) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/websocket", r.URL.Path)
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		var req jsonRpcRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			t.Errorf("reading subscribe request failed: %v", err)
+			return
+		}
+		assert.Equal(t, "subscribe", req.Method)
+		assert.Equal(t, NEW_BLOCK_EVENTS_QUERY, req.Params["query"])
+
+		for _, message := range messages {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestSubscribeNewBlockEvents(t *testing.T) {
+	server := newStandInNodeServer(t, recordedNodeEvents)
+	defer server.Close()
+
+	node := NodeConfig{Wallet: WalletConfig{NodeRpc: server.URL}}
+	events := make(chan BlockEvent, len(recordedNodeEvents))
+	subscribed := false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := node.SubscribeNewBlockEvents(ctx, events, func() { subscribed = true })
+	close(events)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "subscription dropped")
+	assert.True(t, subscribed)
+
+	received := []BlockEvent{}
+	for event := range events {
+		received = append(received, event)
+	}
+	require.Len(t, received, 2)
+	assert.Equal(t, BlockHeight(1200), received[0].Height)
+	assert.Empty(t, received[0].ReputerNonceOpenedTopicIds)
+	assert.Equal(t, BlockHeight(1201), received[1].Height)
+	assert.Equal(t, []uint64{1, 7}, received[1].ReputerNonceOpenedTopicIds)
+}
+
+func TestSubscribeNewBlockEventsRejected(t *testing.T) {
+	server := newStandInNodeServer(t, []string{
+		`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"Internal error","data":"max_subscriptions_per_client 5 reached"}}`,
+	})
+	defer server.Close()
+
+	node := NodeConfig{Wallet: WalletConfig{NodeRpc: server.URL}}
+	err := node.SubscribeNewBlockEvents(context.Background(), make(chan BlockEvent), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max_subscriptions_per_client")
+}
+
+func TestSubscribeNewBlockEventsKeepalive(t *testing.T) {
+	readTimeout, pingInterval := websocketReadTimeout, websocketPingInterval
+	websocketReadTimeout, websocketPingInterval = 300*time.Millisecond, 100*time.Millisecond
+	defer func() { websocketReadTimeout, websocketPingInterval = readTimeout, pingInterval }()
+
+	tests := []struct {
+		name        string
+		answerPings bool
+		expectedErr string
+	}{
+		{name: "node answering pings between blocks", answerPings: true, expectedErr: context.DeadlineExceeded.Error()},
+		{name: "half-open connection", answerPings: false, expectedErr: "subscription dropped"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Acknowledges the subscription, then sends no event
+			upgrader := websocket.Upgrader{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				conn, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					t.Errorf("upgrade failed: %v", err)
+					return
+				}
+				defer conn.Close()
+				var req jsonRpcRequest
+				if err := conn.ReadJSON(&req); err != nil {
+					return
+				}
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(recordedNodeEvents[0])); err != nil {
+					return
+				}
+				if !tt.answerPings {
+					// Pings are only answered while reading
+					<-r.Context().Done()
+					return
+				}
+				for {
+					if _, _, err := conn.ReadMessage(); err != nil {
+						return
+					}
+				}
+			}))
+			defer server.Close()
+
+			node := NodeConfig{Wallet: WalletConfig{NodeRpc: server.URL}}
+			ctx, cancel := context.WithTimeout(context.Background(), 3*websocketReadTimeout)
+			defer cancel()
+			start := time.Now()
+			err := node.SubscribeNewBlockEvents(ctx, make(chan BlockEvent), nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedErr)
+			assert.Less(t, time.Since(start), 5*websocketReadTimeout)
+		})
+	}
+}
+
+func TestGetNodeWebsocket(t *testing.T) {
+	tests := []struct {
+		name     string
+		wallet   WalletConfig
+		expected string
+		err      bool
+	}{
+		{"http rpc", WalletConfig{NodeRpc: "http://localhost:26657"}, "ws://localhost:26657/websocket", false},
+		{"https rpc with path", WalletConfig{NodeRpc: "https://allora-rpc.testnet.allora.network/"}, "wss://allora-rpc.testnet.allora.network/websocket", false},
+		{"explicit websocket", WalletConfig{NodeRpc: "http://localhost:26657", NodeWebsocket: "ws://other:26657/websocket"}, "ws://other:26657/websocket", false},
+		{"unsupported scheme", WalletConfig{NodeRpc: "ftp://localhost"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, err := tt.wallet.GetNodeWebsocket()
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, endpoint)
+			assert.True(t, strings.HasSuffix(endpoint, "/websocket"))
+		})
+	}
+}
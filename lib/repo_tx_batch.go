@@ -0,0 +1,235 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/rs/zerolog/log"
+)
+
+var failedMessageIndexRegex = regexp.MustCompile(`message index: (\d+)`)
+
+// Index of the msg a multi-msg tx failed on, if the chain attributed the failure to one
+func FailedMessageIndex(err error) (int, bool) {
+	var txErr *TxError
+	if !errors.As(err, &txErr) {
+		return 0, false
+	}
+	matches := failedMessageIndexRegex.FindStringSubmatch(txErr.RawLog)
+	if len(matches) != 2 {
+		return 0, false
+	}
+	index, parseErr := strconv.Atoi(matches[1])
+	if parseErr != nil {
+		return 0, false
+	}
+	return index, true
+}
+
+// Sends the msgs as a single tx, returning its result once included in a block
+type SendMsgsFunc func(ctx context.Context, msgs []sdktypes.Msg, infoMsg string) (*TxResult, error)
+
+type batchOutcome struct {
+	result *TxResult
+	err    error
+}
+
+type batchedMsg struct {
+	topicId emissionstypes.TopicId
+	msg     sdktypes.Msg
+	infoMsg string
+	done    chan batchOutcome
+}
+
+// Collects the msgs of an account's actors submitted within a window into a single multi-msg tx,
+// e.g. the payloads of topics whose submission windows open on the same block
+type TxBatcher struct {
+	window         time.Duration
+	resubmitOthers bool
+	policies       map[string]TxErrorPolicy
+	send           SendMsgsFunc
+
+	mu      sync.Mutex
+	pending []*batchedMsg
+	// Flushes the pending msgs once the window is over, stopped if they are all withdrawn.
+	// Its generation tells a timer that fired while being stopped not to flush the next batch.
+	timer           *time.Timer
+	timerGeneration uint64
+
+	// Batches are sent on their own, cancelled on Close
+	ctx      context.Context
+	cancel   context.CancelFunc
+	inFlight sync.WaitGroup
+}
+
+func NewTxBatcher(window time.Duration, resubmitOthers bool, policies map[string]TxErrorPolicy, send SendMsgsFunc) *TxBatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TxBatcher{
+		window:         window,
+		resubmitOthers: resubmitOthers,
+		policies:       policies,
+		send:           send,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+// Abandons the batches being sent, once their broadcast is done, and waits for them to return. Nil-safe.
+func (b *TxBatcher) Close() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.cancel()
+	b.mu.Unlock()
+	b.inFlight.Wait()
+}
+
+// Adds the msg of the topic to the current batch, opening one if needed, and blocks until the batch is sent.
+// Returns the result of the tx the msg was included in, or the error of the msg itself, or the error
+// preventing the batch from being sent.
+func (b *TxBatcher) Submit(ctx context.Context, topicId emissionstypes.TopicId, msg sdktypes.Msg, infoMsg string) (*TxResult, error) {
+	item := &batchedMsg{topicId: topicId, msg: msg, infoMsg: infoMsg, done: make(chan batchOutcome, 1)}
+	b.mu.Lock()
+	b.pending = append(b.pending, item)
+	if len(b.pending) == 1 {
+		b.timerGeneration++
+		generation := b.timerGeneration
+		b.timer = time.AfterFunc(b.window, func() { b.flush(generation) })
+	}
+	b.mu.Unlock()
+
+	select {
+	case outcome := <-item.done:
+		return outcome.result, outcome.err
+	case <-ctx.Done():
+		// Withdraw the msg if its batch isn't sent yet
+		b.mu.Lock()
+		b.pending = slices.DeleteFunc(b.pending, func(pending *batchedMsg) bool { return pending == item })
+		if len(b.pending) == 0 && b.timer != nil {
+			b.timer.Stop()
+			b.timer = nil
+			b.timerGeneration++
+		}
+		b.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (b *TxBatcher) flush(generation uint64) {
+	b.mu.Lock()
+	if generation != b.timerGeneration {
+		// The batch of this timer was withdrawn
+		b.mu.Unlock()
+		return
+	}
+	b.timer = nil
+	batch := b.pending
+	b.pending = nil
+	closed := b.ctx.Err()
+	if len(batch) > 0 && closed == nil {
+		b.inFlight.Add(1)
+	}
+	b.mu.Unlock()
+	if closed != nil {
+		for _, item := range batch {
+			item.done <- batchOutcome{err: closed}
+		}
+		return
+	}
+	if len(batch) == 0 {
+		return
+	}
+	defer b.inFlight.Done()
+
+	b.sendBatch(b.ctx, batch)
+}
+
+// Sends the batch. If a msg fails the tx, it is reported against its topic, and the others
+// are either resubmitted without it or reported as not sent because of it.
+func (b *TxBatcher) sendBatch(ctx context.Context, batch []*batchedMsg) {
+	for len(batch) > 0 {
+		msgs := make([]sdktypes.Msg, len(batch))
+		infoMsgs := make([]string, len(batch))
+		topicIds := make([]uint64, len(batch))
+		for i, item := range batch {
+			msgs[i] = item.msg
+			infoMsgs[i] = item.infoMsg
+			topicIds[i] = item.topicId
+		}
+		if len(batch) > 1 {
+			log.Info().Uints64("topicIds", topicIds).Msg("Sending batch of msgs in a single tx")
+		}
+
+		result, err := b.send(ctx, msgs, strings.Join(infoMsgs, "; "))
+		if err == nil {
+			for _, item := range batch {
+				item.done <- batchOutcome{result: result}
+			}
+			return
+		}
+
+		index, ok := FailedMessageIndex(err)
+		if !ok || index >= len(batch) || len(batch) == 1 {
+			for _, item := range batch {
+				item.done <- batchOutcome{err: err}
+			}
+			return
+		}
+
+		failed := batch[index]
+		log.Error().Err(err).Uint64("topicId", failed.topicId).Msg("Msg of topic failed the batch tx")
+		if ClassifyTxError(err, b.policies) == TX_ERROR_POLICY_ALREADY_SUBMITTED {
+			failed.done <- batchOutcome{}
+		} else {
+			failed.done <- batchOutcome{err: fmt.Errorf("msg of topic %d failed the batch tx: %w", failed.topicId, err)}
+		}
+		batch = append(batch[:index:index], batch[index+1:]...)
+
+		if !b.resubmitOthers {
+			for _, item := range batch {
+				item.done <- batchOutcome{err: fmt.Errorf("batch tx not sent because the msg of topic %d failed: %w", failed.topicId, err)}
+			}
+			return
+		}
+		log.Info().Uint64("failedTopicId", failed.topicId).Msg("Resubmitting the other msgs of the batch")
+	}
+}
+
+// Sends the msg of the topic, in a batch with the account's other msgs if batching is enabled.
+// Returns the result of the tx once included in a block, nil if the data was already submitted.
+func (node *NodeConfig) SubmitMsg(ctx context.Context, topicId emissionstypes.TopicId, msg sdktypes.Msg, infoMsg string) (*TxResult, error) {
+	if node.Chain.Batcher == nil {
+		return node.SendMsgs(ctx, []sdktypes.Msg{msg}, infoMsg)
+	}
+	return node.Chain.Batcher.Submit(ctx, topicId, msg, infoMsg)
+}
+
+// Sends the msgs as a single tx with SendMsgsWithRetry, returning its result
+func (node *NodeConfig) SendMsgs(ctx context.Context, msgs []sdktypes.Msg, infoMsg string) (*TxResult, error) {
+	res, err := node.SendMsgsWithRetry(ctx, msgs, infoMsg)
+	if err != nil || res == nil || res.TxResponse == nil {
+		return nil, err
+	}
+	if result, ok := node.Chain.TxResults.Get(res.TxHash); ok {
+		return result, nil
+	}
+	return &TxResult{
+		TxHash:    res.TxHash,
+		Height:    res.Height,
+		GasWanted: res.GasWanted,
+		GasUsed:   res.GasUsed,
+		Codespace: res.Codespace,
+		Code:      res.Code,
+		RawLog:    res.RawLog,
+	}, nil
+}
@@ -0,0 +1,220 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	errorsmod "cosmossdk.io/errors"
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Chain stand-in failing any tx containing the msg of a failing topic, attributing the failure to that msg
+type fakeBatchChain struct {
+	mu             sync.Mutex
+	failingTopics  map[uint64]*errorsmod.Error
+	unattributable error
+	sent           [][]uint64
+}
+
+func (f *fakeBatchChain) send(ctx context.Context, msgs []sdktypes.Msg, infoMsg string) (*TxResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	topicIds := make([]uint64, len(msgs))
+	for i, msg := range msgs {
+		topicIds[i] = msg.(*emissionstypes.InsertWorkerPayloadRequest).WorkerDataBundle.TopicId
+	}
+	f.sent = append(f.sent, topicIds)
+	if f.unattributable != nil {
+		return nil, f.unattributable
+	}
+	for i, topicId := range topicIds {
+		if err, ok := f.failingTopics[topicId]; ok {
+			return nil, &TxError{
+				Codespace: err.Codespace(),
+				Code:      err.ABCICode(),
+				RawLog:    fmt.Sprintf("failed to execute message; message index: %d: %s", i, err),
+			}
+		}
+	}
+	return &TxResult{TxHash: fmt.Sprintf("TX%d", len(f.sent)), Height: 100}, nil
+}
+
+func submitTopics(batcher *TxBatcher, topicIds ...uint64) (map[uint64]*TxResult, map[uint64]error) {
+	var mu sync.Mutex
+	results := map[uint64]*TxResult{}
+	errs := map[uint64]error{}
+	var wg sync.WaitGroup
+	for _, topicId := range topicIds {
+		wg.Add(1)
+		go func(topicId uint64) {
+			defer wg.Done()
+			msg := &emissionstypes.InsertWorkerPayloadRequest{
+				WorkerDataBundle: &emissionstypes.WorkerDataBundle{TopicId: topicId},
+			}
+			result, err := batcher.Submit(context.Background(), topicId, msg, "test")
+			mu.Lock()
+			results[topicId] = result
+			errs[topicId] = err
+			mu.Unlock()
+		}(topicId)
+	}
+	wg.Wait()
+	return results, errs
+}
+
+func TestTxBatcher(t *testing.T) {
+	tests := []struct {
+		name           string
+		resubmitOthers bool
+		failingTopics  map[uint64]*errorsmod.Error
+		unattributable error
+		expectedTxs    int
+		failedTopics   []uint64
+	}{
+		{
+			name:        "msgs within the window share a tx",
+			expectedTxs: 1,
+		},
+		{
+			name:          "failure reported against its topic and the others not sent",
+			failingTopics: map[uint64]*errorsmod.Error{2: emissionstypes.ErrUnfulfilledNonceNotFound},
+			expectedTxs:   1,
+			failedTopics:  []uint64{1, 2, 3},
+		},
+		{
+			name:           "others resubmitted without the failing msg",
+			resubmitOthers: true,
+			failingTopics:  map[uint64]*errorsmod.Error{2: emissionstypes.ErrUnfulfilledNonceNotFound},
+			expectedTxs:    2,
+			failedTopics:   []uint64{2},
+		},
+		{
+			name:           "several failing msgs are dropped one after the other",
+			resubmitOthers: true,
+			failingTopics:  map[uint64]*errorsmod.Error{1: emissionstypes.ErrUnfulfilledNonceNotFound, 3: emissionstypes.ErrWorkerNonceWindowNotAvailable},
+			expectedTxs:    3,
+			failedTopics:   []uint64{1, 3},
+		},
+		{
+			name:           "already sent payload counts as submitted",
+			resubmitOthers: true,
+			failingTopics:  map[uint64]*errorsmod.Error{3: emissionstypes.ErrCantUpdateEmaMoreThanOncePerWindow},
+			expectedTxs:    2,
+		},
+		{
+			name:           "unattributable failure fails the whole batch",
+			resubmitOthers: true,
+			unattributable: &TxError{Codespace: "sdk", Code: 13, RawLog: "insufficient fees; got: 10uallo required: 2000uallo: insufficient fee"},
+			expectedTxs:    1,
+			failedTopics:   []uint64{1, 2, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &fakeBatchChain{failingTopics: tt.failingTopics, unattributable: tt.unattributable}
+			batcher := NewTxBatcher(50*time.Millisecond, tt.resubmitOthers, nil, chain.send)
+
+			results, errs := submitTopics(batcher, 1, 2, 3)
+
+			require.Len(t, chain.sent, tt.expectedTxs)
+			assert.Len(t, chain.sent[0], 3, "the first tx batches every msg")
+			for _, topicId := range []uint64{1, 2, 3} {
+				if assert.Contains(t, errs, topicId) {
+					if slices.Contains(tt.failedTopics, topicId) {
+						assert.Error(t, errs[topicId], "topic %d", topicId)
+					} else {
+						assert.NoError(t, errs[topicId], "topic %d", topicId)
+					}
+					if results[topicId] != nil {
+						assert.Equal(t, fmt.Sprintf("TX%d", tt.expectedTxs), results[topicId].TxHash, "result of the tx including the msg of topic %d", topicId)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTxBatcherAttributesFailure(t *testing.T) {
+	chain := &fakeBatchChain{failingTopics: map[uint64]*errorsmod.Error{2: emissionstypes.ErrUnfulfilledNonceNotFound}}
+	_, errs := submitTopics(NewTxBatcher(50*time.Millisecond, false, nil, chain.send), 1, 2)
+	assert.ErrorContains(t, errs[2], "msg of topic 2 failed")
+	assert.ErrorContains(t, errs[1], "because the msg of topic 2 failed")
+}
+
+func TestFailedMessageIndex(t *testing.T) {
+	index, ok := FailedMessageIndex(&TxError{Codespace: "sdk", Code: 5, RawLog: "failed to execute message; message index: 4: insufficient funds"})
+	assert.True(t, ok)
+	assert.Equal(t, 4, index)
+
+	_, ok = FailedMessageIndex(&TxError{Codespace: "sdk", Code: 32, RawLog: "account sequence mismatch, expected 2, got 1"})
+	assert.False(t, ok)
+	_, ok = FailedMessageIndex(errors.New("failed to execute message; message index: 4: not a chain error"))
+	assert.False(t, ok)
+	_, ok = FailedMessageIndex(nil)
+	assert.False(t, ok)
+}
+
+func TestTxBatcherShutdown(t *testing.T) {
+	chain := &fakeBatchChain{}
+	batcher := NewTxBatcher(50*time.Millisecond, false, nil, chain.send)
+	msg := &emissionstypes.InsertWorkerPayloadRequest{
+		WorkerDataBundle: &emissionstypes.WorkerDataBundle{TopicId: 1},
+	}
+
+	// A msg withdrawn before its batch is sent is never sent
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := batcher.Submit(ctx, 1, msg, "test")
+	require.ErrorIs(t, err, context.Canceled)
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, chain.sent)
+
+	// Once closed, batches are not sent anymore
+	done := make(chan error, 1)
+	go func() {
+		_, err := batcher.Submit(context.Background(), 1, msg, "test")
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	batcher.Close()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("msg submitted to a closed batcher never returned")
+	}
+	assert.Empty(t, chain.sent)
+}
+
+func TestTxBatcherWithdrawnMsgStopsTimer(t *testing.T) {
+	chain := &fakeBatchChain{}
+	window := 200 * time.Millisecond
+	batcher := NewTxBatcher(window, false, nil, chain.send)
+	defer batcher.Close()
+	newMsg := func(topicId uint64) *emissionstypes.InsertWorkerPayloadRequest {
+		return &emissionstypes.InsertWorkerPayloadRequest{
+			WorkerDataBundle: &emissionstypes.WorkerDataBundle{TopicId: topicId},
+		}
+	}
+
+	// The only pending msg is withdrawn halfway through its window
+	ctx, cancel := context.WithTimeout(context.Background(), window/2)
+	defer cancel()
+	_, err := batcher.Submit(ctx, 1, newMsg(1), "test")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The next msg gets a full window of its own, not the rest of the withdrawn one
+	start := time.Now()
+	_, err = batcher.Submit(context.Background(), 2, newMsg(2), "test")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), window)
+	assert.Equal(t, [][]uint64{{2}}, chain.sent)
+}
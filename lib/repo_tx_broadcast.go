@@ -0,0 +1,175 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	errorsmod "cosmossdk.io/errors"
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+)
+
+const SIMULATED_GAS_MARGIN = 20000 // added to the simulated gas, which can fall short of what the actual tx needs
+
+const SIMULATE_TX_PATH = "/cosmos.tx.v1beta1.Service/Simulate"
+
+// Typed error of a failed tx, telling the sequencer about sequence mismatches
+func newTxError(codespace string, code uint32, rawLog string, txHash string) error {
+	txErr := &TxError{Codespace: codespace, Code: code, RawLog: rawLog, TxHash: txHash}
+	if codespace == sdkerrors.ErrWrongSequence.Codespace() && code == sdkerrors.ErrWrongSequence.ABCICode() {
+		return NewSequenceMismatchError(txErr, rawLog)
+	}
+	return txErr
+}
+
+func txErrorOf(res *sdktypes.TxResponse) error {
+	return newTxError(res.Codespace, res.Code, res.RawLog, res.TxHash)
+}
+
+// Simulates the tx to estimate its gas, querying the node directly so that a failure
+// keeps the codespace and code of the chain error
+func simulateTx(ctx context.Context, clientCtx client.Context, txf tx.Factory, msgs ...sdktypes.Msg) (uint64, error) {
+	txBytes, err := txf.BuildSimTx(msgs...)
+	if err != nil {
+		return 0, err
+	}
+	reqBytes, err := (&txtypes.SimulateRequest{TxBytes: txBytes}).Marshal()
+	if err != nil {
+		return 0, err
+	}
+	res, err := clientCtx.Client.ABCIQuery(ctx, SIMULATE_TX_PATH, reqBytes)
+	if err != nil {
+		return 0, err
+	}
+	if !res.Response.IsOK() {
+		return 0, newTxError(res.Response.Codespace, res.Response.Code, res.Response.Log, "")
+	}
+	var simRes txtypes.SimulateResponse
+	if err := simRes.Unmarshal(res.Response.Value); err != nil {
+		return 0, err
+	}
+	return uint64(txf.GasAdjustment() * float64(simRes.GasInfo.GasUsed)), nil
+}
+
+// Fetches the account number and next sequence of the node's account from the chain
+func (node *NodeConfig) syncAccountSequence() (uint64, uint64, error) {
+	clientCtx := node.Chain.Client.Context()
+	return clientCtx.AccountRetriever.GetAccountNumberSequence(clientCtx, SignerAddress(node.Chain.Signer))
+}
+
+// Signs the msgs into a single tx from the node's account with the given sequence, and broadcasts it synchronously.
+// The signature is verified before the broadcast.
+// Returns the response of the mempool's check of the tx, and the fee it pays.
+func (node *NodeConfig) signAndBroadcastTx(ctx context.Context, accountNumber uint64, sequence uint64, msgs ...sdktypes.Msg) (*sdktypes.TxResponse, sdktypes.Coins, error) {
+	node.Chain.Client.SetConfigAddressPrefix()
+	for _, msg := range msgs {
+		if msg, ok := msg.(sdktypes.HasValidateBasic); ok {
+			if err := msg.ValidateBasic(); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	clientCtx := node.Chain.Client.Context().
+		WithFromName(node.Wallet.AddressKeyName).
+		WithFromAddress(SignerAddress(node.Chain.Signer))
+	txf := node.Chain.Client.TxFactory.
+		WithAccountNumber(accountNumber).
+		WithSequence(sequence)
+
+	if node.Wallet.Gas != "" && node.Wallet.Gas != cosmosclient.GasAuto {
+		gas, err := strconv.ParseUint(node.Wallet.Gas, 10, 64)
+		if err != nil {
+			return nil, nil, errorsmod.Wrap(err, "invalid gas")
+		}
+		txf = txf.WithGas(gas * uint64(len(msgs)))
+	} else {
+		if node.Wallet.GasAdjustment != 0 {
+			txf = txf.WithGasAdjustment(node.Wallet.GasAdjustment)
+		}
+		gas, err := simulateTx(ctx, clientCtx, txf, msgs...)
+		if err != nil {
+			return nil, nil, errorsmod.Wrap(err, "could not simulate tx")
+		}
+		txf = txf.WithGas(gas + SIMULATED_GAS_MARGIN)
+	}
+
+	fee, err := node.Chain.Fees.Fee(ctx, txf.Gas())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !fee.IsZero() {
+		txf = txf.WithFees(fee.String())
+	}
+
+	txBuilder, err := txf.BuildUnsignedTx(msgs...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := signTx(ctx, clientCtx.TxConfig, txf, node.Chain.Signer, txBuilder); err != nil {
+		return nil, nil, err
+	}
+	txBytes, err := clientCtx.TxConfig.TxEncoder()(txBuilder.GetTx())
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := clientCtx.BroadcastTx(txBytes)
+	return res, fee, err
+}
+
+// Broadcasts the msgs as a single tx from the node's account, with the next sequence of the account's
+// sequencer, and waits for its inclusion in a block. A tx failing after its inclusion returns a TxError with its result.
+// Broadcasts of the account are serialized only until the mempool accepts the tx, so the node's
+// actors don't wait for each other's txs to be included before broadcasting theirs.
+// Once the context is done, no tx is broadcast anymore, and the wait for inclusion is abandoned.
+func (node *NodeConfig) BroadcastTx(ctx context.Context, msgs ...sdktypes.Msg) (cosmosclient.Response, error) {
+	var res *sdktypes.TxResponse
+	var fee sdktypes.Coins
+	err := node.Chain.Sequencer.Broadcast(node.syncAccountSequence, func(accountNumber uint64, sequence uint64) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		// A broadcast once started is not cancelled, so that the sequence of the account stays consistent on shutdown
+		var err error
+		res, fee, err = node.signAndBroadcastTx(context.WithoutCancel(ctx), accountNumber, sequence, msgs...)
+		if err != nil {
+			return false, err
+		}
+		if res.Code != 0 {
+			return false, txErrorOf(res)
+		}
+		node.Chain.Fees.RecordPaid(fee)
+		return true, nil
+	})
+	if err != nil {
+		return cosmosclient.Response{}, err
+	}
+
+	included, result, err := node.confirmTx(ctx, res.TxHash, fee)
+	if err != nil {
+		return cosmosclient.Response{}, err
+	}
+	response := cosmosclient.Response{
+		Codec:      node.Chain.Client.Context().Codec,
+		TxResponse: sdktypes.NewResponseResultTx(included, nil, ""),
+	}
+	if !result.Succeeded() {
+		err := newTxError(result.Codespace, result.Code, result.RawLog, result.TxHash)
+		var txErr *TxError
+		if errors.As(err, &txErr) {
+			txErr.Result = result
+		}
+		var mismatch *SequenceMismatchError
+		if errors.As(err, &mismatch) {
+			node.Chain.Sequencer.Invalidate()
+		}
+		return response, err
+	}
+	node.Chain.Fees.Reset()
+	return response, nil
+}
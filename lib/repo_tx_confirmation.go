@@ -0,0 +1,151 @@
+package lib
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/rs/zerolog/log"
+)
+
+const DEFAULT_TX_CONFIRMATION_TIMEOUT_SECONDS = 60 // how long to wait for a tx to be included in a block, unless configured
+const TX_CONFIRMATION_POLL_INTERVAL = time.Second
+const TX_RESULT_LOG_SIZE = 1000 // number of recent tx results kept by the node
+
+// Outcome of a tx included in a block
+type TxResult struct {
+	TxHash    string
+	Height    int64
+	GasWanted int64
+	GasUsed   int64
+	Codespace string
+	Code      uint32
+	RawLog    string
+	Fee       string // fee paid by the tx, if known
+}
+
+func (r *TxResult) Succeeded() bool {
+	return r.Code == 0
+}
+
+func txResultOf(res *ctypes.ResultTx) *TxResult {
+	return &TxResult{
+		TxHash:    res.Hash.String(),
+		Height:    res.Height,
+		GasWanted: res.TxResult.GasWanted,
+		GasUsed:   res.TxResult.GasUsed,
+		Codespace: res.TxResult.Codespace,
+		Code:      res.TxResult.Code,
+		RawLog:    res.TxResult.Log,
+	}
+}
+
+// Looks up a tx in the blocks, failing if it isn't included yet
+type TxLookupFunc func(ctx context.Context, hash []byte) (*ctypes.ResultTx, error)
+
+// Polls for the tx every pollInterval until it is found in a block or the timeout elapses
+func pollTxResult(ctx context.Context, txHash string, timeout time.Duration, pollInterval time.Duration, lookup TxLookupFunc) (*ctypes.ResultTx, error) {
+	hash, err := hex.DecodeString(txHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tx hash %q: %w", txHash, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		res, err := lookup(ctx, hash)
+		if err == nil {
+			return res, nil
+		}
+		log.Trace().Err(err).Str("txHash", txHash).Msg("Tx not found in a block yet")
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("not included within %s: %w", timeout, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Waits for the tx to be included in a block, for up to the configured confirmation timeout.
+// The result is recorded in the node's tx results, whatever its code.
+func (node *NodeConfig) ConfirmTx(ctx context.Context, txHash string) (*TxResult, error) {
+	_, result, err := node.confirmTx(ctx, txHash, nil)
+	return result, err
+}
+
+func (node *NodeConfig) confirmTx(ctx context.Context, txHash string, fee sdktypes.Coins) (*ctypes.ResultTx, *TxResult, error) {
+	timeout := time.Duration(node.Wallet.TxConfirmationTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = DEFAULT_TX_CONFIRMATION_TIMEOUT_SECONDS * time.Second
+	}
+	lookup := func(ctx context.Context, hash []byte) (*ctypes.ResultTx, error) {
+		return node.Chain.Client.RPC.Tx(ctx, hash, false)
+	}
+	res, err := pollTxResult(ctx, txHash, timeout, TX_CONFIRMATION_POLL_INTERVAL, lookup)
+	if err != nil {
+		return nil, nil, &TxInclusionError{TxHash: txHash, Err: err}
+	}
+	result := txResultOf(res)
+	result.Fee = fee.String()
+	node.Chain.TxResults.Record(result)
+	log.Debug().Str("txHash", result.TxHash).Str("fee", result.Fee).Int64("height", result.Height).Int64("gasUsed", result.GasUsed).Uint32("code", result.Code).Msg("Tx included in a block")
+	return res, result, nil
+}
+
+// Most recent tx results of an account, by hash
+type TxResultLog struct {
+	mu      sync.Mutex
+	size    int
+	results map[string]*TxResult
+	order   []string
+}
+
+func NewTxResultLog(size int) *TxResultLog {
+	return &TxResultLog{size: size, results: make(map[string]*TxResult)}
+}
+
+// Records the result, forgetting the oldest one once the log is full. Nil-safe: ignored without a log.
+func (l *TxResultLog) Record(result *TxResult) {
+	if l == nil || result == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.results[result.TxHash]; !ok {
+		l.order = append(l.order, result.TxHash)
+	}
+	l.results[result.TxHash] = result
+	for len(l.order) > l.size {
+		delete(l.results, l.order[0])
+		l.order = l.order[1:]
+	}
+}
+
+func (l *TxResultLog) Get(txHash string) (*TxResult, bool) {
+	if l == nil {
+		return nil, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result, ok := l.results[txHash]
+	return result, ok
+}
+
+// Recorded results, oldest first
+func (l *TxResultLog) Recent() []*TxResult {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	results := make([]*TxResult, 0, len(l.order))
+	for _, txHash := range l.order {
+		results = append(results, l.results[txHash])
+	}
+	return results
+}
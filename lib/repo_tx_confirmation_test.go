@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollTxResult(t *testing.T) {
+	lookups := 0
+	lookup := func(ctx context.Context, hash []byte) (*ctypes.ResultTx, error) {
+		lookups++
+		if lookups < 3 {
+			return nil, errors.New("tx (AB12) not found")
+		}
+		return &ctypes.ResultTx{
+			Hash:   hash,
+			Height: 1234,
+			TxResult: abcitypes.ExecTxResult{
+				Code:      78,
+				Codespace: "emissions",
+				Log:       "failed to execute message; message index: 0: cannot update EMA more than once per window",
+				GasWanted: 200000,
+				GasUsed:   150321,
+			},
+		}, nil
+	}
+
+	res, err := pollTxResult(context.Background(), "AB12", time.Second, time.Millisecond, lookup)
+	require.NoError(t, err)
+	assert.Equal(t, 3, lookups, "the tx is polled until found in a block")
+
+	result := txResultOf(res)
+	assert.Equal(t, &TxResult{
+		TxHash:    "AB12",
+		Height:    1234,
+		GasWanted: 200000,
+		GasUsed:   150321,
+		Codespace: "emissions",
+		Code:      78,
+		RawLog:    "failed to execute message; message index: 0: cannot update EMA more than once per window",
+	}, result)
+	assert.False(t, result.Succeeded())
+}
+
+func TestPollTxResultTimeout(t *testing.T) {
+	lookup := func(ctx context.Context, hash []byte) (*ctypes.ResultTx, error) {
+		return nil, errors.New("tx (AB12) not found")
+	}
+	start := time.Now()
+	_, err := pollTxResult(context.Background(), "AB12", 50*time.Millisecond, 10*time.Millisecond, lookup)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	_, err = pollTxResult(context.Background(), "not hex", time.Second, time.Millisecond, lookup)
+	assert.Error(t, err)
+}
+
+func TestTxResultLog(t *testing.T) {
+	results := NewTxResultLog(2)
+	results.Record(&TxResult{TxHash: "A", Height: 1})
+	results.Record(&TxResult{TxHash: "B", Height: 2})
+	results.Record(&TxResult{TxHash: "A", Height: 3})
+	assert.Len(t, results.Recent(), 2, "a tx recorded again is updated")
+
+	results.Record(&TxResult{TxHash: "C", Height: 4})
+	_, ok := results.Get("A")
+	assert.False(t, ok, "the oldest result is forgotten once the log is full")
+	result, ok := results.Get("C")
+	require.True(t, ok)
+	assert.Equal(t, int64(4), result.Height)
+	assert.Equal(t, []string{"B", "C"}, []string{results.Recent()[0].TxHash, results.Recent()[1].TxHash})
+
+	var noLog *TxResultLog
+	noLog.Record(&TxResult{TxHash: "A"})
+	_, ok = noLog.Get("A")
+	assert.False(t, ok)
+}
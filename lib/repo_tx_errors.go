@@ -0,0 +1,157 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	errorsmod "cosmossdk.io/errors"
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+// How SendDataWithRetry handles a tx error
+type TxErrorPolicy string
+
+const (
+	TX_ERROR_POLICY_RETRY             TxErrorPolicy = "retry"             // retried after RetryDelay
+	TX_ERROR_POLICY_FATAL             TxErrorPolicy = "fatal"             // not retried, the tx can't succeed
+	TX_ERROR_POLICY_ALREADY_SUBMITTED TxErrorPolicy = "already-submitted" // not retried, the data is already on chain
+	TX_ERROR_POLICY_FEE               TxErrorPolicy = "fee"               // retried with a growing delay, the tx is outbid
+)
+
+// ABCI error of a tx, returned by the chain on its simulation, its check or its execution in a block
+type TxError struct {
+	Codespace string
+	Code      uint32
+	RawLog    string
+	TxHash    string
+	Result    *TxResult // set if the tx failed after its inclusion in a block
+}
+
+func (e *TxError) Error() string {
+	return fmt.Sprintf("error code: '%d' codespace: '%s' msg: '%s'", e.Code, e.Codespace, e.RawLog)
+}
+
+// Key of the error in the policy table, e.g. "sdk:32"
+func (e *TxError) Key() string {
+	return TxErrorKey(e.Codespace, e.Code)
+}
+
+// Returned once a tx is accepted in the mempool but couldn't be found in a block
+type TxInclusionError struct {
+	TxHash string
+	Err    error
+}
+
+func (e *TxInclusionError) Error() string {
+	return fmt.Sprintf("tx %s accepted but not found in a block: %s", e.TxHash, e.Err)
+}
+
+func (e *TxInclusionError) Unwrap() error {
+	return e.Err
+}
+
+func TxErrorKey(codespace string, code uint32) string {
+	return codespace + ":" + strconv.FormatUint(uint64(code), 10)
+}
+
+func txErrorKeyOf(err *errorsmod.Error) string {
+	return TxErrorKey(err.Codespace(), err.ABCICode())
+}
+
+// Policies of the chain errors a worker or reputer tx runs into, by codespace and code.
+// Errors missing from the table are retried.
+var DefaultTxErrorPolicies = map[string]TxErrorPolicy{
+	txErrorKeyOf(sdkerrors.ErrWrongSequence):     TX_ERROR_POLICY_RETRY,
+	txErrorKeyOf(sdkerrors.ErrInvalidSequence):   TX_ERROR_POLICY_RETRY,
+	txErrorKeyOf(sdkerrors.ErrOutOfGas):          TX_ERROR_POLICY_RETRY,
+	txErrorKeyOf(sdkerrors.ErrTxTimeoutHeight):   TX_ERROR_POLICY_RETRY,
+	txErrorKeyOf(sdkerrors.ErrMempoolIsFull):     TX_ERROR_POLICY_FEE,
+	txErrorKeyOf(sdkerrors.ErrInsufficientFee):   TX_ERROR_POLICY_FEE,
+	txErrorKeyOf(sdkerrors.ErrTxInMempoolCache):  TX_ERROR_POLICY_ALREADY_SUBMITTED,
+	txErrorKeyOf(sdkerrors.ErrTxTooLarge):        TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrInvalidChainID):    TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrInsufficientFunds): TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrUnauthorized):      TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrUnknownAddress):    TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrInvalidAddress):    TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrInvalidPubKey):     TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrTxDecode):          TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrInvalidRequest):    TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrUnknownRequest):    TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrNoSignatures):      TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrTooManySignatures): TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrMemoTooLarge):      TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrInvalidGasLimit):   TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrInvalidCoins):      TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrNotSupported):      TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(sdkerrors.ErrInvalidType):       TX_ERROR_POLICY_FATAL,
+
+	txErrorKeyOf(emissionstypes.ErrCantUpdateEmaMoreThanOncePerWindow): TX_ERROR_POLICY_ALREADY_SUBMITTED,
+	txErrorKeyOf(emissionstypes.ErrReputerAlreadyRegisteredInTopic):    TX_ERROR_POLICY_ALREADY_SUBMITTED,
+	txErrorKeyOf(emissionstypes.ErrAddressAlreadyRegisteredInATopic):   TX_ERROR_POLICY_ALREADY_SUBMITTED,
+	txErrorKeyOf(emissionstypes.ErrWorkerNonceWindowNotAvailable):      TX_ERROR_POLICY_RETRY,
+	txErrorKeyOf(emissionstypes.ErrReputerNonceWindowNotAvailable):     TX_ERROR_POLICY_RETRY,
+	txErrorKeyOf(emissionstypes.ErrTopicMempoolAtCapacity):             TX_ERROR_POLICY_RETRY,
+	txErrorKeyOf(emissionstypes.ErrUnfulfilledNonceNotFound):           TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrNonceStillUnfulfilled):              TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrSignatureVerificationFailed):        TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrInvalidWorkerData):                  TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrInvalidReputerData):                 TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrNoValidInferences):                  TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrNoValidForecastElements):            TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrInvalidValue):                       TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrInvalidTopicId):                     TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrTopicDoesNotExist):                  TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrAddressNotRegistered):               TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrAddressIsNotRegisteredInThisTopic):  TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrAddressIsNotRegisteredInAnyTopic):   TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrInsufficientStake):                  TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrNotInReputerWhitelist):              TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrDataSenderNotEnoughDenom):           TX_ERROR_POLICY_FATAL,
+	txErrorKeyOf(emissionstypes.ErrTopicRegistrantNotEnoughDenom):      TX_ERROR_POLICY_FATAL,
+}
+
+func IsValidTxErrorPolicy(policy TxErrorPolicy) bool {
+	switch policy {
+	case TX_ERROR_POLICY_RETRY, TX_ERROR_POLICY_FATAL, TX_ERROR_POLICY_ALREADY_SUBMITTED, TX_ERROR_POLICY_FEE:
+		return true
+	}
+	return false
+}
+
+// Checks the policy overrides of a config, keyed by "codespace:code"
+func ValidateTxErrorPolicies(policies map[string]TxErrorPolicy) error {
+	var errs []error
+	for key, policy := range policies {
+		codespace, code, found := strings.Cut(key, ":")
+		if _, err := strconv.ParseUint(code, 10, 32); !found || codespace == "" || err != nil {
+			errs = append(errs, fmt.Errorf("invalid tx error %q, expected codespace:code", key))
+		}
+		if !IsValidTxErrorPolicy(policy) {
+			errs = append(errs, fmt.Errorf("invalid policy %q for tx error %q", policy, key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Policy of a tx error: the override for its codespace and code if any, else the default one.
+// Fee caps being reached is fatal, and other errors which aren't ABCI errors, e.g. a node unavailable, are retried.
+func ClassifyTxError(err error, overrides map[string]TxErrorPolicy) TxErrorPolicy {
+	if errors.Is(err, ErrMaxFeeExceeded) {
+		return TX_ERROR_POLICY_FATAL
+	}
+	var txErr *TxError
+	if !errors.As(err, &txErr) {
+		return TX_ERROR_POLICY_RETRY
+	}
+	if policy, ok := overrides[txErr.Key()]; ok {
+		return policy
+	}
+	if policy, ok := DefaultTxErrorPolicies[txErr.Key()]; ok {
+		return policy
+	}
+	return TX_ERROR_POLICY_RETRY
+}
@@ -0,0 +1,76 @@
+package lib
+
+import (
+	"errors"
+	"testing"
+
+	errorsmod "cosmossdk.io/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyTxError(t *testing.T) {
+	// Codespaces, codes and raw logs as returned by the chain
+	tests := []struct {
+		name      string
+		codespace string
+		code      uint32
+		rawLog    string
+		policy    TxErrorPolicy
+	}{
+		{"wrong sequence", "sdk", 32, "account sequence mismatch, expected 1504, got 1503: incorrect account sequence", TX_ERROR_POLICY_RETRY},
+		{"invalid sequence", "sdk", 3, "invalid sequence", TX_ERROR_POLICY_RETRY},
+		{"out of gas", "sdk", 11, "out of gas in location: WriteFlat; gasWanted: 158794, gasUsed: 159802: out of gas", TX_ERROR_POLICY_RETRY},
+		{"insufficient fee", "sdk", 13, "insufficient fees; got: 1000uallo required: 2000uallo: insufficient fee", TX_ERROR_POLICY_FEE},
+		{"mempool full", "sdk", 20, "mempool is full", TX_ERROR_POLICY_FEE},
+		{"already in mempool cache", "sdk", 19, "tx already in mempool", TX_ERROR_POLICY_ALREADY_SUBMITTED},
+		{"tx too large", "sdk", 21, "tx too large", TX_ERROR_POLICY_FATAL},
+		{"insufficient funds", "sdk", 5, "spendable balance 12uallo is smaller than 2000uallo: insufficient funds", TX_ERROR_POLICY_FATAL},
+		{"account unknown", "sdk", 9, "account allo1k9ss0xfer54nyack5678frl36e5g3rj2yzxtfj not found: unknown address", TX_ERROR_POLICY_FATAL},
+		{"invalid chain id", "sdk", 28, "invalid chain-id on InitChain; expected: allora-testnet-1, got: allora-devnet: invalid chain-id", TX_ERROR_POLICY_FATAL},
+		{"ema already updated", "emissions", 78, "failed to execute message; message index: 0: cannot update EMA more than once per window", TX_ERROR_POLICY_ALREADY_SUBMITTED},
+		{"worker window not available", "emissions", 67, "failed to execute message; message index: 0: worker nonce window not available", TX_ERROR_POLICY_RETRY},
+		{"reputer window not available", "emissions", 68, "failed to execute message; message index: 0: reputer nonce window not available", TX_ERROR_POLICY_RETRY},
+		{"nonce closed", "emissions", 75, "failed to execute message; message index: 0: unfulfilled nonce not found", TX_ERROR_POLICY_FATAL},
+		{"bad bundle signature", "emissions", 47, "failed to execute message; message index: 0: signature verification was failed", TX_ERROR_POLICY_FATAL},
+		{"worker not registered", "emissions", 10, "failed to execute message; message index: 0: address is not registered in this topic", TX_ERROR_POLICY_FATAL},
+		{"reputer already registered", "emissions", 7, "failed to execute message; message index: 0: reputer already registered in topic", TX_ERROR_POLICY_ALREADY_SUBMITTED},
+		{"unknown emissions error", "emissions", 999, "something new", TX_ERROR_POLICY_RETRY},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTxError(tt.codespace, tt.code, tt.rawLog, "")
+			assert.Equal(t, tt.policy, ClassifyTxError(err, nil))
+			// Wrapped errors keep their policy
+			assert.Equal(t, tt.policy, ClassifyTxError(errorsmod.Wrap(err, "could not simulate tx"), nil))
+		})
+	}
+
+	assert.Equal(t, TX_ERROR_POLICY_RETRY, ClassifyTxError(errors.New("post failed: connection refused"), nil))
+	assert.Equal(t, TX_ERROR_POLICY_RETRY, ClassifyTxError(&TxInclusionError{TxHash: "AB", Err: errors.New("waiting for next block")}, nil))
+}
+
+func TestClassifyTxErrorOverrides(t *testing.T) {
+	overrides := map[string]TxErrorPolicy{"emissions:67": TX_ERROR_POLICY_FATAL, "sdk:19": TX_ERROR_POLICY_RETRY}
+	require.NoError(t, ValidateTxErrorPolicies(overrides))
+
+	assert.Equal(t, TX_ERROR_POLICY_FATAL, ClassifyTxError(newTxError("emissions", 67, "worker nonce window not available", ""), overrides))
+	assert.Equal(t, TX_ERROR_POLICY_RETRY, ClassifyTxError(newTxError("sdk", 19, "tx already in mempool", ""), overrides))
+	assert.Equal(t, TX_ERROR_POLICY_FEE, ClassifyTxError(newTxError("sdk", 13, "insufficient fee", ""), overrides))
+
+	err := ValidateTxErrorPolicies(map[string]TxErrorPolicy{"emissions": TX_ERROR_POLICY_FATAL, "sdk:13": "ignore"})
+	assert.ErrorContains(t, err, `invalid tx error "emissions"`)
+	assert.ErrorContains(t, err, `invalid policy "ignore"`)
+}
+
+func TestSequenceMismatchTxError(t *testing.T) {
+	err := newTxError("sdk", 32, "account sequence mismatch, expected 1504, got 1503: incorrect account sequence", "")
+	var mismatch *SequenceMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, uint64(1504), mismatch.Expected)
+	var txErr *TxError
+	require.ErrorAs(t, err, &txErr)
+	assert.Equal(t, "sdk:32", txErr.Key())
+
+	assert.False(t, errors.As(newTxError("sdk", 3, "invalid sequence", ""), &mismatch))
+}
@@ -0,0 +1,195 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	nodeservice "github.com/cosmos/cosmos-sdk/client/grpc/node"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/rs/zerolog/log"
+)
+
+const MIN_GAS_PRICE_REFRESH_INTERVAL = 10 * time.Minute // how often the node's minimum gas price is queried again
+
+// Returned instead of sending a tx whose fee would exceed a configured cap
+var ErrMaxFeeExceeded = errors.New("max fee exceeded")
+
+// Queries the minimum gas prices accepted by the node
+type MinGasPriceQueryFunc func(ctx context.Context) (sdktypes.DecCoins, error)
+
+// Prices the fee of an account's txs according to its FeeConfig: a fixed or queried gas price,
+// escalated after fee related tx errors, within the caps per tx and per day
+type FeeManager struct {
+	config       FeeConfig
+	denom        string
+	gasPrice     sdkmath.LegacyDec
+	maxFeePerTx  *sdkmath.Int
+	maxFeePerDay *sdkmath.Int
+	queryMinGas  MinGasPriceQueryFunc
+	now          func() time.Time
+
+	mu          sync.Mutex
+	multiplier  sdkmath.LegacyDec
+	minGasPrice sdkmath.LegacyDec
+	queriedAt   time.Time
+	day         string
+	spentToday  sdkmath.Int
+}
+
+func parseFeeCap(fee string, denom string) (*sdkmath.Int, string, error) {
+	if fee == "" {
+		return nil, denom, nil
+	}
+	coin, err := sdktypes.ParseCoinNormalized(fee)
+	if err != nil {
+		return nil, denom, err
+	}
+	if denom != "" && coin.Denom != denom {
+		return nil, denom, fmt.Errorf("fee %s not in the gas price denom %s", fee, denom)
+	}
+	return &coin.Amount, coin.Denom, nil
+}
+
+func NewFeeManager(config FeeConfig, defaultDenom string, queryMinGas MinGasPriceQueryFunc) (*FeeManager, error) {
+	fees := &FeeManager{
+		config:      config,
+		gasPrice:    sdkmath.LegacyZeroDec(),
+		queryMinGas: queryMinGas,
+		now:         time.Now,
+		multiplier:  sdkmath.LegacyOneDec(),
+		minGasPrice: sdkmath.LegacyZeroDec(),
+		spentToday:  sdkmath.ZeroInt(),
+	}
+	if config.GasPrice != "" {
+		gasPrice, err := sdktypes.ParseDecCoin(config.GasPrice)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gas price: %w", err)
+		}
+		fees.gasPrice, fees.denom = gasPrice.Amount, gasPrice.Denom
+	}
+	var err error
+	if fees.maxFeePerTx, fees.denom, err = parseFeeCap(config.MaxFeePerTx, fees.denom); err != nil {
+		return nil, fmt.Errorf("invalid max fee per tx: %w", err)
+	}
+	if fees.maxFeePerDay, fees.denom, err = parseFeeCap(config.MaxFeePerDay, fees.denom); err != nil {
+		return nil, fmt.Errorf("invalid max fee per day: %w", err)
+	}
+	if fees.denom == "" {
+		fees.denom = defaultDenom
+	}
+	if config.EscalationFactor < 0 {
+		return nil, fmt.Errorf("invalid gas price escalation factor %v", config.EscalationFactor)
+	}
+	return fees, nil
+}
+
+// Gas price before escalation: the fixed one, or the node's minimum if higher and queried
+func (f *FeeManager) baseGasPrice(ctx context.Context) sdkmath.LegacyDec {
+	if !f.config.QueryMinGasPrice || f.queryMinGas == nil {
+		return f.gasPrice
+	}
+	if f.queriedAt.IsZero() || f.now().Sub(f.queriedAt) >= MIN_GAS_PRICE_REFRESH_INTERVAL {
+		f.queriedAt = f.now()
+		minGasPrices, err := f.queryMinGas(ctx)
+		if err != nil {
+			log.Warn().Err(err).Str("minGasPrice", f.minGasPrice.String()).Msg("Could not query the node's minimum gas price, keeping previous value")
+		} else {
+			f.minGasPrice = minGasPrices.AmountOf(f.denom)
+		}
+	}
+	return sdkmath.LegacyMaxDec(f.gasPrice, f.minGasPrice)
+}
+
+func (f *FeeManager) rollDay() {
+	if day := f.now().UTC().Format(time.DateOnly); day != f.day {
+		f.day = day
+		f.spentToday = sdkmath.ZeroInt()
+	}
+}
+
+// Fee of a tx using the given gas, nil if no gas price applies.
+// The escalated fee is capped to the max fee per tx, and a tx which can't fit within the caps fails with ErrMaxFeeExceeded.
+// Nil-safe: no fee without a fee manager.
+func (f *FeeManager) Fee(ctx context.Context, gas uint64) (sdktypes.Coins, error) {
+	if f == nil {
+		return nil, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	base := f.baseGasPrice(ctx)
+	if !base.IsPositive() {
+		return nil, nil
+	}
+	gasDec := sdkmath.LegacyNewDecFromInt(sdkmath.NewIntFromUint64(gas))
+	baseFee := base.Mul(gasDec).Ceil().TruncateInt()
+	fee := base.Mul(f.multiplier).Mul(gasDec).Ceil().TruncateInt()
+	if f.maxFeePerTx != nil && fee.GT(*f.maxFeePerTx) {
+		if baseFee.GT(*f.maxFeePerTx) {
+			return nil, fmt.Errorf("%w: fee %s%s for %d gas above the max fee per tx %s%s", ErrMaxFeeExceeded, baseFee, f.denom, gas, f.maxFeePerTx, f.denom)
+		}
+		fee = *f.maxFeePerTx
+	}
+	f.rollDay()
+	if f.maxFeePerDay != nil && f.spentToday.Add(fee).GT(*f.maxFeePerDay) {
+		return nil, fmt.Errorf("%w: fee %s%s on top of %s%s already spent today above the max fee per day %s%s", ErrMaxFeeExceeded, fee, f.denom, f.spentToday, f.denom, f.maxFeePerDay, f.denom)
+	}
+	return sdktypes.NewCoins(sdktypes.NewCoin(f.denom, fee)), nil
+}
+
+// Raises the gas price after a fee related tx error. Returns false if escalation is disabled.
+func (f *FeeManager) Escalate() bool {
+	if f == nil || f.config.EscalationFactor <= 1 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.multiplier = f.multiplier.Mul(sdkmath.LegacyMustNewDecFromStr(fmt.Sprintf("%f", f.config.EscalationFactor)))
+	log.Info().Str("multiplier", f.multiplier.String()).Msg("Escalated gas price")
+	return true
+}
+
+// Back to the base gas price, once a tx went through
+func (f *FeeManager) Reset() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.multiplier = sdkmath.LegacyOneDec()
+}
+
+// Counts the fee of a tx accepted in the mempool towards the max fee per day
+func (f *FeeManager) RecordPaid(fee sdktypes.Coins) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rollDay()
+	f.spentToday = f.spentToday.Add(fee.AmountOf(f.denom))
+}
+
+// Fees counted towards the max fee per day since the start of the UTC day
+func (f *FeeManager) SpentToday() sdktypes.Coin {
+	if f == nil {
+		return sdktypes.Coin{}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rollDay()
+	return sdktypes.NewCoin(f.denom, f.spentToday)
+}
+
+// Minimum gas prices configured on the node the client is connected to
+func (node *NodeConfig) QueryMinGasPrice(ctx context.Context) (sdktypes.DecCoins, error) {
+	res, err := nodeservice.NewServiceClient(node.Chain.Client.Context()).Config(ctx, &nodeservice.ConfigRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return sdktypes.ParseDecCoins(res.MinimumGasPrice)
+}
@@ -0,0 +1,128 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeManager(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       FeeConfig
+		minGasPrice  string
+		escalations  int
+		gas          uint64
+		expectedFee  string
+		maxFeeErrors bool
+	}{
+		{"no fees by default", FeeConfig{}, "", 0, 100000, "", false},
+		{"fixed gas price", FeeConfig{GasPrice: "0.025uallo"}, "", 0, 100001, "2501uallo", false},
+		{"queried min gas price above the fixed one", FeeConfig{GasPrice: "0.01uallo", QueryMinGasPrice: true}, "0.05uallo", 0, 100000, "5000uallo", false},
+		{"queried min gas price below the fixed one", FeeConfig{GasPrice: "0.1uallo", QueryMinGasPrice: true}, "0.05uallo", 0, 100000, "10000uallo", false},
+		{"queried min gas price only", FeeConfig{QueryMinGasPrice: true}, "0.05uallo,1stake", 0, 100000, "5000uallo", false},
+		{"escalated gas price", FeeConfig{GasPrice: "0.1uallo", EscalationFactor: 1.5}, "", 2, 100000, "22500uallo", false},
+		{"escalation disabled", FeeConfig{GasPrice: "0.1uallo"}, "", 2, 100000, "10000uallo", false},
+		{"escalated fee capped per tx", FeeConfig{GasPrice: "0.1uallo", EscalationFactor: 2, MaxFeePerTx: "15000uallo"}, "", 3, 100000, "15000uallo", false},
+		{"base fee above the cap per tx", FeeConfig{GasPrice: "0.1uallo", MaxFeePerTx: "5000uallo"}, "", 0, 100000, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := func(ctx context.Context) (sdktypes.DecCoins, error) {
+				return sdktypes.ParseDecCoins(tt.minGasPrice)
+			}
+			fees, err := NewFeeManager(tt.config, DEFAULT_BOND_DENOM, query)
+			require.NoError(t, err)
+			for i := 0; i < tt.escalations; i++ {
+				fees.Escalate()
+			}
+			fee, err := fees.Fee(context.Background(), tt.gas)
+			if tt.maxFeeErrors {
+				assert.ErrorIs(t, err, ErrMaxFeeExceeded)
+				assert.Equal(t, TX_ERROR_POLICY_FATAL, ClassifyTxError(err, nil))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedFee, fee.String())
+
+			fees.Reset()
+			fee, err = fees.Fee(context.Background(), tt.gas)
+			require.NoError(t, err)
+			if tt.escalations == 0 {
+				assert.Equal(t, tt.expectedFee, fee.String(), "a reset keeps the base gas price")
+			}
+		})
+	}
+}
+
+func TestFeeManagerMaxFeePerDay(t *testing.T) {
+	fees, err := NewFeeManager(FeeConfig{GasPrice: "0.1uallo", MaxFeePerDay: "25000uallo"}, DEFAULT_BOND_DENOM, nil)
+	require.NoError(t, err)
+	now := time.Date(2024, 10, 1, 23, 0, 0, 0, time.UTC)
+	fees.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		fee, err := fees.Fee(context.Background(), 100000)
+		require.NoError(t, err)
+		fees.RecordPaid(fee)
+	}
+	assert.Equal(t, "20000uallo", fees.SpentToday().String())
+	_, err = fees.Fee(context.Background(), 100000)
+	assert.ErrorIs(t, err, ErrMaxFeeExceeded)
+
+	// The budget is renewed every UTC day
+	now = now.Add(2 * time.Hour)
+	_, err = fees.Fee(context.Background(), 100000)
+	assert.NoError(t, err)
+	assert.Equal(t, "0uallo", fees.SpentToday().String())
+}
+
+func TestFeeManagerMinGasPriceQuery(t *testing.T) {
+	queries := 0
+	queryErr := error(nil)
+	fees, err := NewFeeManager(FeeConfig{GasPrice: "0.01uallo", QueryMinGasPrice: true}, DEFAULT_BOND_DENOM, func(ctx context.Context) (sdktypes.DecCoins, error) {
+		queries++
+		if queryErr != nil {
+			return nil, queryErr
+		}
+		return sdktypes.ParseDecCoins("0.05uallo")
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	fees.now = func() time.Time { return now }
+
+	fee, _ := fees.Fee(context.Background(), 1000)
+	assert.Equal(t, "50uallo", fee.String())
+	fees.Fee(context.Background(), 1000)
+	assert.Equal(t, 1, queries, "the min gas price is cached")
+
+	// A failed refresh keeps the previous min gas price
+	now = now.Add(MIN_GAS_PRICE_REFRESH_INTERVAL)
+	queryErr = errors.New("node unavailable")
+	fee, _ = fees.Fee(context.Background(), 1000)
+	assert.Equal(t, 2, queries)
+	assert.Equal(t, "50uallo", fee.String())
+}
+
+func TestNewFeeManagerInvalidConfig(t *testing.T) {
+	for _, config := range []FeeConfig{
+		{GasPrice: "cheap"},
+		{GasPrice: "0.1uallo", MaxFeePerTx: "100stake"},
+		{MaxFeePerDay: "lots"},
+		{EscalationFactor: -1},
+	} {
+		_, err := NewFeeManager(config, DEFAULT_BOND_DENOM, nil)
+		assert.Error(t, err, "%+v", config)
+	}
+
+	var noFees *FeeManager
+	fee, err := noFees.Fee(context.Background(), 100000)
+	assert.NoError(t, err)
+	assert.True(t, fee.IsZero())
+	assert.False(t, noFees.Escalate())
+}
@@ -7,14 +7,13 @@ import (
 
 	cosmossdk_io_math "cosmossdk.io/math"
 	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
 )
 
 // True if the actor is ultimately, definitively registered for the specified topic, else False
 // Idempotent in registration
-func (node *NodeConfig) RegisterWorkerIdempotently(config WorkerConfig) bool {
-	ctx := context.Background()
-
-	isRegistered, err := node.IsWorkerRegistered(config.TopicId)
+func (node *NodeConfig) RegisterWorkerIdempotently(ctx context.Context, config WorkerConfig) bool {
+	isRegistered, err := node.IsWorkerRegistered(ctx, config.TopicId)
 	if err != nil {
 		log.Error().Err(err).Msg("Could not check if the node is already registered for topic as worker, skipping")
 	}
@@ -29,7 +28,7 @@ func (node *NodeConfig) RegisterWorkerIdempotently(config WorkerConfig) bool {
 		return false
 	}
 
-	balance, err := node.GetBalance()
+	balance, err := node.GetBalance(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("Could not check if the worker node has enough balance to register, skipping")
 		return false
@@ -61,10 +60,8 @@ func (node *NodeConfig) RegisterWorkerIdempotently(config WorkerConfig) bool {
 // True if the actor is ultimately, definitively registered for the specified topic with at least config.MinStake placed on topic, else False
 // Actor may be either a worker or a reputer
 // Idempotent in registration and stake addition
-func (node *NodeConfig) RegisterAndStakeReputerIdempotently(config ReputerConfig) bool {
-	ctx := context.Background()
-
-	isRegistered, err := node.IsReputerRegistered(config.TopicId)
+func (node *NodeConfig) RegisterAndStakeReputerIdempotently(ctx context.Context, config ReputerConfig) bool {
+	isRegistered, err := node.IsReputerRegistered(ctx, config.TopicId)
 	if err != nil {
 		log.Error().Err(err).Msg("Could not check if the node is already registered for topic as reputer, skipping")
 	}
@@ -74,7 +71,7 @@ func (node *NodeConfig) RegisterAndStakeReputerIdempotently(config ReputerConfig
 	} else {
 		log.Info().Uint64("topicId", config.TopicId).Msg("Reputer node not yet registered. Attempting registration...")
 
-		balance, err := node.GetBalance()
+		balance, err := node.GetBalance(ctx)
 		if err != nil {
 			log.Error().Err(err).Msg("Could not check if the Reputer node has enough balance to register, skipping")
 			return false
@@ -108,7 +105,7 @@ func (node *NodeConfig) RegisterAndStakeReputerIdempotently(config ReputerConfig
 		log.Info().Uint64("topicId", config.TopicId).Msg("Reputer node registered")
 	}
 
-	stake, err := node.GetReputerStakeInTopic(config.TopicId, node.Chain.Address)
+	stake, err := node.GetReputerStakeInTopic(ctx, config.TopicId, node.Chain.Address)
 	if err != nil {
 		log.Error().Err(err).Msg("Could not check if the reputer node has enough balance to stake, skipping")
 		return false
@@ -119,12 +116,7 @@ func (node *NodeConfig) RegisterAndStakeReputerIdempotently(config ReputerConfig
 		return true
 	}
 
-	msgAddStake := &emissionstypes.AddStakeRequest{
-		Sender:  node.Wallet.Address,
-		Amount:  minStake.Sub(stake),
-		TopicId: config.TopicId,
-	}
-	res, err := node.SendDataWithRetry(ctx, msgAddStake, "Add reputer stake")
+	res, err := node.AddReputerStake(ctx, config.TopicId, minStake.Sub(stake))
 	if err != nil {
 		txHash := ""
 		if res != nil {
@@ -135,3 +127,34 @@ func (node *NodeConfig) RegisterAndStakeReputerIdempotently(config ReputerConfig
 	}
 	return true
 }
+
+// Removes the registration of the node's account in the topic as a worker or a reputer
+func (node *NodeConfig) RemoveRegistration(ctx context.Context, topicId emissionstypes.TopicId, role ActorRole) (*cosmosclient.Response, error) {
+	msg := &emissionstypes.RemoveRegistrationRequest{
+		Sender:    node.Chain.Address,
+		TopicId:   topicId,
+		IsReputer: role == ROLE_REPUTER,
+	}
+	return node.SendDataWithRetry(ctx, msg, "Remove "+string(role)+" registration")
+}
+
+// Adds the amount to the stake of the node's account as a reputer in the topic
+func (node *NodeConfig) AddReputerStake(ctx context.Context, topicId emissionstypes.TopicId, amount cosmossdk_io_math.Int) (*cosmosclient.Response, error) {
+	msg := &emissionstypes.AddStakeRequest{
+		Sender:  node.Wallet.Address,
+		Amount:  amount,
+		TopicId: topicId,
+	}
+	return node.SendDataWithRetry(ctx, msg, "Add reputer stake")
+}
+
+// Starts the removal of the amount from the stake of the node's account as a reputer in the topic.
+// The chain removes it after the stake removal delay, see GetStakeRemoval.
+func (node *NodeConfig) RemoveReputerStake(ctx context.Context, topicId emissionstypes.TopicId, amount cosmossdk_io_math.Int) (*cosmosclient.Response, error) {
+	msg := &emissionstypes.RemoveStakeRequest{
+		Sender:  node.Wallet.Address,
+		Amount:  amount,
+		TopicId: topicId,
+	}
+	return node.SendDataWithRetry(ctx, msg, "Remove reputer stake")
+}
@@ -0,0 +1,107 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+var accountSequenceMismatchRegex = regexp.MustCompile(`account sequence mismatch, expected (\d+), got (\d+)`)
+
+// Keeps track of an account's number and next sequence locally, so that concurrent submissions
+// from the same account neither wait for each other's inclusion in a block nor collide on a sequence.
+// Broadcasts of the account are serialized up to their acceptance in the mempool, and the sequence
+// is only fetched from the chain on startup and after a mismatch the chain didn't resolve.
+type AccountSequencer struct {
+	mu            sync.Mutex
+	accountNumber uint64
+	sequence      uint64
+	synced        bool
+}
+
+func NewAccountSequencer() *AccountSequencer {
+	return &AccountSequencer{}
+}
+
+// Fetches the account number and the next sequence of the account from the chain
+type SequenceSyncFunc func() (accountNumber uint64, sequence uint64, err error)
+
+// Broadcasts a tx signed with the given account number and sequence.
+// Returns whether the tx was accepted in the mempool, consuming the sequence.
+type SequencedBroadcastFunc func(accountNumber uint64, sequence uint64) (accepted bool, err error)
+
+// Returned by a broadcast rejected because of its sequence, with the sequence the chain expects if it reported it
+type SequenceMismatchError struct {
+	Expected      uint64
+	ExpectedKnown bool
+	Err           error
+}
+
+func (e *SequenceMismatchError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SequenceMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// Wraps err in a SequenceMismatchError, picking up the expected sequence from the chain's log if present
+func NewSequenceMismatchError(err error, rawLog string) *SequenceMismatchError {
+	mismatch := &SequenceMismatchError{Err: err}
+	if matches := accountSequenceMismatchRegex.FindStringSubmatch(rawLog); len(matches) == 3 {
+		if expected, parseErr := strconv.ParseUint(matches[1], 10, 64); parseErr == nil {
+			mismatch.Expected, mismatch.ExpectedKnown = expected, true
+		}
+	}
+	return mismatch
+}
+
+// Runs broadcast with the next sequence of the account, syncing it from the chain first if needed.
+// No other broadcast of the account runs meanwhile.
+// On a sequence mismatch, the sequence expected by the chain is used from then on,
+// or the account is synced again before the next broadcast if the chain didn't report it.
+func (s *AccountSequencer) Broadcast(sync SequenceSyncFunc, broadcast SequencedBroadcastFunc) error {
+	if s == nil {
+		return errors.New("no account sequencer")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.synced {
+		accountNumber, sequence, err := sync()
+		if err != nil {
+			return fmt.Errorf("could not sync account sequence: %w", err)
+		}
+		s.accountNumber, s.sequence, s.synced = accountNumber, sequence, true
+	}
+
+	accepted, err := broadcast(s.accountNumber, s.sequence)
+	if accepted {
+		s.sequence++
+	}
+	var mismatch *SequenceMismatchError
+	if errors.As(err, &mismatch) {
+		if mismatch.ExpectedKnown {
+			s.sequence = mismatch.Expected
+		} else {
+			s.synced = false
+		}
+	}
+	return err
+}
+
+// Forces a sync from the chain before the next broadcast
+func (s *AccountSequencer) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.synced = false
+}
+
+// Next sequence to be used, and whether it is known
+func (s *AccountSequencer) NextSequence() (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sequence, s.synced
+}
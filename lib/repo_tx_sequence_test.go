@@ -0,0 +1,106 @@
+package lib
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountSequencer(t *testing.T) {
+	syncs := 0
+	chainSequence := uint64(7)
+	syncSequence := func() (uint64, uint64, error) {
+		syncs++
+		return 42, chainSequence, nil
+	}
+	var used []uint64
+	accept := func(accountNumber uint64, sequence uint64) (bool, error) {
+		assert.Equal(t, uint64(42), accountNumber)
+		used = append(used, sequence)
+		return true, nil
+	}
+
+	sequencer := NewAccountSequencer()
+	require.NoError(t, sequencer.Broadcast(syncSequence, accept))
+	require.NoError(t, sequencer.Broadcast(syncSequence, accept))
+	assert.Equal(t, []uint64{7, 8}, used, "sequences are handed out locally after the first sync")
+	assert.Equal(t, 1, syncs)
+
+	// A tx rejected for another reason doesn't consume its sequence
+	err := sequencer.Broadcast(syncSequence, func(uint64, uint64) (bool, error) {
+		return false, errors.New("error code: '13' msg: 'insufficient fee'")
+	})
+	require.Error(t, err)
+	next, synced := sequencer.NextSequence()
+	assert.Equal(t, uint64(9), next)
+	assert.True(t, synced)
+
+	// A mismatch reporting the expected sequence is resolved without querying the chain
+	rawLog := "account sequence mismatch, expected 12, got 9: incorrect account sequence"
+	err = sequencer.Broadcast(syncSequence, func(uint64, uint64) (bool, error) {
+		return false, NewSequenceMismatchError(errors.New(rawLog), rawLog)
+	})
+	var mismatch *SequenceMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, uint64(12), mismatch.Expected)
+	next, _ = sequencer.NextSequence()
+	assert.Equal(t, uint64(12), next)
+	assert.Equal(t, 1, syncs)
+
+	// Otherwise the account is synced again before the next broadcast
+	err = sequencer.Broadcast(syncSequence, func(uint64, uint64) (bool, error) {
+		return false, NewSequenceMismatchError(errors.New("incorrect account sequence"), "")
+	})
+	require.Error(t, err)
+	_, synced = sequencer.NextSequence()
+	assert.False(t, synced)
+	chainSequence = 20
+	used = nil
+	require.NoError(t, sequencer.Broadcast(syncSequence, accept))
+	assert.Equal(t, []uint64{20}, used)
+	assert.Equal(t, 2, syncs)
+
+	// A failed sync doesn't broadcast
+	sequencer.Invalidate()
+	err = sequencer.Broadcast(func() (uint64, uint64, error) {
+		return 0, 0, errors.New("node unavailable")
+	}, func(uint64, uint64) (bool, error) {
+		t.Fatal("broadcast without a synced sequence")
+		return false, nil
+	})
+	assert.ErrorContains(t, err, "node unavailable")
+
+	var noSequencer *AccountSequencer
+	assert.Error(t, noSequencer.Broadcast(syncSequence, accept))
+}
+
+func TestAccountSequencerConcurrentBroadcasts(t *testing.T) {
+	sequencer := NewAccountSequencer()
+	syncSequence := func() (uint64, uint64, error) { return 1, 0, nil }
+
+	var mu sync.Mutex
+	seen := map[uint64]bool{}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := sequencer.Broadcast(syncSequence, func(_ uint64, sequence uint64) (bool, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				assert.False(t, seen[sequence], "sequence %d handed out twice", sequence)
+				seen[sequence] = true
+				return true, nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, 50)
+	next, _ := sequencer.NextSequence()
+	assert.Equal(t, uint64(50), next)
+}
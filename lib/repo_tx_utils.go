@@ -2,102 +2,112 @@ package lib
 
 import (
 	"context"
+	"errors"
 	"math"
-	"regexp"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
 	errorsmod "cosmossdk.io/errors"
 	sdktypes "github.com/cosmos/cosmos-sdk/types"
-	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
 	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
 )
 
-const ERROR_MESSAGE_EMA_ALREADY_SENT = "cannot update EMA"
-const ERROR_MESSAGE_TX_INCLUDED_IN_BLOCK = "waiting for next block"
-const ERROR_MESSAGE_ACCOUNT_SEQUENCE_MISMATCH = "account sequence mismatch"
-const ERROR_MESSAGE_ABCI_ERROR_CODE_MARKER = "error code:"
+// Sleeps for the duration, or until the context is done, returning its error then
+func SleepWithContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
 // SendDataWithRetry attempts to send data with a uniform backoff strategy for retries.
 // uniform backoff is preferred to avoid exiting the open submission windows
 func (node *NodeConfig) SendDataWithRetry(ctx context.Context, req sdktypes.Msg, infoMsg string) (*cosmosclient.Response, error) {
+	return node.SendMsgsWithRetry(ctx, []sdktypes.Msg{req}, infoMsg)
+}
+
+// SendMsgsWithRetry sends the msgs as a single tx, retrying like SendDataWithRetry.
+// Errors are handled according to their TxErrorPolicy.
+// A multi-msg tx failed by one of its msgs is not retried, for the caller to handle that msg separately.
+// Once the context is done, no tx is broadcast anymore and retries are abandoned.
+func (node *NodeConfig) SendMsgsWithRetry(ctx context.Context, msgs []sdktypes.Msg, infoMsg string) (*cosmosclient.Response, error) {
 	var txResp *cosmosclient.Response
 	var err error
-	var hadEOFTxError bool
+	var hadInclusionError bool
+	var hadSequenceMismatch bool
 	for retryCount := int64(0); retryCount <= node.Wallet.MaxRetries; retryCount++ {
-		txResponse, err := node.Chain.Client.BroadcastTx(ctx, node.Chain.Account, req)
+		var txResponse cosmosclient.Response
+		txResponse, err = node.BroadcastTx(ctx, msgs...)
 		txResp = &txResponse
 		if err == nil {
 			log.Debug().Str("msg", infoMsg).Str("txHash", txResp.TxHash).Msg("Success")
 			return txResp, nil
 		}
+		if ctx.Err() != nil {
+			return nil, errorsmod.Wrap(err, "tx abandoned on shutdown")
+		}
 
-		if strings.Contains(err.Error(), ERROR_MESSAGE_ABCI_ERROR_CODE_MARKER) {
-			// Parse ABCI numerical error code
-			re := regexp.MustCompile(`error code: '(\d+)'`)
-			matches := re.FindStringSubmatch(err.Error())
-			if len(matches) == 2 {
-				errorCode, parseErr := strconv.Atoi(matches[1])
-				if parseErr != nil {
-					log.Error().Err(parseErr).Str("msg", infoMsg).Msg("Failed to parse ABCI error code")
-				} else {
-					switch errorCode {
-					case int(sdkerrors.ErrMempoolIsFull.ABCICode()):
-						// Mempool is full, retry with exponential backoff
-						log.Warn().Str("msg", infoMsg).Msg("Mempool is full, retrying with exponential backoff")
-						delay := time.Duration(math.Pow(float64(node.Wallet.RetryDelay), float64(retryCount))) * time.Second
-						time.Sleep(delay)
-						continue
-					case int(sdkerrors.ErrWrongSequence.ABCICode()):
-					case int(sdkerrors.ErrInvalidSequence.ABCICode()):
-						log.Warn().Str("msg", infoMsg).Msg("Account sequence mismatch detected, allow re-fetching sequence")
-						// Wait a fixed block-related waiting time
-						time.Sleep(time.Duration(node.Wallet.AccountSequenceRetryDelay) * time.Second)
-						continue
-					case int(sdkerrors.ErrTxTooLarge.ABCICode()):
-						return nil, errorsmod.Wrapf(err, "tx too large")
-					case int(sdkerrors.ErrTxInMempoolCache.ABCICode()):
-						return nil, errorsmod.Wrapf(err, "tx already in mempool cache")
-					case int(sdkerrors.ErrInvalidChainID.ABCICode()):
-						return nil, errorsmod.Wrapf(err, "invalid chain-id")
+		if _, ok := FailedMessageIndex(err); ok && len(msgs) > 1 {
+			return nil, err
+		}
 
-					default:
-						log.Info().Str("msg", infoMsg).Msg("ABCI error, but not special case - regular retry")
-					}
+		var mismatch *SequenceMismatchError
+		if errors.As(err, &mismatch) {
+			// The sequencer has already resynced: retry right away, unless another process keeps using the account
+			if hadSequenceMismatch {
+				log.Warn().Str("msg", infoMsg).Msg("Repeated account sequence mismatch, waiting before retrying")
+				if err := SleepWithContext(ctx, time.Duration(node.Wallet.AccountSequenceRetryDelay)*time.Second); err != nil {
+					return nil, err
 				}
 			} else {
-				log.Error().Str("msg", infoMsg).Msg("Unmatched error format, cannot classify as ABCI error")
+				log.Warn().Str("msg", infoMsg).Msg("Account sequence mismatch detected, retrying with resynced sequence")
 			}
+			hadSequenceMismatch = true
+			continue
 		}
 
-		// NOT ABCI error code: keep on checking for specially handled error types
-		if strings.Contains(err.Error(), ERROR_MESSAGE_ACCOUNT_SEQUENCE_MISMATCH) {
-			log.Warn().Str("msg", infoMsg).Msg("Account sequence mismatch detected, re-fetching sequence")
-			// Wait a fixed block-related waiting time
-			time.Sleep(time.Duration(node.Wallet.AccountSequenceRetryDelay) * time.Second)
-			continue
-		} else if strings.Contains(err.Error(), ERROR_MESSAGE_TX_INCLUDED_IN_BLOCK) {
-			// First time seeing this error, set up the EOFTxError flag and retry normally
-			if !hadEOFTxError {
-				hadEOFTxError = true
+		var inclusionErr *TxInclusionError
+		if errors.As(err, &inclusionErr) {
+			// First time seeing this error, set up the flag and retry normally
+			if !hadInclusionError {
+				hadInclusionError = true
 				log.Warn().Err(err).Str("msg", infoMsg).Msg("Tx sent, waiting for tx to be included in a block, regular retry")
 			}
-			// Wait for the next block
-		} else if strings.Contains(err.Error(), ERROR_MESSAGE_EMA_ALREADY_SENT) {
-			if hadEOFTxError {
-				log.Info().Str("msg", infoMsg).Msg("Confirmation: the tx sent for this epoch has been accepted")
-			} else {
-				log.Info().Err(err).Str("msg", infoMsg).Msg("Already sent data for this epoch.")
+		} else {
+			switch ClassifyTxError(err, node.Wallet.TxErrorPolicies) {
+			case TX_ERROR_POLICY_ALREADY_SUBMITTED:
+				if hadInclusionError {
+					log.Info().Str("msg", infoMsg).Msg("Confirmation: the tx sent for this epoch has been accepted")
+				} else {
+					log.Info().Err(err).Str("msg", infoMsg).Msg("Already sent data for this epoch.")
+				}
+				return txResp, nil
+			case TX_ERROR_POLICY_FATAL:
+				return nil, errorsmod.Wrap(err, "tx cannot succeed, not retrying")
+			case TX_ERROR_POLICY_FEE:
+				if node.Chain.Fees.Escalate() {
+					log.Warn().Err(err).Str("msg", infoMsg).Msg("Tx outbid, retrying with a higher gas price")
+					break
+				}
+				log.Warn().Err(err).Str("msg", infoMsg).Msg("Tx outbid, retrying with exponential backoff")
+				delay := time.Duration(math.Pow(float64(node.Wallet.RetryDelay), float64(retryCount))) * time.Second
+				if err := SleepWithContext(ctx, delay); err != nil {
+					return nil, err
+				}
+				continue
 			}
-			return txResp, nil
 		}
 		// Log the error for each retry.
 		log.Error().Err(err).Str("msg", infoMsg).Msgf("Failed, retrying... (Retry %d/%d)", retryCount, node.Wallet.MaxRetries)
 		// Wait for the uniform delay before retrying
-		time.Sleep(time.Duration(node.Wallet.RetryDelay) * time.Second)
+		if err := SleepWithContext(ctx, time.Duration(node.Wallet.RetryDelay)*time.Second); err != nil {
+			return nil, err
+		}
 	}
 	// All retries failed, return the last error
 	return nil, err
@@ -3,9 +3,15 @@ package main
 import (
 	"allora_offchain_node/lib"
 	usecase "allora_offchain_node/usecase"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
@@ -57,53 +63,83 @@ func ConvertEntrypointsToInstances(userConfig lib.UserConfig) error {
 	return nil
 }
 
+// Reads the config from the JSON env var, or else from the JSON, YAML or TOML config file, and instantiates its adapters.
+// Environment variables referenced by its values are substituted. Unknown fields and invalid values are rejected,
+// all of them reported at once.
+func LoadUserConfig() (lib.UserConfig, error) {
+	var userConfig lib.UserConfig
+	if alloraJsonConfig := os.Getenv(lib.ALLORA_OFFCHAIN_NODE_CONFIG_JSON); alloraJsonConfig != "" {
+		log.Info().Msg("Config using JSON env var")
+		config, err := lib.ParseUserConfig([]byte(alloraJsonConfig))
+		if err != nil {
+			return lib.UserConfig{}, fmt.Errorf("failed to parse JSON config from env var: %w", err)
+		}
+		userConfig = config
+	} else if configFilePath := os.Getenv(lib.ALLORA_OFFCHAIN_NODE_CONFIG_FILE_PATH); configFilePath != "" {
+		log.Info().Str("path", configFilePath).Msg("Config using config file")
+		config, err := lib.LoadUserConfigFile(configFilePath)
+		if err != nil {
+			return lib.UserConfig{}, fmt.Errorf("failed to load config file %s: %w", configFilePath, err)
+		}
+		userConfig = config
+	} else {
+		return lib.UserConfig{}, errors.New("could not find config file. Please create a config.json file and pass as environment variable")
+	}
+
+	// Convert entrypoints to instances of adapters
+	if err := ConvertEntrypointsToInstances(userConfig); err != nil {
+		return lib.UserConfig{}, fmt.Errorf("failed to convert Entrypoints to instances of adapters: %w", err)
+	}
+	return userConfig, nil
+}
+
+// Loads the config again and applies it to the running actors, or keeps the running config if it is invalid
+func ReloadUserConfig(spawner *usecase.UseCaseSuite) {
+	log.Info().Msg("Reloading config...")
+	userConfig, err := LoadUserConfig()
+	if err == nil {
+		err = spawner.Reload(userConfig)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Rejected new config, keeping the running one")
+	}
+}
+
 func main() {
 	initLogger()
 	if dotErr := godotenv.Load(); dotErr != nil {
 		log.Info().Msg("Unable to load .env file")
 	}
 
-	log.Info().Msg("Starting allora offchain node...")
-
-	metrics := lib.NewMetrics(lib.COUNTER_DATA)
-	metrics.RegisterMetricsCounters()
-	metrics.StartMetricsServer(":2112")
+	// Cancelled on SIGINT or SIGTERM, e.g. on a Kubernetes rollout, to stop the actors.
+	// A second signal kills the node right away.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	finalUserConfig := lib.UserConfig{}
-	alloraJsonConfig := os.Getenv(lib.ALLORA_OFFCHAIN_NODE_CONFIG_JSON)
-	if alloraJsonConfig != "" {
-		log.Info().Msg("Config using JSON env var")
-		// completely reset UserConfig
-		err := json.Unmarshal([]byte(alloraJsonConfig), &finalUserConfig)
-		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to parse JSON config file from Config")
-			return
-		}
-	} else if os.Getenv(lib.ALLORA_OFFCHAIN_NODE_CONFIG_FILE_PATH) != "" {
-		log.Info().Msg("Config using JSON config file")
-		// parse file defined in CONFIG_FILE_PATH into UserConfig
-		file, err := os.Open(os.Getenv(lib.ALLORA_OFFCHAIN_NODE_CONFIG_FILE_PATH))
-		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to open JSON config file")
-			return
-		}
-		defer file.Close()
-		decoder := json.NewDecoder(file)
-		// completely reset UserConfig
-		err = decoder.Decode(&finalUserConfig)
-		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to parse JSON config file")
-			return
+	// Admin commands manage the accounts of the config and exit, see ADMIN_USAGE
+	if len(os.Args) > 1 {
+		err := RunAdminCommand(ctx, os.Args[1:])
+		if err != nil && !errors.Is(err, flag.ErrHelp) {
+			stop()
+			log.Fatal().Err(err).Msgf("Command %s failed", os.Args[1])
 		}
-	} else {
-		log.Fatal().Msg("Could not find config file. Please create a config.json file and pass as environment variable.")
 		return
 	}
 
-	// Convert entrypoints to instances of adapters
-	err := ConvertEntrypointsToInstances(finalUserConfig)
+	log.Info().Msg("Starting allora offchain node...")
+	go func() {
+		<-ctx.Done()
+		stop()
+		log.Info().Msg("Shutting down, waiting for the actors to stop...")
+	}()
+
+	metrics := lib.NewMetrics(lib.COUNTER_DATA)
+	metrics.RegisterMetricsCounters()
+	metricsServer := metrics.StartMetricsServer(":2112")
+
+	finalUserConfig, err := LoadUserConfig()
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to convert Entrypoints to instances of adapters")
+		log.Fatal().Err(err).Msg("Failed to load config")
 		return
 	}
 	spawner, err := usecase.NewUseCaseSuite(finalUserConfig)
@@ -113,5 +149,34 @@ func main() {
 	}
 
 	spawner.Metrics = *metrics
-	spawner.Spawn()
+	// Audit endpoints of the submission ledger and of the ground truth cache, served alongside the metrics
+	http.Handle("/submissions", spawner.Ledger)
+	http.Handle("/groundtruths", spawner.GroundTruths)
+
+	// Reload the config on SIGHUP, keeping the running one if the new one is invalid
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	go func() {
+		for {
+			select {
+			case <-hangups:
+				ReloadUserConfig(spawner)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	spawner.Spawn(ctx)
+
+	if err := spawner.Close(); err != nil {
+		log.Error().Err(err).Msg("Failed to close submission ledger")
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), lib.SHUTDOWN_TIMEOUT_SECONDS*time.Second)
+	defer cancel()
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to stop metrics server")
+	}
+	log.Info().Msg("Allora offchain node stopped")
 }
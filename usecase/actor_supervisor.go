@@ -0,0 +1,252 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Identifies an actor across config reloads. There is at most one actor per role, topic and wallet, see UserConfig.Validate.
+type actorKey struct {
+	role    lib.ActorRole
+	topicId emissionstypes.TopicId
+	keyName string
+}
+
+func (k actorKey) String() string {
+	return fmt.Sprintf("%s of topic %d with wallet %q", k.role, k.topicId, k.keyName)
+}
+
+// Config of an actor as found in the user config, either a worker or a reputer
+type actorConfig struct {
+	worker  *lib.WorkerConfig
+	reputer *lib.ReputerConfig
+}
+
+func (c actorConfig) wallet() *lib.WalletConfig {
+	if c.worker != nil {
+		return c.worker.Wallet
+	}
+	return c.reputer.Wallet
+}
+
+func actorsOf(config lib.UserConfig) map[actorKey]actorConfig {
+	actors := make(map[actorKey]actorConfig)
+	for i := range config.Worker {
+		worker := config.Worker[i]
+		actors[actorKey{lib.ROLE_WORKER, worker.TopicId, config.Wallet.Override(worker.Wallet).AddressKeyName}] = actorConfig{worker: &worker}
+	}
+	for i := range config.Reputer {
+		reputer := config.Reputer[i]
+		actors[actorKey{lib.ROLE_REPUTER, reputer.TopicId, config.Wallet.Override(reputer.Wallet).AddressKeyName}] = actorConfig{reputer: &reputer}
+	}
+	return actors
+}
+
+// Changes to apply to the running actors to match a new config
+type actorPlan struct {
+	start   []actorKey // actors new to the config
+	stop    []actorKey // actors removed from the config
+	update  []actorKey // actors keeping running, with their config updated in place
+	restart []actorKey // actors whose registration depends on a changed field, i.e. the min stake of a reputer
+}
+
+// Plans the changes from the applied config of the actors to the reloaded one.
+// Fails if the node wallet, or the wallet of a running actor, changed, as wallets are only loaded on startup.
+func planActors(applied lib.UserConfig, reloaded lib.UserConfig) (actorPlan, error) {
+	if !reflect.DeepEqual(applied.Wallet, reloaded.Wallet) {
+		return actorPlan{}, errors.New("wallet config changed, restart the node to apply it")
+	}
+	plan := actorPlan{}
+	oldActors, newActors := actorsOf(applied), actorsOf(reloaded)
+	for key := range oldActors {
+		if _, ok := newActors[key]; !ok {
+			plan.stop = append(plan.stop, key)
+		}
+	}
+	for key, newConfig := range newActors {
+		oldConfig, ok := oldActors[key]
+		switch {
+		case !ok:
+			plan.start = append(plan.start, key)
+		case !reflect.DeepEqual(oldConfig.wallet(), newConfig.wallet()):
+			return actorPlan{}, fmt.Errorf("wallet of the %s changed, restart the node to apply it", key)
+		case newConfig.reputer != nil && newConfig.reputer.MinStake != oldConfig.reputer.MinStake:
+			plan.restart = append(plan.restart, key)
+		case !reflect.DeepEqual(oldConfig, newConfig):
+			plan.update = append(plan.update, key)
+		}
+	}
+	return plan, nil
+}
+
+// Config of a running actor, read by the actor on every iteration so that reloads apply in place
+type liveConfig[T any] struct {
+	mu     sync.RWMutex
+	config T
+}
+
+func newLiveConfig[T any](config T) *liveConfig[T] {
+	return &liveConfig[T]{config: config}
+}
+
+func (c *liveConfig[T]) Get() T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
+
+func (c *liveConfig[T]) Set(config T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = config
+}
+
+type runningActor struct {
+	cancel  context.CancelFunc
+	done    chan struct{}
+	worker  *liveConfig[lib.WorkerConfig]  // nil for reputers
+	reputer *liveConfig[lib.ReputerConfig] // nil for workers
+}
+
+// Runs the actors of the user config, and reconciles them with the config on every reload
+type ActorSupervisor struct {
+	mu      sync.Mutex
+	ctx     context.Context // nil until the actors are spawned
+	wg      sync.WaitGroup
+	config  lib.UserConfig // config last applied
+	running map[actorKey]*runningActor
+}
+
+func NewActorSupervisor(config lib.UserConfig) *ActorSupervisor {
+	return &ActorSupervisor{config: config, running: make(map[actorKey]*runningActor)}
+}
+
+// Validations of a user config common to startup and reloads
+func validateUserConfig(config *lib.UserConfig) error {
+	return config.Validate()
+}
+
+// Starts the actors of the config last applied, which then run until the context is done
+func (suite *UseCaseSuite) startActors(ctx context.Context) {
+	supervisor := suite.Actors
+	supervisor.mu.Lock()
+	defer supervisor.mu.Unlock()
+	supervisor.ctx = ctx
+	for key, config := range actorsOf(supervisor.config) {
+		suite.startActor(key, config)
+	}
+}
+
+// Waits for every actor to stop
+func (suite *UseCaseSuite) waitForActors() {
+	suite.Actors.wg.Wait()
+}
+
+// Reconciles the running actors with the new config: starts the new actors, stops the removed ones,
+// and updates the others in place, without interrupting them.
+// An invalid config is rejected, the actors keep running with the config last applied.
+func (suite *UseCaseSuite) Reload(config lib.UserConfig) error {
+	if err := validateUserConfig(&config); err != nil {
+		return err
+	}
+	supervisor := suite.Actors
+	supervisor.mu.Lock()
+	defer supervisor.mu.Unlock()
+
+	if supervisor.ctx != nil && supervisor.ctx.Err() != nil {
+		return errors.New("node is shutting down")
+	}
+	plan, err := planActors(supervisor.config, config)
+	if err != nil {
+		return err
+	}
+	newActors := actorsOf(config)
+	for _, key := range plan.start {
+		if err := suite.loadActorNode(newActors[key].wallet()); err != nil {
+			return fmt.Errorf("cannot load wallet of the %s: %w", key, err)
+		}
+	}
+	supervisor.config = config
+	if supervisor.ctx == nil {
+		// Not spawned yet, the actors start with the new config
+		return nil
+	}
+
+	for _, key := range append(plan.stop, plan.restart...) {
+		suite.stopActor(key)
+	}
+	for _, key := range append(plan.start, plan.restart...) {
+		suite.startActor(key, newActors[key])
+	}
+	for _, key := range plan.update {
+		running, ok := supervisor.running[key]
+		if !ok {
+			// The actor stopped on its own, e.g. failing to register, and is given another chance
+			suite.startActor(key, newActors[key])
+			continue
+		}
+		if running.worker != nil {
+			running.worker.Set(*newActors[key].worker)
+		} else {
+			running.reputer.Set(*newActors[key].reputer)
+		}
+		log.Info().Stringer("actor", key).Msg("Actor config updated")
+	}
+	log.Info().Int("started", len(plan.start)).Int("stopped", len(plan.stop)).Int("restarted", len(plan.restart)).Int("updated", len(plan.update)).Msg("Config reloaded")
+	return nil
+}
+
+// Starts the actor. The supervisor lock must be held.
+func (suite *UseCaseSuite) startActor(key actorKey, config actorConfig) {
+	supervisor := suite.Actors
+	ctx, cancel := context.WithCancel(supervisor.ctx)
+	running := &runningActor{cancel: cancel, done: make(chan struct{})}
+	actor := suite.ForActor(config.wallet())
+	if config.worker != nil {
+		running.worker = newLiveConfig(*config.worker)
+	} else {
+		running.reputer = newLiveConfig(*config.reputer)
+	}
+	supervisor.running[key] = running
+
+	supervisor.wg.Add(1)
+	go func() {
+		defer supervisor.wg.Done()
+		// Unless stopped by the supervisor, forget the actor once it has returned on its own
+		defer func() {
+			running.cancel()
+			supervisor.mu.Lock()
+			defer supervisor.mu.Unlock()
+			if supervisor.running[key] == running {
+				delete(supervisor.running, key)
+			}
+		}()
+		defer close(running.done)
+		if running.worker != nil {
+			actor.runWorkerProcess(ctx, running.worker)
+		} else {
+			actor.runReputerProcess(ctx, running.reputer)
+		}
+	}()
+}
+
+// Stops the actor and waits for it to return, so that it never runs alongside its replacement.
+// The supervisor lock must be held.
+func (suite *UseCaseSuite) stopActor(key actorKey) {
+	supervisor := suite.Actors
+	running, ok := supervisor.running[key]
+	if !ok {
+		return
+	}
+	delete(supervisor.running, key)
+	running.cancel()
+	<-running.done
+	log.Info().Stringer("actor", key).Msg("Actor stopped")
+}
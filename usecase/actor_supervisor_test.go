@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanActors(t *testing.T) {
+	applied := lib.UserConfig{
+		Wallet: lib.WalletConfig{AddressKeyName: "node"},
+		Worker: []lib.WorkerConfig{
+			{TopicId: 1, LoopSeconds: 5, Parameters: map[string]string{"InferenceEndpoint": "http://source/1"}},
+			{TopicId: 2, LoopSeconds: 5},
+		},
+		Reputer: []lib.ReputerConfig{
+			{TopicId: 1, MinStake: 100},
+			{TopicId: 2, MinStake: 100},
+		},
+	}
+	workerKey := func(topicId uint64) actorKey { return actorKey{lib.ROLE_WORKER, topicId, "node"} }
+	reputerKey := func(topicId uint64) actorKey { return actorKey{lib.ROLE_REPUTER, topicId, "node"} }
+
+	tests := []struct {
+		name     string
+		edit     func(config *lib.UserConfig)
+		expected actorPlan
+		err      string
+	}{
+		{
+			name:     "unchanged",
+			edit:     func(config *lib.UserConfig) {},
+			expected: actorPlan{},
+		},
+		{
+			name: "added and removed topics",
+			edit: func(config *lib.UserConfig) {
+				config.Worker = append(config.Worker[:1], lib.WorkerConfig{TopicId: 3})
+				config.Reputer = config.Reputer[1:]
+			},
+			expected: actorPlan{start: []actorKey{workerKey(3)}, stop: []actorKey{workerKey(2), reputerKey(1)}},
+		},
+		{
+			name: "parameters and loop seconds updated in place",
+			edit: func(config *lib.UserConfig) {
+				config.Worker[0].Parameters = map[string]string{"InferenceEndpoint": "http://other-source/1"}
+				config.Reputer[1].LoopSeconds = 10
+			},
+			expected: actorPlan{update: []actorKey{workerKey(1), reputerKey(2)}},
+		},
+		{
+			name: "min stake restarts the reputer",
+			edit: func(config *lib.UserConfig) {
+				config.Reputer[0].MinStake = 200
+			},
+			expected: actorPlan{restart: []actorKey{reputerKey(1)}},
+		},
+		{
+			name: "new wallet of an actor starts a separate actor",
+			edit: func(config *lib.UserConfig) {
+				config.Worker[1].Wallet = &lib.WalletConfig{AddressKeyName: "challenger"}
+			},
+			expected: actorPlan{start: []actorKey{{lib.ROLE_WORKER, 2, "challenger"}}, stop: []actorKey{workerKey(2)}},
+		},
+		{
+			name: "node wallet changed",
+			edit: func(config *lib.UserConfig) {
+				config.Wallet.NodeRpc = "http://other-node:26657"
+			},
+			err: "wallet config changed",
+		},
+		{
+			name: "wallet of a running actor changed",
+			edit: func(config *lib.UserConfig) {
+				config.Worker[1].Wallet = &lib.WalletConfig{AddressKeyName: "node", Gas: "auto"}
+			},
+			err: "wallet of the worker of topic 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloaded := applied
+			reloaded.Worker = slices.Clone(applied.Worker)
+			reloaded.Reputer = slices.Clone(applied.Reputer)
+			tt.edit(&reloaded)
+
+			plan, err := planActors(applied, reloaded)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected.start, plan.start, "start")
+			assert.ElementsMatch(t, tt.expected.stop, plan.stop, "stop")
+			assert.ElementsMatch(t, tt.expected.update, plan.update, "update")
+			assert.ElementsMatch(t, tt.expected.restart, plan.restart, "restart")
+		})
+	}
+}
+
+func TestReloadRejectsInvalidConfig(t *testing.T) {
+	worker := func(topicId uint64, loopSeconds int64) lib.WorkerConfig {
+		return lib.WorkerConfig{
+			TopicId:                 topicId,
+			InferenceEntrypointName: "api-worker-reputer",
+			LoopSeconds:             loopSeconds,
+			Parameters:              map[string]string{"InferenceEndpoint": "http://source/{Token}", "Token": "ETH"},
+		}
+	}
+	config := lib.UserConfig{
+		Wallet: lib.WalletConfig{AddressKeyName: "node", NodeRpc: "http://localhost:26657"},
+		Worker: []lib.WorkerConfig{worker(1, 5)},
+	}
+	suite := &UseCaseSuite{
+		Node:   lib.NodeConfig{Wallet: config.Wallet},
+		Actors: NewActorSupervisor(config),
+	}
+
+	invalid := config
+	invalid.Worker = []lib.WorkerConfig{worker(1, 5), worker(1, 5)}
+	assert.ErrorContains(t, suite.Reload(invalid), "worker[1]: shares a topic and a wallet with worker[0]")
+
+	invalid = config
+	invalid.Wallet.TxErrorPolicies = map[string]lib.TxErrorPolicy{"emissions:67": "sometimes"}
+	assert.Error(t, suite.Reload(invalid))
+
+	invalid = config
+	invalid.Worker = []lib.WorkerConfig{worker(1, 0)}
+	assert.ErrorContains(t, suite.Reload(invalid), "worker[0].loopSeconds")
+	assert.Equal(t, config, suite.Actors.config, "the running config is kept")
+
+	valid := config
+	valid.Worker = []lib.WorkerConfig{worker(1, 10), worker(2, 5)}
+	require.NoError(t, suite.Reload(valid))
+	assert.Equal(t, valid, suite.Actors.config)
+}
+
+func TestLiveConfig(t *testing.T) {
+	config := newLiveConfig(lib.WorkerConfig{TopicId: 1, LoopSeconds: 5})
+	worker := config.Get()
+	config.Set(lib.WorkerConfig{TopicId: 1, LoopSeconds: 10})
+	assert.Equal(t, int64(5), worker.LoopSeconds, "a config read before an update is left untouched")
+	assert.Equal(t, int64(10), config.Get().LoopSeconds)
+}
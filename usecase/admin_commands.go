@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	cosmossdk_io_math "cosmossdk.io/math"
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+)
+
+// Worker or reputer of the config targeted by an admin command, with the node of the wallet it acts with
+type AdminTarget struct {
+	Role    lib.ActorRole
+	TopicId emissionstypes.TopicId
+	Node    *lib.NodeConfig
+	Worker  *lib.WorkerConfig  // set for a worker
+	Reputer *lib.ReputerConfig // set for a reputer
+}
+
+// Balance of an account of the node
+type AccountBalance struct {
+	KeyName string
+	Address string
+	Balance cosmossdk_io_math.Int
+}
+
+// Actors of the config with the role and in the topic, of any role if the role is empty and in any topic if it is 0.
+// Workers come first, in the order of the config.
+func (suite *UseCaseSuite) AdminTargets(role lib.ActorRole, topicId emissionstypes.TopicId) []AdminTarget {
+	var targets []AdminTarget
+	if role == "" || role == lib.ROLE_WORKER {
+		for i, worker := range suite.Node.Worker {
+			if topicId == 0 || worker.TopicId == topicId {
+				node := &suite.ForActor(worker.Wallet).Node
+				targets = append(targets, AdminTarget{Role: lib.ROLE_WORKER, TopicId: worker.TopicId, Node: node, Worker: &suite.Node.Worker[i]})
+			}
+		}
+	}
+	if role == "" || role == lib.ROLE_REPUTER {
+		for i, reputer := range suite.Node.Reputer {
+			if topicId == 0 || reputer.TopicId == topicId {
+				node := &suite.ForActor(reputer.Wallet).Node
+				targets = append(targets, AdminTarget{Role: lib.ROLE_REPUTER, TopicId: reputer.TopicId, Node: node, Reputer: &suite.Node.Reputer[i]})
+			}
+		}
+	}
+	return targets
+}
+
+// Runs the action for every target, returning the errors of all the failed ones
+func forEachTarget(targets []AdminTarget, action func(target AdminTarget) error) error {
+	var errs []error
+	for _, target := range targets {
+		if err := action(target); err != nil {
+			errs = append(errs, fmt.Errorf("%s of topic %d: %w", target.Role, target.TopicId, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Registers the targets in their topics, and stakes reputers up to their minStake, as the actors do on startup
+func (suite *UseCaseSuite) RegisterTargets(ctx context.Context, targets []AdminTarget) error {
+	return forEachTarget(targets, func(target AdminTarget) error {
+		var registered bool
+		if target.Worker != nil {
+			registered = target.Node.RegisterWorkerIdempotently(ctx, *target.Worker)
+		} else {
+			registered = target.Node.RegisterAndStakeReputerIdempotently(ctx, *target.Reputer)
+		}
+		if !registered {
+			return errors.New("registration failed, see the logs")
+		}
+		return nil
+	})
+}
+
+// Removes the registrations of the targets in their topics
+func (suite *UseCaseSuite) UnregisterTargets(ctx context.Context, targets []AdminTarget) error {
+	return forEachTarget(targets, func(target AdminTarget) error {
+		_, err := target.Node.RemoveRegistration(ctx, target.TopicId, target.Role)
+		return err
+	})
+}
+
+// Adds the amount to the stake of every reputer of the targets
+func (suite *UseCaseSuite) AddStakeToTargets(ctx context.Context, targets []AdminTarget, amount cosmossdk_io_math.Int) error {
+	return forEachTarget(targets, func(target AdminTarget) error {
+		if target.Role != lib.ROLE_REPUTER {
+			return errors.New("only reputers stake")
+		}
+		_, err := target.Node.AddReputerStake(ctx, target.TopicId, amount)
+		return err
+	})
+}
+
+// Starts the removal of the amount from the stake of every reputer of the targets
+func (suite *UseCaseSuite) RemoveStakeFromTargets(ctx context.Context, targets []AdminTarget, amount cosmossdk_io_math.Int) error {
+	return forEachTarget(targets, func(target AdminTarget) error {
+		if target.Role != lib.ROLE_REPUTER {
+			return errors.New("only reputers stake")
+		}
+		_, err := target.Node.RemoveReputerStake(ctx, target.TopicId, amount)
+		return err
+	})
+}
+
+// Queries the status of every target, returning the statuses which could be queried along with the errors of the others
+func (suite *UseCaseSuite) TargetStatuses(ctx context.Context, targets []AdminTarget) ([]lib.ActorStatus, error) {
+	statuses := make([]lib.ActorStatus, 0, len(targets))
+	err := forEachTarget(targets, func(target AdminTarget) error {
+		status, err := target.Node.GetActorStatus(ctx, target.Role, target.TopicId)
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, status)
+		return nil
+	})
+	return statuses, err
+}
+
+// Balances of the node's account and of the accounts of the actors with their own wallet, by key name
+func (suite *UseCaseSuite) Balances(ctx context.Context) ([]AccountBalance, error) {
+	keyNames := make([]string, 0, len(suite.ActorNodes))
+	for keyName := range suite.ActorNodes {
+		keyNames = append(keyNames, keyName)
+	}
+	sort.Strings(keyNames)
+	nodes := []*lib.NodeConfig{&suite.Node}
+	for _, keyName := range keyNames {
+		nodes = append(nodes, suite.ActorNodes[keyName])
+	}
+
+	balances := make([]AccountBalance, 0, len(nodes))
+	for _, node := range nodes {
+		balance, err := node.GetBalance(ctx)
+		if err != nil {
+			return balances, fmt.Errorf("cannot get balance of %s: %w", node.Chain.Address, err)
+		}
+		balances = append(balances, AccountBalance{KeyName: node.Wallet.AddressKeyName, Address: node.Chain.Address, Balance: balance})
+	}
+	return balances, nil
+}
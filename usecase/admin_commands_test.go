@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"testing"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminTargets(t *testing.T) {
+	challengerWallet := &lib.WalletConfig{AddressKeyName: "challenger"}
+	suite := &UseCaseSuite{
+		Node: lib.NodeConfig{
+			Wallet: lib.WalletConfig{Address: "allo1node", AddressKeyName: "node"},
+			Chain:  lib.ChainConfig{Address: "allo1node"},
+			Worker: []lib.WorkerConfig{{TopicId: 1}, {TopicId: 2}},
+			Reputer: []lib.ReputerConfig{
+				{TopicId: 1},
+				{TopicId: 1, Wallet: challengerWallet},
+			},
+		},
+		ActorNodes: map[string]*lib.NodeConfig{
+			"challenger": {
+				Wallet: lib.WalletConfig{Address: "allo1challenger", AddressKeyName: "challenger"},
+				Chain:  lib.ChainConfig{Address: "allo1challenger"},
+			},
+		},
+	}
+
+	type target struct {
+		role    lib.ActorRole
+		topicId emissionstypes.TopicId
+		address string
+	}
+	tests := []struct {
+		name     string
+		role     lib.ActorRole
+		topicId  emissionstypes.TopicId
+		expected []target
+	}{
+		{name: "all", expected: []target{
+			{lib.ROLE_WORKER, 1, "allo1node"}, {lib.ROLE_WORKER, 2, "allo1node"},
+			{lib.ROLE_REPUTER, 1, "allo1node"}, {lib.ROLE_REPUTER, 1, "allo1challenger"},
+		}},
+		{name: "topic", topicId: 1, expected: []target{
+			{lib.ROLE_WORKER, 1, "allo1node"}, {lib.ROLE_REPUTER, 1, "allo1node"}, {lib.ROLE_REPUTER, 1, "allo1challenger"},
+		}},
+		{name: "role", role: lib.ROLE_WORKER, expected: []target{{lib.ROLE_WORKER, 1, "allo1node"}, {lib.ROLE_WORKER, 2, "allo1node"}}},
+		{name: "role and topic", role: lib.ROLE_REPUTER, topicId: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []target
+			for _, adminTarget := range suite.AdminTargets(tt.role, tt.topicId) {
+				got = append(got, target{adminTarget.Role, adminTarget.TopicId, adminTarget.Node.Chain.Address})
+				assert.Equal(t, adminTarget.Role == lib.ROLE_WORKER, adminTarget.Worker != nil)
+				assert.Equal(t, adminTarget.Role == lib.ROLE_REPUTER, adminTarget.Reputer != nil)
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
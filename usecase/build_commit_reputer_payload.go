@@ -7,51 +7,61 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	alloraMath "github.com/allora-network/allora-chain/math"
 	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
-	"github.com/cosmos/cosmos-sdk/types/tx/signing"
 	"github.com/rs/zerolog/log"
 )
 
 // Get the reputer's values at the block from the chain
 // Compute loss bundle with the reputer provided Loss function and ground truth
 // sign and commit to chain
-func (suite *UseCaseSuite) BuildCommitReputerPayload(reputer lib.ReputerConfig, nonce lib.BlockHeight) (bool, error) {
-	ctx := context.Background()
+func (suite *UseCaseSuite) BuildCommitReputerPayload(ctx context.Context, reputer lib.ReputerConfig, nonce lib.BlockHeight) (bool, error) {
+	payload, err := suite.BuildSignedReputerPayload(ctx, reputer, nonce)
+	if err != nil {
+		return false, err
+	}
+	return suite.CommitSignedPayload(ctx, payload)
+}
 
-	valueBundle, err := suite.Node.GetReputerValuesAtBlock(reputer.TopicId, nonce)
+// Computes the losses of the network's values at the nonce against the ground truth with the reputer's adapters,
+// and signs them into the msg to submit
+func (suite *UseCaseSuite) BuildSignedReputerPayload(ctx context.Context, reputer lib.ReputerConfig, nonce lib.BlockHeight) (*SignedPayload, error) {
+	valueBundle, err := suite.Node.GetReputerValuesAtBlock(ctx, reputer.TopicId, nonce)
 	if err != nil {
 		log.Error().Err(err).Uint64("topicId", reputer.TopicId).Msg("Failed to get reputer values at block")
-		return false, err
+		return nil, err
 	}
 	valueBundle.ReputerRequestNonce = &emissionstypes.ReputerRequestNonce{
 		ReputerNonce: &emissionstypes.Nonce{BlockHeight: nonce},
 	}
 	valueBundle.Reputer = suite.Node.Wallet.Address
 
-	sourceTruth, err := reputer.GroundTruthEntrypoint.GroundTruth(reputer, nonce)
+	sourceTruth, err := suite.GetGroundTruth(ctx, reputer, nonce)
 	if err != nil {
 		log.Error().Err(err).Uint64("topicId", reputer.TopicId).Msg("Failed to get source truth from reputer")
-		return false, err
+		return nil, err
 	}
-	suite.Metrics.IncrementMetricsCounter(lib.TruthRequestCount, suite.Node.Chain.Address, reputer.TopicId)
 
-	lossBundle, err := suite.ComputeLossBundle(sourceTruth, valueBundle, reputer)
+	lossCtx, cancel := suite.withLossDeadline(ctx, reputer.TopicId, nonce)
+	defer cancel()
+	lossBundle, err := suite.ComputeLossBundle(lossCtx, sourceTruth, valueBundle, reputer)
 	if err != nil {
 		log.Error().Err(err).Uint64("topicId", reputer.TopicId).Msg("Failed to compute loss bundle")
-		return false, err
+		return nil, err
 	}
 	suite.Metrics.IncrementMetricsCounter(lib.ReputerDataBuildCount, suite.Node.Chain.Address, reputer.TopicId)
 
-	signedValueBundle, err := suite.SignReputerValueBundle(&lossBundle)
+	signedValueBundle, err := suite.SignReputerValueBundle(ctx, &lossBundle)
 	if err != nil {
 		log.Error().Err(err).Uint64("topicId", reputer.TopicId).Msg("Failed to sign reputer value bundle")
-		return false, err
+		return nil, err
 	}
 
 	if err := signedValueBundle.Validate(); err != nil {
-		return false, err
+		return nil, err
 	}
 
 	req := &emissionstypes.InsertReputerPayloadRequest{
@@ -64,21 +74,49 @@ func (suite *UseCaseSuite) BuildCommitReputerPayload(reputer lib.ReputerConfig,
 	} else {
 		log.Debug().Uint64("topicId", reputer.TopicId).Msgf("Sending InsertReputerPayload to chain %s", string(reqJSON))
 	}
-	if suite.Node.Wallet.SubmitTx {
-		_, err = suite.Node.SendDataWithRetry(ctx, req, "Send Reputer Data to chain")
-		if err != nil {
-			log.Error().Err(err).Uint64("topicId", reputer.TopicId).Msgf("Error sending Reputer Data to chain: %s", err)
-			return false, err
-		}
-		suite.Metrics.IncrementMetricsCounter(lib.ReputerChainSubmissionCount, suite.Node.Chain.Address, reputer.TopicId)
+
+	return &SignedPayload{
+		Role:        lib.ROLE_REPUTER,
+		TopicId:     reputer.TopicId,
+		Nonce:       nonce,
+		Bundle:      signedValueBundle.ValueBundle,
+		Signature:   signedValueBundle.Signature,
+		Pubkey:      signedValueBundle.Pubkey,
+		Request:     req,
+		RequestJSON: reqJSON,
+	}, nil
+}
+
+// Ground truth of the reputer at the nonce, reused from the cache if a reputer already fetched it from the same source
+func (suite *UseCaseSuite) GetGroundTruth(ctx context.Context, reputer lib.ReputerConfig, nonce lib.BlockHeight) (lib.Truth, error) {
+	source := lib.GroundTruthSource(reputer, nonce)
+	truth, fetched, err := suite.GroundTruths.GetOrFetch(ctx, source, nonce, reputer.TopicId, func(ctx context.Context) (lib.Truth, error) {
+		return reputer.GroundTruthEntrypoint.GroundTruth(ctx, reputer, nonce)
+	})
+	if err != nil {
+		return "", err
+	}
+	if fetched {
+		suite.Metrics.IncrementMetricsCounter(lib.TruthRequestCount, suite.Node.Chain.Address, reputer.TopicId)
 	} else {
-		log.Info().Uint64("topicId", reputer.TopicId).Msg("SubmitTx=false; Skipping sending Reputer Data to chain")
+		log.Debug().Uint64("topicId", reputer.TopicId).Int64("BlockHeight", nonce).Msg("Reusing cached ground truth")
 	}
+	return truth, nil
+}
 
-	return true, nil
+// Bounds the computation of the losses of the nonce by the deadline of its reputer window. Unbounded if the window
+// cannot be scheduled or isn't open, e.g. when running a past nonce once.
+func (suite *UseCaseSuite) withLossDeadline(ctx context.Context, topicId emissionstypes.TopicId, nonce lib.BlockHeight) (context.Context, context.CancelFunc) {
+	schedule, err := suite.Scheduler.GetTopicSchedule(ctx, topicId)
+	if err != nil || !schedule.IsReputerWindowOpen(nonce) {
+		return context.WithCancel(ctx)
+	}
+	deadline := schedule.ReputerLossDeadline(nonce)
+	log.Debug().Uint64("topicId", topicId).Int64("BlockHeight", nonce).Time("deadline", deadline).Msg("Loss computation deadline")
+	return context.WithDeadline(ctx, deadline)
 }
 
-func (suite *UseCaseSuite) ComputeLossBundle(sourceTruth string, vb *emissionstypes.ValueBundle, reputer lib.ReputerConfig) (emissionstypes.ValueBundle, error) {
+func (suite *UseCaseSuite) ComputeLossBundle(ctx context.Context, sourceTruth string, vb *emissionstypes.ValueBundle, reputer lib.ReputerConfig) (emissionstypes.ValueBundle, error) {
 	if vb == nil {
 		return emissionstypes.ValueBundle{}, errors.New("nil ValueBundle")
 	}
@@ -100,7 +138,7 @@ func (suite *UseCaseSuite) ComputeLossBundle(sourceTruth string, vb *emissionsty
 		is_never_negative = *reputer.LossFunctionParameters.IsNeverNegative
 	} else {
 		var err error
-		is_never_negative, err = reputer.LossFunctionEntrypoint.IsLossFunctionNeverNegative(reputer, lossMethodOptions)
+		is_never_negative, err = reputer.LossFunctionEntrypoint.IsLossFunctionNeverNegative(ctx, reputer, lossMethodOptions)
 		if err != nil {
 			log.Error().Err(err).Uint64("topicId", reputer.TopicId).Msg("Failed to determine if loss function is never negative")
 			return emissionstypes.ValueBundle{}, err
@@ -116,105 +154,179 @@ func (suite *UseCaseSuite) ComputeLossBundle(sourceTruth string, vb *emissionsty
 		ExtraData:           vb.ExtraData,
 	}
 
-	computeLoss := func(value alloraMath.Dec, description string) (alloraMath.Dec, error) {
-		lossStr, err := reputer.LossFunctionEntrypoint.LossFunction(reputer, sourceTruth, value.String(), lossMethodOptions)
-		if err != nil {
-			return alloraMath.Dec{}, fmt.Errorf("error computing loss for %s: %w", description, err)
+	// Every value of the bundle, along with where its loss goes in the loss bundle
+	values := []lossValue{
+		{"combined value", vb.CombinedValue, func(loss alloraMath.Dec) { losses.CombinedValue = loss }},
+		{"naive value", vb.NaiveValue, func(loss alloraMath.Dec) { losses.NaiveValue = loss }},
+	}
+	losses.InfererValues = make([]*emissionstypes.WorkerAttributedValue, len(vb.InfererValues))
+	for i, val := range vb.InfererValues {
+		values = append(values, lossValue{fmt.Sprintf("inferer value %d", i), val.Value, func(loss alloraMath.Dec) {
+			losses.InfererValues[i] = &emissionstypes.WorkerAttributedValue{Worker: val.Worker, Value: loss}
+		}})
+	}
+	losses.ForecasterValues = make([]*emissionstypes.WorkerAttributedValue, len(vb.ForecasterValues))
+	for i, val := range vb.ForecasterValues {
+		values = append(values, lossValue{fmt.Sprintf("forecaster value %d", i), val.Value, func(loss alloraMath.Dec) {
+			losses.ForecasterValues[i] = &emissionstypes.WorkerAttributedValue{Worker: val.Worker, Value: loss}
+		}})
+	}
+	losses.OneOutInfererValues = make([]*emissionstypes.WithheldWorkerAttributedValue, len(vb.OneOutInfererValues))
+	for i, val := range vb.OneOutInfererValues {
+		values = append(values, lossValue{fmt.Sprintf("one out inferer value %d", i), val.Value, func(loss alloraMath.Dec) {
+			losses.OneOutInfererValues[i] = &emissionstypes.WithheldWorkerAttributedValue{Worker: val.Worker, Value: loss}
+		}})
+	}
+	losses.OneOutForecasterValues = make([]*emissionstypes.WithheldWorkerAttributedValue, len(vb.OneOutForecasterValues))
+	for i, val := range vb.OneOutForecasterValues {
+		values = append(values, lossValue{fmt.Sprintf("one out forecaster value %d", i), val.Value, func(loss alloraMath.Dec) {
+			losses.OneOutForecasterValues[i] = &emissionstypes.WithheldWorkerAttributedValue{Worker: val.Worker, Value: loss}
+		}})
+	}
+	losses.OneInForecasterValues = make([]*emissionstypes.WorkerAttributedValue, len(vb.OneInForecasterValues))
+	for i, val := range vb.OneInForecasterValues {
+		values = append(values, lossValue{fmt.Sprintf("one in forecaster value %d", i), val.Value, func(loss alloraMath.Dec) {
+			losses.OneInForecasterValues[i] = &emissionstypes.WorkerAttributedValue{Worker: val.Worker, Value: loss}
+		}})
+	}
+
+	losses.OneOutInfererForecasterValues = make([]*emissionstypes.OneOutInfererForecasterValues, len(vb.OneOutInfererForecasterValues))
+	for i, forecasterValues := range vb.OneOutInfererForecasterValues {
+		lossValues := &emissionstypes.OneOutInfererForecasterValues{
+			Forecaster:          forecasterValues.Forecaster,
+			OneOutInfererValues: make([]*emissionstypes.WithheldWorkerAttributedValue, len(forecasterValues.OneOutInfererValues)),
+		}
+		losses.OneOutInfererForecasterValues[i] = lossValues
+		for j, val := range forecasterValues.OneOutInfererValues {
+			values = append(values, lossValue{fmt.Sprintf("one out inferer value %d of forecaster %d", j, i), val.Value, func(loss alloraMath.Dec) {
+				lossValues.OneOutInfererValues[j] = &emissionstypes.WithheldWorkerAttributedValue{Worker: val.Worker, Value: loss}
+			}})
 		}
+	}
 
-		loss, err := alloraMath.NewDecFromString(lossStr)
+	lossStrs, err := computeLosses(ctx, reputer, sourceTruth, values)
+	if err != nil {
+		log.Error().Err(err).Uint64("topicId", reputer.TopicId).Msg("Error computing losses")
+		return emissionstypes.ValueBundle{}, err
+	}
+	for i, value := range values {
+		loss, err := alloraMath.NewDecFromString(lossStrs[i])
 		if err != nil {
-			return alloraMath.Dec{}, fmt.Errorf("error parsing loss value for %s: %w", description, err)
+			return emissionstypes.ValueBundle{}, fmt.Errorf("error parsing loss value for %s: %w", value.description, err)
 		}
 
 		if is_never_negative {
+			if loss.IsZero() {
+				loss = minNeverNegativeLoss
+			}
 			loss, err = alloraMath.Log10(loss)
 			if err != nil {
-				return alloraMath.Dec{}, fmt.Errorf("error Log10 for %s: %w", description, err)
+				return emissionstypes.ValueBundle{}, fmt.Errorf("error Log10 for %s: %w", value.description, err)
 			}
 		}
 
 		if err := emissionstypes.ValidateDec(loss); err != nil {
-			return alloraMath.Dec{}, fmt.Errorf("invalid loss value for %s: %w", description, err)
+			return emissionstypes.ValueBundle{}, fmt.Errorf("invalid loss value for %s: %w", value.description, err)
 		}
-
-		return loss, nil
+		value.setLoss(loss)
 	}
+	return losses, nil
+}
 
-	// Combined Value
-	if combinedLoss, err := computeLoss(vb.CombinedValue, "combined value"); err != nil {
-		log.Error().Err(err).Msg("Error computing loss for combined value")
-		return emissionstypes.ValueBundle{}, err
-	} else {
-		losses.CombinedValue = combinedLoss
-	}
+// Stands for a never negative loss of 0, whose log10 is undefined
+var minNeverNegativeLoss = alloraMath.MustNewDecFromString(lib.MIN_NEVER_NEGATIVE_LOSS)
 
-	// Naive Value
-	if naiveLoss, err := computeLoss(vb.NaiveValue, "naive value"); err != nil {
-		log.Error().Err(err).Msg("Error computing loss for naive value")
-		return emissionstypes.ValueBundle{}, err
-	} else {
-		losses.NaiveValue = naiveLoss
-	}
+// Cancels the losses left to compute once one of them failed
+var errLossFailed = errors.New("another loss failed")
 
-	// Inferer Values
-	losses.InfererValues = make([]*emissionstypes.WorkerAttributedValue, len(vb.InfererValues))
-	for i, val := range vb.InfererValues {
-		if loss, err := computeLoss(val.Value, fmt.Sprintf("inferer value %d", i)); err != nil {
-			log.Error().Err(err).Msg("Error computing loss for inferer value")
-			return emissionstypes.ValueBundle{}, err
-		} else {
-			losses.InfererValues[i] = &emissionstypes.WorkerAttributedValue{Worker: val.Worker, Value: loss}
-		}
+// Value of a bundle to compute the loss of
+type lossValue struct {
+	description string
+	value       alloraMath.Dec
+	setLoss     func(loss alloraMath.Dec) // sets the loss of the value in the loss bundle
+}
+
+// Computes the losses of the values against the source truth with the loss function adapter of the reputer,
+// in a single batch if the adapter supports it or else concurrently, in the order of the values
+func computeLosses(ctx context.Context, reputer lib.ReputerConfig, sourceTruth string, values []lossValue) ([]string, error) {
+	options := reputer.LossFunctionParameters.LossMethodOptions
+	valueStrs := make([]string, len(values))
+	for i, value := range values {
+		valueStrs[i] = value.value.String()
 	}
 
-	// Forecaster Values
-	losses.ForecasterValues = make([]*emissionstypes.WorkerAttributedValue, len(vb.ForecasterValues))
-	for i, val := range vb.ForecasterValues {
-		if loss, err := computeLoss(val.Value, fmt.Sprintf("forecaster value %d", i)); err != nil {
-			log.Error().Err(err).Msg("Error computing loss for forecaster value")
-			return emissionstypes.ValueBundle{}, err
-		} else {
-			losses.ForecasterValues[i] = &emissionstypes.WorkerAttributedValue{Worker: val.Worker, Value: loss}
+	if batchAdapter, ok := reputer.LossFunctionEntrypoint.(lib.BatchLossFunctionAdapter); ok {
+		lossStrs, err := batchAdapter.BatchLossFunction(ctx, reputer, sourceTruth, valueStrs, options)
+		switch {
+		case errors.Is(err, lib.ErrBatchLossFunctionUnsupported):
+			log.Debug().Uint64("topicId", reputer.TopicId).Msg("Batch loss function not supported, computing losses one by one")
+		case err != nil:
+			return nil, fmt.Errorf("error computing losses in batch: %w", err)
+		case len(lossStrs) != len(values):
+			return nil, fmt.Errorf("error computing losses in batch: got %d losses for %d values", len(lossStrs), len(values))
+		default:
+			return lossStrs, nil
 		}
 	}
 
-	// One Out Inferer Values
-	losses.OneOutInfererValues = make([]*emissionstypes.WithheldWorkerAttributedValue, len(vb.OneOutInfererValues))
-	for i, val := range vb.OneOutInfererValues {
-		if loss, err := computeLoss(val.Value, fmt.Sprintf("one out inferer value %d", i)); err != nil {
-			log.Error().Err(err).Msg("Error computing loss for one out inferer value")
-			return emissionstypes.ValueBundle{}, err
-		} else {
-			losses.OneOutInfererValues[i] = &emissionstypes.WithheldWorkerAttributedValue{Worker: val.Worker, Value: loss}
+	// One loss per value, at most Concurrency at once. Once a loss fails, the values not started yet are skipped.
+	lossCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	lossStrs := make([]string, len(values))
+	errs := make([]error, len(values))
+	slots := make(chan struct{}, reputer.LossFunctionParameters.Concurrency())
+	var wg sync.WaitGroup
+	for i := range values {
+		select {
+		case slots <- struct{}{}:
+		case <-lossCtx.Done():
+		}
+		if lossCtx.Err() != nil {
+			break
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			lossStr, err := reputer.LossFunctionEntrypoint.LossFunction(lossCtx, reputer, sourceTruth, valueStrs[i], options)
+			if err != nil {
+				errs[i] = err
+				cancel(errLossFailed)
+				return
+			}
+			lossStrs[i] = lossStr
+		}()
 	}
+	wg.Wait()
 
-	// One Out Forecaster Values
-	losses.OneOutForecasterValues = make([]*emissionstypes.WithheldWorkerAttributedValue, len(vb.OneOutForecasterValues))
-	for i, val := range vb.OneOutForecasterValues {
-		if loss, err := computeLoss(val.Value, fmt.Sprintf("one out forecaster value %d", i)); err != nil {
-			log.Error().Err(err).Msg("Error computing loss for one out forecaster value")
-			return emissionstypes.ValueBundle{}, err
-		} else {
-			losses.OneOutForecasterValues[i] = &emissionstypes.WithheldWorkerAttributedValue{Worker: val.Worker, Value: loss}
+	if err := ctx.Err(); err != nil {
+		if deadline, ok := ctx.Deadline(); ok && errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("losses not computed by the deadline %s: %w", deadline.Format(time.RFC3339), err)
 		}
+		return nil, err
 	}
-
-	// One In Forecaster Values
-	losses.OneInForecasterValues = make([]*emissionstypes.WorkerAttributedValue, len(vb.OneInForecasterValues))
-	for i, val := range vb.OneInForecasterValues {
-		if loss, err := computeLoss(val.Value, fmt.Sprintf("one in forecaster value %d", i)); err != nil {
-			log.Error().Err(err).Msg("Error computing loss for one in forecaster value")
-			return emissionstypes.ValueBundle{}, err
-		} else {
-			losses.OneInForecasterValues[i] = &emissionstypes.WorkerAttributedValue{Worker: val.Worker, Value: loss}
+	// The error reported is the one of the first value in the order of the bundle which failed on its own,
+	// rather than as another one failed first
+	failed := -1
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if failed == -1 {
+			failed = i
+		}
+		if !errors.Is(err, context.Canceled) || context.Cause(lossCtx) != errLossFailed {
+			failed = i
+			break
 		}
 	}
-	return losses, nil
+	if failed != -1 {
+		return nil, fmt.Errorf("error computing loss for %s: %w", values[failed].description, errs[failed])
+	}
+	return lossStrs, nil
 }
 
-func (suite *UseCaseSuite) SignReputerValueBundle(valueBundle *emissionstypes.ValueBundle) (*emissionstypes.ReputerValueBundle, error) {
+func (suite *UseCaseSuite) SignReputerValueBundle(ctx context.Context, valueBundle *emissionstypes.ValueBundle) (*emissionstypes.ReputerValueBundle, error) {
 	// Marshall and sign the bundle
 	protoBytesIn := make([]byte, 0) // Create a byte slice with initial length 0 and capacity greater than 0
 	protoBytesIn, err := valueBundle.XXX_Marshal(protoBytesIn, true)
@@ -222,12 +334,13 @@ func (suite *UseCaseSuite) SignReputerValueBundle(valueBundle *emissionstypes.Va
 		log.Error().Err(err).Msg("Error Marshalling valueBundle")
 		return &emissionstypes.ReputerValueBundle{}, err
 	}
-	sig, pk, err := suite.Node.Chain.Client.Context().Keyring.Sign(suite.Node.Chain.Account.Name, protoBytesIn, signing.SignMode_SIGN_MODE_DIRECT)
-	pkStr := hex.EncodeToString(pk.Bytes())
+	// The signature is verified before the bundle is submitted
+	sig, err := lib.SignAndVerify(ctx, suite.Node.Chain.Signer, protoBytesIn)
 	if err != nil {
 		log.Error().Err(err).Msg("Error signing valueBundle")
 		return &emissionstypes.ReputerValueBundle{}, err
 	}
+	pkStr := hex.EncodeToString(suite.Node.Chain.Signer.PubKey().Bytes())
 
 	reputerValueBundle := &emissionstypes.ReputerValueBundle{
 		ValueBundle: valueBundle,
@@ -1,14 +1,21 @@
 package usecase
 
 import (
+	native_loss "allora_offchain_node/adapter/native/loss"
 	"allora_offchain_node/lib"
+	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	alloraMath "github.com/allora-network/allora-chain/math"
 	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestComputeLossBundle(t *testing.T) {
@@ -29,6 +36,7 @@ func TestComputeLossBundle(t *testing.T) {
 		reputerConfig       lib.ReputerConfig
 		expectedLossStrings map[string]string
 		mockSetup           func(*MockAlloraAdapter)
+		lossAdapter         lib.AlloraAdapter // computes the losses instead of the mock if set
 		expectError         bool
 		errorContains       string
 	}{
@@ -99,6 +107,29 @@ func TestComputeLossBundle(t *testing.T) {
 			expectError:   true,
 			errorContains: "error parsing loss",
 		},
+		{
+			name:        "Native losses of values equal to the ground truth",
+			sourceTruth: "10.0",
+			valueBundle: &emissionstypes.ValueBundle{
+				CombinedValue:    alloraMath.MustNewDecFromString("10"),
+				NaiveValue:       alloraMath.MustNewDecFromString("10"),
+				InfererValues:    []*emissionstypes.WorkerAttributedValue{{Value: alloraMath.MustNewDecFromString("10")}},
+				ForecasterValues: []*emissionstypes.WorkerAttributedValue{{Value: alloraMath.MustNewDecFromString("10")}},
+			},
+			reputerConfig: lib.ReputerConfig{
+				LossFunctionParameters: lib.LossFunctionParameters{
+					LossMethodOptions: map[string]string{"loss_method": "sqe"},
+				},
+			},
+			expectedLossStrings: map[string]string{
+				"CombinedValue":    "-18.00000000000000000000000000000000",
+				"NaiveValue":       "-18.00000000000000000000000000000000",
+				"InfererValues":    "-18.00000000000000000000000000000000",
+				"ForecasterValues": "-18.00000000000000000000000000000000",
+			},
+			mockSetup:   func(m *MockAlloraAdapter) {},
+			lossAdapter: native_loss.NewAlloraAdapter(),
+		},
 		{
 			name:          "Nil ValueBundle",
 			sourceTruth:   "10.0",
@@ -125,9 +156,12 @@ func TestComputeLossBundle(t *testing.T) {
 			tt.mockSetup(mockAdapter)
 			tt.reputerConfig.GroundTruthEntrypoint = mockAdapter
 			tt.reputerConfig.LossFunctionEntrypoint = mockAdapter
+			if tt.lossAdapter != nil {
+				tt.reputerConfig.LossFunctionEntrypoint = tt.lossAdapter
+			}
 
 			suite := &UseCaseSuite{}
-			result, err := suite.ComputeLossBundle(tt.sourceTruth, tt.valueBundle, tt.reputerConfig)
+			result, err := suite.ComputeLossBundle(context.Background(), tt.sourceTruth, tt.valueBundle, tt.reputerConfig)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -148,3 +182,285 @@ func TestComputeLossBundle(t *testing.T) {
 		})
 	}
 }
+
+func TestComputeLossBundleInBatch(t *testing.T) {
+	reputerOptions := map[string]string{"loss_method": "sqe"}
+	reputerConfig := lib.ReputerConfig{
+		LossFunctionParameters: lib.LossFunctionParameters{
+			LossMethodOptions: reputerOptions,
+			IsNeverNegative:   &[]bool{false}[0],
+		},
+	}
+	valueBundle := &emissionstypes.ValueBundle{
+		CombinedValue:         alloraMath.MustNewDecFromString("9.5"),
+		NaiveValue:            alloraMath.MustNewDecFromString("9"),
+		InfererValues:         []*emissionstypes.WorkerAttributedValue{{Worker: "inferer", Value: alloraMath.MustNewDecFromString("9.7")}},
+		OneInForecasterValues: []*emissionstypes.WorkerAttributedValue{{Worker: "forecaster", Value: alloraMath.MustNewDecFromString("9.8")}},
+	}
+	values := []string{"9.5", "9", "9.7", "9.8"}
+
+	tests := []struct {
+		name          string
+		mockSetup     func(*MockBatchAlloraAdapter)
+		errorContains string
+	}{
+		{
+			name: "losses computed in a single batch",
+			mockSetup: func(m *MockBatchAlloraAdapter) {
+				m.On("BatchLossFunction", mock.Anything, "10.0", values, reputerOptions).Return([]string{"0.25", "1", "0.09", "0.04"}, nil).Once()
+			},
+		},
+		{
+			name: "fallback on one loss per value if the batch is unsupported",
+			mockSetup: func(m *MockBatchAlloraAdapter) {
+				m.On("BatchLossFunction", mock.Anything, "10.0", values, reputerOptions).Return(nil, lib.ErrBatchLossFunctionUnsupported).Once()
+				m.On("LossFunction", mock.Anything, "10.0", "9.5", reputerOptions).Return("0.25", nil).Once()
+				m.On("LossFunction", mock.Anything, "10.0", "9", reputerOptions).Return("1", nil).Once()
+				m.On("LossFunction", mock.Anything, "10.0", "9.7", reputerOptions).Return("0.09", nil).Once()
+				m.On("LossFunction", mock.Anything, "10.0", "9.8", reputerOptions).Return("0.04", nil).Once()
+			},
+		},
+		{
+			name: "batch error",
+			mockSetup: func(m *MockBatchAlloraAdapter) {
+				m.On("BatchLossFunction", mock.Anything, "10.0", values, reputerOptions).Return(nil, errors.New("service down")).Once()
+			},
+			errorContains: "error computing losses in batch: service down",
+		},
+		{
+			name: "batch missing losses",
+			mockSetup: func(m *MockBatchAlloraAdapter) {
+				m.On("BatchLossFunction", mock.Anything, "10.0", values, reputerOptions).Return([]string{"0.25"}, nil).Once()
+			},
+			errorContains: "got 1 losses for 4 values",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAdapter := &MockBatchAlloraAdapter{}
+			tt.mockSetup(mockAdapter)
+			reputer := reputerConfig
+			reputer.LossFunctionEntrypoint = mockAdapter
+
+			suite := &UseCaseSuite{}
+			result, err := suite.ComputeLossBundle(context.Background(), "10.0", valueBundle, reputer)
+			mockAdapter.AssertExpectations(t)
+			if tt.errorContains != "" {
+				assert.ErrorContains(t, err, tt.errorContains)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "0.25", result.CombinedValue.String())
+			assert.Equal(t, "1", result.NaiveValue.String())
+			assert.Equal(t, &emissionstypes.WorkerAttributedValue{Worker: "inferer", Value: alloraMath.MustNewDecFromString("0.09")}, result.InfererValues[0])
+			assert.Equal(t, &emissionstypes.WorkerAttributedValue{Worker: "forecaster", Value: alloraMath.MustNewDecFromString("0.04")}, result.OneInForecasterValues[0])
+		})
+	}
+}
+
+// Loss function adapter returning each value as its loss, after the delay of the value, or failing with the error of the value
+type delayedLossAdapter struct {
+	MockAlloraAdapter
+	delays      map[string]time.Duration
+	errs        map[string]error
+	ignoreCtx   bool // if set, values wait for their delay even once the context is done
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (a *delayedLossAdapter) LossFunction(ctx context.Context, node lib.ReputerConfig, sourceTruth string, value string, options map[string]string) (string, error) {
+	inFlight := a.inFlight.Add(1)
+	defer a.inFlight.Add(-1)
+	for {
+		maxInFlight := a.maxInFlight.Load()
+		if inFlight <= maxInFlight || a.maxInFlight.CompareAndSwap(maxInFlight, inFlight) {
+			break
+		}
+	}
+	if a.ignoreCtx {
+		time.Sleep(a.delays[value])
+	} else {
+		select {
+		case <-time.After(a.delays[value]):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	if err := a.errs[value]; err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func TestComputeLossBundleConcurrently(t *testing.T) {
+	valueBundle := &emissionstypes.ValueBundle{
+		CombinedValue: alloraMath.MustNewDecFromString("1"),
+		NaiveValue:    alloraMath.MustNewDecFromString("2"),
+	}
+	for i := 3; i <= 12; i++ {
+		valueBundle.InfererValues = append(valueBundle.InfererValues, &emissionstypes.WorkerAttributedValue{Worker: fmt.Sprint("inferer", i), Value: alloraMath.NewDecFromInt64(int64(i))})
+	}
+	reputer := lib.ReputerConfig{
+		LossFunctionParameters: lib.LossFunctionParameters{MaxConcurrency: 3, IsNeverNegative: &[]bool{false}[0]},
+	}
+
+	tests := []struct {
+		name          string
+		adapter       *delayedLossAdapter
+		ctxTimeout    time.Duration
+		errorContains string
+	}{
+		{
+			name: "losses in the order of the bundle, whichever is computed first",
+			adapter: &delayedLossAdapter{delays: map[string]time.Duration{
+				"1": 30 * time.Millisecond, "3": 20 * time.Millisecond, "4": 10 * time.Millisecond,
+			}},
+		},
+		{
+			name: "first failure in the order of the bundle, even if it fails last",
+			adapter: &delayedLossAdapter{
+				delays:    map[string]time.Duration{"3": 30 * time.Millisecond},
+				errs:      map[string]error{"3": errors.New("slow failure"), "4": errors.New("fast failure")},
+				ignoreCtx: true,
+			},
+			errorContains: "error computing loss for inferer value 0: slow failure",
+		},
+		{
+			name: "failure of a value rather than the cancellation of the values it interrupted",
+			adapter: &delayedLossAdapter{
+				delays: map[string]time.Duration{"1": time.Minute, "2": time.Minute},
+				errs:   map[string]error{"3": errors.New("fast failure")},
+			},
+			errorContains: "error computing loss for inferer value 0: fast failure",
+		},
+		{
+			name:          "deadline",
+			adapter:       &delayedLossAdapter{delays: map[string]time.Duration{"5": time.Minute}},
+			ctxTimeout:    50 * time.Millisecond,
+			errorContains: "losses not computed by the deadline",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctxTimeout)
+				defer cancel()
+			}
+			reputer := reputer
+			reputer.LossFunctionEntrypoint = tt.adapter
+
+			suite := &UseCaseSuite{}
+			result, err := suite.ComputeLossBundle(ctx, "0", valueBundle, reputer)
+			assert.LessOrEqual(t, tt.adapter.maxInFlight.Load(), int32(3))
+			if tt.errorContains != "" {
+				assert.ErrorContains(t, err, tt.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int32(3), tt.adapter.maxInFlight.Load())
+			assert.Equal(t, "1", result.CombinedValue.String())
+			assert.Equal(t, "2", result.NaiveValue.String())
+			for i, inferer := range result.InfererValues {
+				assert.Equal(t, fmt.Sprint("inferer", i+3), inferer.Worker)
+				assert.Equal(t, fmt.Sprint(i+3), inferer.Value.String())
+			}
+		})
+	}
+}
+
+// Loss function adapter returning the value plus one as its loss
+type offsetLossAdapter struct {
+	MockAlloraAdapter
+}
+
+func (a *offsetLossAdapter) LossFunction(ctx context.Context, node lib.ReputerConfig, sourceTruth string, value string, options map[string]string) (string, error) {
+	loss, err := alloraMath.MustNewDecFromString(value).Add(alloraMath.OneDec())
+	return loss.String(), err
+}
+
+// Every field of the chain's ValueBundle must be carried over to the loss bundle: the values as their losses,
+// the others as is. A field added to ValueBundle fails this test until ComputeLossBundle and this table handle it.
+func TestComputeLossBundleCoversValueBundle(t *testing.T) {
+	three, four := alloraMath.MustNewDecFromString("3"), alloraMath.MustNewDecFromString("4")
+	nonce := &emissionstypes.ReputerRequestNonce{ReputerNonce: &emissionstypes.Nonce{BlockHeight: 100}}
+
+	tests := []struct {
+		field    string
+		bundle   emissionstypes.ValueBundle
+		expected any // expected value of the field in the loss bundle
+	}{
+		{"TopicId", emissionstypes.ValueBundle{TopicId: 1}, uint64(1)},
+		{"ReputerRequestNonce", emissionstypes.ValueBundle{ReputerRequestNonce: nonce}, nonce},
+		{"Reputer", emissionstypes.ValueBundle{Reputer: "reputer"}, "reputer"},
+		{"ExtraData", emissionstypes.ValueBundle{ExtraData: []byte("extra")}, []byte("extra")},
+		{"CombinedValue", emissionstypes.ValueBundle{CombinedValue: three}, four},
+		{"NaiveValue", emissionstypes.ValueBundle{NaiveValue: three}, four},
+		{
+			"InfererValues",
+			emissionstypes.ValueBundle{InfererValues: []*emissionstypes.WorkerAttributedValue{{Worker: "inferer", Value: three}}},
+			[]*emissionstypes.WorkerAttributedValue{{Worker: "inferer", Value: four}},
+		},
+		{
+			"ForecasterValues",
+			emissionstypes.ValueBundle{ForecasterValues: []*emissionstypes.WorkerAttributedValue{{Worker: "forecaster", Value: three}}},
+			[]*emissionstypes.WorkerAttributedValue{{Worker: "forecaster", Value: four}},
+		},
+		{
+			"OneOutInfererValues",
+			emissionstypes.ValueBundle{OneOutInfererValues: []*emissionstypes.WithheldWorkerAttributedValue{{Worker: "inferer", Value: three}}},
+			[]*emissionstypes.WithheldWorkerAttributedValue{{Worker: "inferer", Value: four}},
+		},
+		{
+			"OneOutForecasterValues",
+			emissionstypes.ValueBundle{OneOutForecasterValues: []*emissionstypes.WithheldWorkerAttributedValue{{Worker: "forecaster", Value: three}}},
+			[]*emissionstypes.WithheldWorkerAttributedValue{{Worker: "forecaster", Value: four}},
+		},
+		{
+			"OneInForecasterValues",
+			emissionstypes.ValueBundle{OneInForecasterValues: []*emissionstypes.WorkerAttributedValue{{Worker: "forecaster", Value: three}}},
+			[]*emissionstypes.WorkerAttributedValue{{Worker: "forecaster", Value: four}},
+		},
+		{
+			"OneOutInfererForecasterValues",
+			emissionstypes.ValueBundle{OneOutInfererForecasterValues: []*emissionstypes.OneOutInfererForecasterValues{
+				{Forecaster: "forecaster", OneOutInfererValues: []*emissionstypes.WithheldWorkerAttributedValue{{Worker: "inferer1", Value: three}, {Worker: "inferer2", Value: four}}},
+				{Forecaster: "forecaster2"},
+			}},
+			[]*emissionstypes.OneOutInfererForecasterValues{
+				{Forecaster: "forecaster", OneOutInfererValues: []*emissionstypes.WithheldWorkerAttributedValue{{Worker: "inferer1", Value: four}, {Worker: "inferer2", Value: alloraMath.MustNewDecFromString("5")}}},
+				{Forecaster: "forecaster2", OneOutInfererValues: []*emissionstypes.WithheldWorkerAttributedValue{}},
+			},
+		},
+	}
+
+	covered := make(map[string]bool)
+	for _, tt := range tests {
+		covered[tt.field] = true
+	}
+	bundleType := reflect.TypeOf(emissionstypes.ValueBundle{})
+	for i := 0; i < bundleType.NumField(); i++ {
+		if field := bundleType.Field(i).Name; !covered[field] {
+			t.Errorf("ValueBundle.%s is not handled by the reputer: compute or copy it in ComputeLossBundle, and add it to this table", field)
+		}
+	}
+
+	reputer := lib.ReputerConfig{
+		LossFunctionEntrypoint: &offsetLossAdapter{},
+		LossFunctionParameters: lib.LossFunctionParameters{IsNeverNegative: &[]bool{false}[0]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			_, ok := bundleType.FieldByName(tt.field)
+			require.True(t, ok, "ValueBundle has no field %s", tt.field)
+
+			suite := &UseCaseSuite{}
+			result, err := suite.ComputeLossBundle(context.Background(), "0", &tt.bundle, reputer)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, reflect.ValueOf(result).FieldByName(tt.field).Interface())
+		})
+	}
+}
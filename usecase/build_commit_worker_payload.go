@@ -5,20 +5,27 @@ import (
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 
 	"github.com/rs/zerolog/log"
 
 	alloraMath "github.com/allora-network/allora-chain/math"
 	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
-	"github.com/cosmos/cosmos-sdk/types/tx/signing"
 )
 
-func (suite *UseCaseSuite) BuildCommitWorkerPayload(worker lib.WorkerConfig, nonce *emissionstypes.Nonce) (bool, error) {
-	ctx := context.Background()
+func (suite *UseCaseSuite) BuildCommitWorkerPayload(ctx context.Context, worker lib.WorkerConfig, nonce *emissionstypes.Nonce) (bool, error) {
+	payload, err := suite.BuildSignedWorkerPayload(ctx, worker, nonce)
+	if err != nil {
+		return false, err
+	}
+	return suite.CommitSignedPayload(ctx, payload)
+}
 
+// Computes the inference and forecasts of the worker for the nonce with its adapters, and signs them into the msg to submit
+func (suite *UseCaseSuite) BuildSignedWorkerPayload(ctx context.Context, worker lib.WorkerConfig, nonce *emissionstypes.Nonce) (*SignedPayload, error) {
 	if worker.InferenceEntrypoint == nil && worker.ForecastEntrypoint == nil {
 		log.Error().Msg("Worker has no valid Inference or Forecast entrypoints")
-		return false, nil
+		return nil, errors.New("worker has no valid inference or forecast entrypoints")
 	}
 
 	var workerResponse = lib.WorkerResponse{
@@ -26,10 +33,10 @@ func (suite *UseCaseSuite) BuildCommitWorkerPayload(worker lib.WorkerConfig, non
 	}
 
 	if worker.InferenceEntrypoint != nil {
-		inference, err := worker.InferenceEntrypoint.CalcInference(worker, nonce.BlockHeight)
+		inference, err := worker.InferenceEntrypoint.CalcInference(ctx, worker, nonce.BlockHeight)
 		if err != nil {
 			log.Error().Err(err).Str("worker", worker.InferenceEntrypoint.Name()).Msg("Error computing inference for worker")
-			return false, err
+			return nil, err
 		}
 		workerResponse.InfererValue = inference
 		suite.Metrics.IncrementMetricsCounter(lib.InferenceRequestCount, suite.Node.Chain.Address, worker.TopicId)
@@ -37,10 +44,10 @@ func (suite *UseCaseSuite) BuildCommitWorkerPayload(worker lib.WorkerConfig, non
 
 	if worker.ForecastEntrypoint != nil {
 		forecasts := []lib.NodeValue{}
-		forecasts, err := worker.ForecastEntrypoint.CalcForecast(worker, nonce.BlockHeight)
+		forecasts, err := worker.ForecastEntrypoint.CalcForecast(ctx, worker, nonce.BlockHeight)
 		if err != nil {
 			log.Error().Err(err).Str("worker", worker.ForecastEntrypoint.Name()).Msg("Error computing forecast for worker")
-			return false, err
+			return nil, err
 		}
 		workerResponse.ForecasterValues = forecasts
 		suite.Metrics.IncrementMetricsCounter(lib.ForecastRequestCount, suite.Node.Chain.Address, worker.TopicId)
@@ -49,20 +56,20 @@ func (suite *UseCaseSuite) BuildCommitWorkerPayload(worker lib.WorkerConfig, non
 	workerPayload, err := suite.BuildWorkerPayload(workerResponse, nonce.BlockHeight)
 	if err != nil {
 		log.Error().Err(err).Msg("Error building workerPayload")
-		return false, err
+		return nil, err
 	}
 	suite.Metrics.IncrementMetricsCounter(lib.WorkerDataBuildCount, suite.Node.Chain.Address, worker.TopicId)
 
-	workerDataBundle, err := suite.SignWorkerPayload(&workerPayload)
+	workerDataBundle, err := suite.SignWorkerPayload(ctx, &workerPayload)
 	if err != nil {
 		log.Error().Err(err).Msg("Error signing workerPayload")
-		return false, err
+		return nil, err
 	}
 	workerDataBundle.Nonce = nonce
 	workerDataBundle.TopicId = worker.TopicId
 
 	if err := workerDataBundle.Validate(); err != nil {
-		return false, err
+		return nil, err
 	}
 
 	req := &emissionstypes.InsertWorkerPayloadRequest{
@@ -76,16 +83,16 @@ func (suite *UseCaseSuite) BuildCommitWorkerPayload(worker lib.WorkerConfig, non
 		log.Info().Str("req", string(reqJSON)).Msg("Sending InsertWorkerPayload to chain")
 	}
 
-	if suite.Node.Wallet.SubmitTx {
-		_, err = suite.Node.SendDataWithRetry(ctx, req, "Send Worker Data to chain")
-		if err != nil {
-			return false, err
-		}
-		suite.Metrics.IncrementMetricsCounter(lib.WorkerChainSubmissionCount, suite.Node.Chain.Address, worker.TopicId)
-	} else {
-		log.Info().Uint64("topicId", worker.TopicId).Msg("SubmitTx=false; Skipping sending Worker Data to chain")
-	}
-	return true, nil
+	return &SignedPayload{
+		Role:        lib.ROLE_WORKER,
+		TopicId:     worker.TopicId,
+		Nonce:       nonce.BlockHeight,
+		Bundle:      workerDataBundle.InferenceForecastsBundle,
+		Signature:   workerDataBundle.InferencesForecastsBundleSignature,
+		Pubkey:      workerDataBundle.Pubkey,
+		Request:     req,
+		RequestJSON: reqJSON,
+	}, nil
 }
 
 func (suite *UseCaseSuite) BuildWorkerPayload(workerResponse lib.WorkerResponse, nonce emissionstypes.BlockHeight) (emissionstypes.InferenceForecastBundle, error) {
@@ -134,7 +141,7 @@ func (suite *UseCaseSuite) BuildWorkerPayload(workerResponse lib.WorkerResponse,
 	return inferenceForecastsBundle, nil
 }
 
-func (suite *UseCaseSuite) SignWorkerPayload(workerPayload *emissionstypes.InferenceForecastBundle) (*emissionstypes.WorkerDataBundle, error) {
+func (suite *UseCaseSuite) SignWorkerPayload(ctx context.Context, workerPayload *emissionstypes.InferenceForecastBundle) (*emissionstypes.WorkerDataBundle, error) {
 	// Marshall and sign the bundle
 	protoBytesIn := make([]byte, 0) // Create a byte slice with initial length 0 and capacity greater than 0
 	protoBytesIn, err := workerPayload.XXX_Marshal(protoBytesIn, true)
@@ -142,12 +149,13 @@ func (suite *UseCaseSuite) SignWorkerPayload(workerPayload *emissionstypes.Infer
 		log.Error().Err(err).Msg("Error Marshalling workerPayload")
 		return &emissionstypes.WorkerDataBundle{}, err
 	}
-	sig, pk, err := suite.Node.Chain.Client.Context().Keyring.Sign(suite.Node.Chain.Account.Name, protoBytesIn, signing.SignMode_SIGN_MODE_DIRECT)
-	pkStr := hex.EncodeToString(pk.Bytes())
+	// The signature is verified before the bundle is submitted
+	sig, err := lib.SignAndVerify(ctx, suite.Node.Chain.Signer, protoBytesIn)
 	if err != nil {
 		log.Error().Err(err).Msg("Error signing the InferenceForecastsBundle message")
 		return &emissionstypes.WorkerDataBundle{}, err
 	}
+	pkStr := hex.EncodeToString(suite.Node.Chain.Signer.PubKey().Bytes())
 	// Create workerDataBundle with signature
 	workerDataBundle := &emissionstypes.WorkerDataBundle{
 		Worker:                             suite.Node.Wallet.Address,
@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Payload of a worker or reputer for a nonce, signed and ready to be submitted
+type SignedPayload struct {
+	Role        lib.ActorRole
+	TopicId     emissionstypes.TopicId
+	Nonce       lib.BlockHeight
+	Bundle      any    // the signed InferenceForecastBundle of a worker, or ValueBundle of a reputer
+	Signature   []byte // signature of the bundle
+	Pubkey      string // hex public key verifying the signature
+	Request     sdktypes.Msg
+	RequestJSON []byte // the request as JSON, nil if it could not be marshalled
+}
+
+// Records the payload in the ledger and submits it to the chain, or only records it as a dry run if SubmitTx is off
+func (suite *UseCaseSuite) CommitSignedPayload(ctx context.Context, payload *SignedPayload) (bool, error) {
+	submission := &lib.Submission{
+		Role:      payload.Role,
+		Actor:     suite.Node.Wallet.Address,
+		TopicId:   payload.TopicId,
+		Nonce:     payload.Nonce,
+		Payload:   payload.RequestJSON,
+		Signature: payload.Signature,
+	}
+	suite.RecordSubmissionPending(submission)
+
+	if !suite.Node.Wallet.SubmitTx {
+		log.Info().Uint64("topicId", payload.TopicId).Str("role", string(payload.Role)).Msg("SubmitTx=false; Skipping sending data to chain")
+		suite.RecordSubmissionDryRun(submission)
+		return true, nil
+	}
+
+	infoMsg, submissionCounter := "Send Worker Data to chain", lib.WorkerChainSubmissionCount
+	if payload.Role == lib.ROLE_REPUTER {
+		infoMsg, submissionCounter = "Send Reputer Data to chain", lib.ReputerChainSubmissionCount
+	}
+	result, err := suite.Node.SubmitMsg(ctx, payload.TopicId, payload.Request, infoMsg)
+	suite.RecordTxOutcome(payload.TopicId, result, err)
+	suite.RecordSubmissionOutcome(submission, result, err)
+	if err != nil {
+		log.Error().Err(err).Uint64("topicId", payload.TopicId).Msgf("Error sending %s data to chain", payload.Role)
+		return false, err
+	}
+	suite.Metrics.IncrementMetricsCounter(submissionCounter, suite.Node.Chain.Address, payload.TopicId)
+	return true, nil
+}
@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Wakes up actor loops as soon as a block may have opened a nonce for them.
+// While the node event subscription is down, actors fall back to polling every LoopSeconds.
+type EventDispatcher struct {
+	mu         sync.RWMutex
+	subscribed bool
+	workers    []chan lib.BlockHeight
+	reputers   map[emissionstypes.TopicId][]chan lib.BlockHeight
+}
+
+func NewEventDispatcher() *EventDispatcher {
+	return &EventDispatcher{
+		reputers: make(map[emissionstypes.TopicId][]chan lib.BlockHeight),
+	}
+}
+
+// Non-blocking send of the latest block height, 0 if unknown.
+// Triggers coalesce if the actor is still busy with the previous one.
+func notify(trigger chan lib.BlockHeight, height lib.BlockHeight) {
+	select {
+	case trigger <- height:
+	default:
+	}
+}
+
+// Returns the channel on which a worker is woken up. Returns nil if events are disabled.
+func (d *EventDispatcher) RegisterWorker() <-chan lib.BlockHeight {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	trigger := make(chan lib.BlockHeight, 1)
+	d.workers = append(d.workers, trigger)
+	return trigger
+}
+
+// Returns the channel on which a reputer of the topic is woken up. Returns nil if events are disabled.
+func (d *EventDispatcher) RegisterReputer(topicId emissionstypes.TopicId) <-chan lib.BlockHeight {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	trigger := make(chan lib.BlockHeight, 1)
+	d.reputers[topicId] = append(d.reputers[topicId], trigger)
+	return trigger
+}
+
+// Stops waking up the actor of the trigger, e.g. once removed from the config. Nil-safe.
+func (d *EventDispatcher) Unregister(trigger <-chan lib.BlockHeight) {
+	if d == nil || trigger == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	isTrigger := func(registered chan lib.BlockHeight) bool { return registered == trigger }
+	d.workers = slices.DeleteFunc(d.workers, isTrigger)
+	for topicId, triggers := range d.reputers {
+		d.reputers[topicId] = slices.DeleteFunc(triggers, isTrigger)
+	}
+}
+
+func (d *EventDispatcher) IsSubscribed() bool {
+	if d == nil {
+		return false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.subscribed
+}
+
+// Wakes up every actor on any change, so that they catch up after a (re)subscription
+// and stop waiting for events that won't come after a drop
+func (d *EventDispatcher) SetSubscribed(subscribed bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subscribed = subscribed
+	for _, trigger := range d.workers {
+		notify(trigger, 0)
+	}
+	for _, triggers := range d.reputers {
+		for _, trigger := range triggers {
+			notify(trigger, 0)
+		}
+	}
+}
+
+// A worker nonce can open at the end of any block, so every worker is woken up on each block.
+// Reputers are only woken up when a reputer nonce opened on their topic.
+func (d *EventDispatcher) Dispatch(event lib.BlockEvent) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, trigger := range d.workers {
+		notify(trigger, event.Height)
+	}
+	for _, topicId := range event.ReputerNonceOpenedTopicIds {
+		log.Debug().Uint64("topicId", topicId).Int64("height", event.Height).Msg("Reputer nonce opened")
+		for _, trigger := range d.reputers[topicId] {
+			notify(trigger, event.Height)
+		}
+	}
+}
+
+// Keeps the node event subscription alive, dispatching its events to the actors, until the context is done
+func (suite *UseCaseSuite) runEventSubscription(ctx context.Context) {
+	events := make(chan lib.BlockEvent)
+	defer close(events)
+	go func() {
+		for event := range events {
+			suite.Events.Dispatch(event)
+		}
+	}()
+
+	retryDelay := time.Duration(max(suite.Node.Wallet.RetryDelay, 1)) * time.Second
+	for {
+		err := suite.Node.SubscribeNewBlockEvents(ctx, events, func() {
+			suite.Events.SetSubscribed(true)
+		})
+		if suite.Events.IsSubscribed() {
+			suite.Events.SetSubscribed(false)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn().Err(err).Msg("Node event subscription down, polling until it is restored")
+		if lib.SleepWithContext(ctx, retryDelay) != nil {
+			return
+		}
+	}
+}
+
+// While subscribed to node events, actors still check for nonces this often, in case an event was missed
+var subscribedNonceCheckInterval = 10 * time.Minute
+
+// Blocks until the actor should check for open nonces again: when triggered while subscribed to node events,
+// or after subscribedNonceCheckInterval at the latest, else after the given number of seconds.
+// Returns early once the context is done.
+func (suite *UseCaseSuite) WaitForNonceCheck(ctx context.Context, trigger <-chan lib.BlockHeight, seconds int64) {
+	if suite.Events.IsSubscribed() && trigger != nil {
+		// A dropped subscription also triggers, so this doesn't wait the whole interval once polling is due
+		select {
+		case <-trigger:
+		case <-time.After(subscribedNonceCheckInterval):
+		case <-ctx.Done():
+		}
+		return
+	}
+	suite.WaitForNonceRetry(ctx, trigger, seconds)
+}
+
+// Blocks for the given number of seconds, or until triggered if subscribed to node events, or until the context is done
+func (suite *UseCaseSuite) WaitForNonceRetry(ctx context.Context, trigger <-chan lib.BlockHeight, seconds int64) {
+	if trigger == nil {
+		suite.Wait(ctx, seconds)
+		return
+	}
+	select {
+	case <-trigger:
+	case <-time.After(time.Duration(seconds) * time.Second):
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func isTriggered(trigger <-chan lib.BlockHeight) bool {
+	select {
+	case <-trigger:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestEventDispatcher(t *testing.T) {
+	dispatcher := NewEventDispatcher()
+	worker := dispatcher.RegisterWorker()
+	reputerTopic1 := dispatcher.RegisterReputer(1)
+	reputerTopic2 := dispatcher.RegisterReputer(2)
+
+	dispatcher.Dispatch(lib.BlockEvent{Height: 10})
+	assert.True(t, isTriggered(worker), "workers are woken up on every block")
+	assert.False(t, isTriggered(reputerTopic1))
+	assert.False(t, isTriggered(reputerTopic2))
+
+	dispatcher.Dispatch(lib.BlockEvent{Height: 11, ReputerNonceOpenedTopicIds: []uint64{2}})
+	dispatcher.Dispatch(lib.BlockEvent{Height: 12, ReputerNonceOpenedTopicIds: []uint64{2}})
+	assert.True(t, isTriggered(worker))
+	assert.False(t, isTriggered(reputerTopic1))
+	assert.True(t, isTriggered(reputerTopic2))
+	assert.False(t, isTriggered(reputerTopic2), "triggers coalesce while the actor is busy")
+
+	dispatcher.SetSubscribed(false)
+	assert.True(t, isTriggered(worker), "subscription changes wake up every actor")
+	assert.True(t, isTriggered(reputerTopic1))
+	assert.True(t, isTriggered(reputerTopic2))
+}
+
+func TestWaitForNonceCheckFallsBackToPolling(t *testing.T) {
+	suite := &UseCaseSuite{Events: NewEventDispatcher()}
+	suite.Events.SetSubscribed(true)
+	trigger := suite.Events.RegisterWorker()
+
+	done := make(chan struct{})
+	go func() {
+		suite.WaitForNonceCheck(context.Background(), trigger, 3600)
+		close(done)
+	}()
+
+	suite.Events.SetSubscribed(false)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("actor kept waiting for events after the subscription dropped")
+	}
+	assert.False(t, suite.Events.IsSubscribed())
+}
+
+func TestWaitForNonceCheckWhileSubscribed(t *testing.T) {
+	interval := subscribedNonceCheckInterval
+	subscribedNonceCheckInterval = 200 * time.Millisecond
+	defer func() { subscribedNonceCheckInterval = interval }()
+	suite := &UseCaseSuite{Events: NewEventDispatcher()}
+	suite.Events.SetSubscribed(true)
+	trigger := suite.Events.RegisterWorker()
+
+	// Not polled every second while subscribed, but still checked in case an event was missed
+	start := time.Now()
+	suite.WaitForNonceCheck(context.Background(), trigger, 0)
+	assert.WithinDuration(t, start.Add(subscribedNonceCheckInterval), time.Now(), 150*time.Millisecond)
+}
+
+func TestWaitForNonceCheckStopsOnShutdown(t *testing.T) {
+	suite := &UseCaseSuite{Events: NewEventDispatcher()}
+	suite.Events.SetSubscribed(true)
+	subscribedTrigger := suite.Events.RegisterWorker()
+
+	for name, trigger := range map[string]<-chan lib.BlockHeight{"subscribed": subscribedTrigger, "polling": nil} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				suite.WaitForNonceCheck(ctx, trigger, 3600)
+				close(done)
+			}()
+
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("actor kept waiting after shutdown")
+			}
+		})
+	}
+}
+
+func TestEventDispatcherUnregister(t *testing.T) {
+	dispatcher := NewEventDispatcher()
+	worker := dispatcher.RegisterWorker()
+	otherWorker := dispatcher.RegisterWorker()
+	reputer := dispatcher.RegisterReputer(1)
+
+	dispatcher.Unregister(worker)
+	dispatcher.Unregister(reputer)
+	dispatcher.Dispatch(lib.BlockEvent{Height: 10, ReputerNonceOpenedTopicIds: []uint64{1}})
+	assert.False(t, isTriggered(worker), "removed actors are not woken up anymore")
+	assert.False(t, isTriggered(reputer))
+	assert.True(t, isTriggered(otherWorker))
+
+	var disabled *EventDispatcher
+	disabled.Unregister(nil)
+}
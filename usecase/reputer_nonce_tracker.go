@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"slices"
+)
+
+// Outcome of the attempts to act upon a single nonce
+type nonceAttempt struct {
+	succeeded bool
+	attempts  int
+}
+
+// Tracks every open reputer nonce of a topic, so that a failed nonce is retried while
+// its window is open even after newer nonces have been acted upon.
+// A nonce's window is open for as long as the chain reports it as unfulfilled.
+type ReputerNonceTracker struct {
+	nonces map[lib.BlockHeight]*nonceAttempt
+}
+
+func NewReputerNonceTracker() *ReputerNonceTracker {
+	return &ReputerNonceTracker{
+		nonces: make(map[lib.BlockHeight]*nonceAttempt),
+	}
+}
+
+// Syncs the tracker with the nonces currently open on chain, forgetting those whose window closed.
+// Returns the open nonces not yet successfully acted upon, oldest first.
+func (t *ReputerNonceTracker) Update(openNonces []lib.BlockHeight) []lib.BlockHeight {
+	open := make(map[lib.BlockHeight]bool, len(openNonces))
+	for _, nonce := range openNonces {
+		open[nonce] = true
+		if _, ok := t.nonces[nonce]; !ok {
+			t.nonces[nonce] = &nonceAttempt{}
+		}
+	}
+	for nonce := range t.nonces {
+		if !open[nonce] {
+			delete(t.nonces, nonce)
+		}
+	}
+
+	pending := []lib.BlockHeight{}
+	for nonce, attempt := range t.nonces {
+		if !attempt.succeeded {
+			pending = append(pending, nonce)
+		}
+	}
+	slices.Sort(pending)
+	return pending
+}
+
+// Records the outcome of an attempt to act upon the nonce. A nil error marks it as succeeded.
+func (t *ReputerNonceTracker) Record(nonce lib.BlockHeight, err error) {
+	attempt, ok := t.nonces[nonce]
+	if !ok {
+		attempt = &nonceAttempt{}
+		t.nonces[nonce] = attempt
+	}
+	attempt.attempts++
+	attempt.succeeded = err == nil
+}
+
+// Number of attempts made so far on the nonce
+func (t *ReputerNonceTracker) Attempts(nonce lib.BlockHeight) int {
+	if attempt, ok := t.nonces[nonce]; ok {
+		return attempt.attempts
+	}
+	return 0
+}
+
+// True if any open nonce had a failed attempt and is waiting to be retried
+func (t *ReputerNonceTracker) HasFailures() bool {
+	for _, attempt := range t.nonces {
+		if attempt.attempts > 0 && !attempt.succeeded {
+			return true
+		}
+	}
+	return false
+}
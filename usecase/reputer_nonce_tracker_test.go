@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReputerNonceTracker(t *testing.T) {
+	tracker := NewReputerNonceTracker()
+
+	// Every open nonce is pending, oldest first, whatever the order reported by the chain
+	pending := tracker.Update([]lib.BlockHeight{300, 100, 200})
+	assert.Equal(t, []lib.BlockHeight{100, 200, 300}, pending)
+	assert.False(t, tracker.HasFailures())
+
+	tracker.Record(100, errors.New("ground truth unavailable"))
+	tracker.Record(200, nil)
+	tracker.Record(300, nil)
+	assert.True(t, tracker.HasFailures())
+	assert.Equal(t, 1, tracker.Attempts(100))
+
+	// A newer nonce doesn't prevent retrying the older failed one
+	pending = tracker.Update([]lib.BlockHeight{100, 200, 300, 400})
+	assert.Equal(t, []lib.BlockHeight{100, 400}, pending)
+
+	tracker.Record(100, errors.New("ground truth unavailable"))
+	tracker.Record(400, nil)
+	assert.Equal(t, 2, tracker.Attempts(100))
+
+	// Once its window closes, the failed nonce is forgotten
+	pending = tracker.Update([]lib.BlockHeight{200, 300, 400})
+	assert.Empty(t, pending)
+	assert.False(t, tracker.HasFailures())
+	assert.Equal(t, 0, tracker.Attempts(100))
+
+	// A nonce succeeding on retry is not acted upon again
+	pending = tracker.Update([]lib.BlockHeight{200, 300, 400, 500})
+	assert.Equal(t, []lib.BlockHeight{500}, pending)
+	tracker.Record(500, errors.New("mempool is full"))
+	tracker.Record(500, nil)
+	assert.Empty(t, tracker.Update([]lib.BlockHeight{500}))
+	assert.False(t, tracker.HasFailures())
+}
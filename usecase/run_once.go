@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"context"
+	"errors"
+	"fmt"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+)
+
+// Builds and signs the payload of the worker or reputer of the config in the topic for the nonce at the block height exactly once,
+// e.g. to debug its adapters. The payload is submitted only if submit is set, whatever SubmitTx is set to, and with
+// the keyring allowed to submit on the chain, see CheckKeyringForChain.
+// A height of 0 acts upon the latest open worker nonce, or the oldest open reputer nonce.
+// The role can be left empty if the config has a single actor in the topic.
+func (suite *UseCaseSuite) RunOnce(ctx context.Context, role lib.ActorRole, topicId emissionstypes.TopicId, height lib.BlockHeight, submit bool) (*SignedPayload, error) {
+	targets := suite.AdminTargets(role, topicId)
+	switch {
+	case len(targets) == 0:
+		return nil, fmt.Errorf("no worker or reputer of the config in topic %d matches the role", topicId)
+	case len(targets) > 1:
+		return nil, fmt.Errorf("%d actors of the config are in topic %d, set the role of the one to run", len(targets), topicId)
+	}
+	target := targets[0]
+
+	var wallet *lib.WalletConfig
+	if target.Worker != nil {
+		wallet = target.Worker.Wallet
+	} else {
+		wallet = target.Reputer.Wallet
+	}
+	actor := *suite.ForActor(wallet)
+	actor.Node.Wallet.SubmitTx = submit
+	// The keyring was checked on loading with the SubmitTx of the config
+	if err := actor.Node.Wallet.CheckKeyringForChain(actor.Node.Chain.ChainId); err != nil {
+		return nil, err
+	}
+
+	var payload *SignedPayload
+	var err error
+	if target.Worker != nil {
+		nonce := &emissionstypes.Nonce{BlockHeight: height}
+		if height == 0 {
+			if nonce, err = actor.Node.GetLatestOpenWorkerNonceByTopicId(ctx, topicId); err != nil {
+				return nil, fmt.Errorf("cannot get latest open worker nonce: %w", err)
+			}
+		}
+		if nonce.BlockHeight == 0 {
+			return nil, errors.New("no open worker nonce, set the block height")
+		}
+		payload, err = actor.BuildSignedWorkerPayload(ctx, *target.Worker, nonce)
+	} else {
+		if height == 0 {
+			if height, err = actor.Node.GetOldestReputerNonceByTopicId(ctx, topicId); err != nil {
+				return nil, fmt.Errorf("cannot get oldest open reputer nonce: %w", err)
+			}
+		}
+		if height == 0 {
+			return nil, errors.New("no open reputer nonce, set the block height")
+		}
+		payload, err = actor.BuildSignedReputerPayload(ctx, *target.Reputer, height)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if submit {
+		if _, err := actor.CommitSignedPayload(ctx, payload); err != nil {
+			return payload, err
+		}
+	}
+	return payload, nil
+}
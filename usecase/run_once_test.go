@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"context"
+	"testing"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// Signer with an in-memory key
+type privKeySigner struct {
+	key *secp256k1.PrivKey
+}
+
+func (s privKeySigner) PubKey() cryptotypes.PubKey {
+	return s.key.PubKey()
+}
+
+func (s privKeySigner) Sign(ctx context.Context, signBytes []byte) ([]byte, error) {
+	return s.key.Sign(signBytes)
+}
+
+func TestRunOnce(t *testing.T) {
+	signer := privKeySigner{key: secp256k1.GenPrivKey()}
+	address := lib.SignerAddress(signer).String()
+	adapter := &MockAlloraAdapter{}
+	adapter.On("CalcInference", mock.AnythingOfType("lib.WorkerConfig"), int64(100)).Return("9.5", nil)
+	adapter.On("Name").Return("mock")
+
+	suite := &UseCaseSuite{
+		Node: lib.NodeConfig{
+			// SubmitTx is overridden: with no chain client, a submission would fail the test
+			Wallet:  lib.WalletConfig{Address: address, AddressKeyName: "node", SubmitTx: true},
+			Chain:   lib.ChainConfig{Address: address, Signer: signer},
+			Worker:  []lib.WorkerConfig{{TopicId: 1, InferenceEntrypoint: adapter}, {TopicId: 2, InferenceEntrypoint: adapter}},
+			Reputer: []lib.ReputerConfig{{TopicId: 2}},
+		},
+	}
+
+	payload, err := suite.RunOnce(context.Background(), "", 1, 100, false)
+	require.NoError(t, err)
+	assert.Equal(t, lib.ROLE_WORKER, payload.Role)
+	assert.Equal(t, emissionstypes.TopicId(1), payload.TopicId)
+	assert.Equal(t, lib.BlockHeight(100), payload.Nonce)
+	bundle, ok := payload.Bundle.(*emissionstypes.InferenceForecastBundle)
+	require.True(t, ok)
+	assert.Equal(t, "9.5", bundle.Inference.Value.String())
+	assert.Equal(t, address, bundle.Inference.Inferer)
+
+	// The signature is the one the chain verifies
+	signBytes, err := bundle.XXX_Marshal(nil, true)
+	require.NoError(t, err)
+	assert.True(t, signer.PubKey().VerifySignature(signBytes, payload.Signature))
+	assert.IsType(t, &emissionstypes.InsertWorkerPayloadRequest{}, payload.Request)
+	assert.Contains(t, string(payload.RequestJSON), `"sender":"`+address+`"`)
+	assert.True(t, suite.Node.Wallet.SubmitTx, "the node's wallet is left untouched")
+
+	_, err = suite.RunOnce(context.Background(), "", 2, 100, false)
+	assert.ErrorContains(t, err, "2 actors of the config are in topic 2, set the role")
+	_, err = suite.RunOnce(context.Background(), lib.ROLE_REPUTER, 1, 100, false)
+	assert.ErrorContains(t, err, "no worker or reputer of the config in topic 1")
+
+	// Submitting is refused with the test keyring on a mainnet, even if the config doesn't submit
+	suite.Node.Wallet.SubmitTx = false
+	suite.Node.Chain.ChainId = "allora-mainnet-1"
+	_, err = suite.RunOnce(context.Background(), "", 1, 100, true)
+	assert.ErrorContains(t, err, "refusing to submit txs on chain allora-mainnet-1 with the unencrypted test keyring backend")
+	_, err = suite.RunOnce(context.Background(), "", 1, 100, false)
+	assert.NoError(t, err, "nothing is submitted without submit")
+}
@@ -0,0 +1,270 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"github.com/rs/zerolog/log"
+)
+
+const BLOCK_TIME_SAMPLE_BLOCKS = 100                 // number of recent blocks over which the block time is measured
+const BLOCK_TIME_REFRESH_INTERVAL = 10 * time.Minute // how often the block time is measured again
+const LOSS_DEADLINE_MARGIN_BLOCKS = 2                // blocks kept after the losses of a reputer nonce are due, to sign and submit them
+
+// Chain queries the scheduler relies on, implemented by lib.NodeConfig
+type ScheduleSource interface {
+	GetTopicInfo(ctx context.Context, topicId emissionstypes.TopicId) (*emissionstypes.Topic, error)
+	GetLatestBlock(ctx context.Context) (lib.BlockHeight, time.Time, error)
+	GetBlockTime(ctx context.Context, height lib.BlockHeight) (time.Time, error)
+}
+
+// Block height at which a nonce is expected to open, and the estimated time at which that block is committed
+type NonceDue struct {
+	Height lib.BlockHeight
+	At     time.Time
+}
+
+// Snapshot of a topic's epoch parameters and of the chain head, from which nonce openings are predicted
+type TopicSchedule struct {
+	TopicId                emissionstypes.TopicId
+	EpochLength            int64
+	EpochLastEnded         lib.BlockHeight
+	GroundTruthLag         int64
+	WorkerSubmissionWindow int64
+	LatestHeight           lib.BlockHeight
+	LatestTime             time.Time
+	BlockTime              time.Duration
+}
+
+// Predicts, per topic, when nonces open so that actors only wake up around those moments
+type Scheduler struct {
+	source ScheduleSource
+
+	mu         sync.Mutex
+	blockTime  time.Duration
+	measuredAt time.Time
+}
+
+func NewScheduler(source ScheduleSource) *Scheduler {
+	return &Scheduler{
+		source:    source,
+		blockTime: lib.SECONDS_PER_BLOCK * time.Second,
+	}
+}
+
+// Average block time over the recent blocks, measured again every BLOCK_TIME_REFRESH_INTERVAL.
+// Keeps the previous value, initially SECONDS_PER_BLOCK, if it cannot be measured.
+func (s *Scheduler) BlockTime(ctx context.Context) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.measuredAt.IsZero() && time.Since(s.measuredAt) < BLOCK_TIME_REFRESH_INTERVAL {
+		return s.blockTime
+	}
+	s.measuredAt = time.Now()
+	blockTime, err := s.measureBlockTime(ctx)
+	if err != nil {
+		log.Warn().Err(err).Dur("blockTime", s.blockTime).Msg("Could not measure block time, keeping previous value")
+		return s.blockTime
+	}
+	log.Debug().Dur("blockTime", blockTime).Msg("Measured block time")
+	s.blockTime = blockTime
+	return s.blockTime
+}
+
+func (s *Scheduler) measureBlockTime(ctx context.Context) (time.Duration, error) {
+	latestHeight, latestTime, err := s.source.GetLatestBlock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sampleStart := max(latestHeight-BLOCK_TIME_SAMPLE_BLOCKS, 1)
+	if sampleStart >= latestHeight {
+		return 0, errors.New("not enough blocks to measure block time")
+	}
+	sampleStartTime, err := s.source.GetBlockTime(ctx, sampleStart)
+	if err != nil {
+		return 0, err
+	}
+	elapsed := latestTime.Sub(sampleStartTime)
+	if elapsed <= 0 {
+		return 0, errors.New("block times are not increasing")
+	}
+	return elapsed / time.Duration(latestHeight-sampleStart), nil
+}
+
+// Returns the current schedule of the topic. Nil-safe: fails if there is no scheduler.
+func (s *Scheduler) GetTopicSchedule(ctx context.Context, topicId emissionstypes.TopicId) (TopicSchedule, error) {
+	if s == nil {
+		return TopicSchedule{}, errors.New("no scheduler")
+	}
+	topic, err := s.source.GetTopicInfo(ctx, topicId)
+	if err != nil {
+		return TopicSchedule{}, err
+	}
+	latestHeight, latestTime, err := s.source.GetLatestBlock(ctx)
+	if err != nil {
+		return TopicSchedule{}, err
+	}
+	// Never trust a block time ahead of the local clock, to rather wake up early than late
+	if now := time.Now(); latestTime.After(now) {
+		latestTime = now
+	}
+	return TopicSchedule{
+		TopicId:                topicId,
+		EpochLength:            topic.EpochLength,
+		EpochLastEnded:         topic.EpochLastEnded,
+		GroundTruthLag:         topic.GroundTruthLag,
+		WorkerSubmissionWindow: topic.WorkerSubmissionWindow,
+		LatestHeight:           latestHeight,
+		LatestTime:             latestTime,
+		BlockTime:              s.BlockTime(ctx),
+	}, nil
+}
+
+// First epoch end strictly after the given height, epochs ending every EpochLength blocks from EpochLastEnded
+func (ts TopicSchedule) nextEpochEnd(height lib.BlockHeight) lib.BlockHeight {
+	if ts.EpochLength <= 0 {
+		return height + 1
+	}
+	offset := height - ts.EpochLastEnded
+	epochs := offset / ts.EpochLength
+	if offset < 0 && offset%ts.EpochLength != 0 {
+		// Round towards minus infinity
+		epochs--
+	}
+	return ts.EpochLastEnded + (epochs+1)*ts.EpochLength
+}
+
+func (ts TopicSchedule) dueAt(height lib.BlockHeight) NonceDue {
+	return NonceDue{
+		Height: height,
+		At:     ts.LatestTime.Add(time.Duration(height-ts.LatestHeight) * ts.BlockTime),
+	}
+}
+
+// Each epoch end opens a worker nonce at that height
+func (ts TopicSchedule) NextWorkerNonce() NonceDue {
+	return ts.dueAt(ts.nextEpochEnd(ts.LatestHeight))
+}
+
+// The reputer nonce of an epoch end can be submitted once the ground truth lag has passed
+func (ts TopicSchedule) NextReputerNonce() NonceDue {
+	return ts.dueAt(ts.nextEpochEnd(ts.LatestHeight-ts.GroundTruthLag) + ts.GroundTruthLag)
+}
+
+// When the chain starts accepting reputer payloads for the nonce
+func (ts TopicSchedule) ReputerWindowOpensAt(nonce lib.BlockHeight) NonceDue {
+	return ts.dueAt(nonce + ts.GroundTruthLag)
+}
+
+// When the chain stops accepting reputer payloads for the nonce
+func (ts TopicSchedule) ReputerWindowClosesAt(nonce lib.BlockHeight) NonceDue {
+	return ts.dueAt(nonce + 2*ts.GroundTruthLag)
+}
+
+// Time by which the losses of the reputer nonce must be computed, LOSS_DEADLINE_MARGIN_BLOCKS before its window closes
+func (ts TopicSchedule) ReputerLossDeadline(nonce lib.BlockHeight) time.Time {
+	return ts.ReputerWindowClosesAt(nonce).At.Add(-LOSS_DEADLINE_MARGIN_BLOCKS * ts.BlockTime)
+}
+
+// True if the chain currently accepts reputer payloads for the nonce
+func (ts TopicSchedule) IsReputerWindowOpen(nonce lib.BlockHeight) bool {
+	return nonce+ts.GroundTruthLag <= ts.LatestHeight && ts.LatestHeight <= nonce+2*ts.GroundTruthLag
+}
+
+// Blocks until the nonce is due, or the context is done. While subscribed to node events, the event of the due block
+// ends the wait as soon as it is committed, and the estimated time plus one block is only a fallback.
+func (suite *UseCaseSuite) WaitUntilDue(ctx context.Context, due NonceDue, blockTime time.Duration, trigger <-chan lib.BlockHeight) {
+	suite.waitUntil(ctx, due.Height, suite.dueDeadline(due, blockTime, trigger), trigger)
+}
+
+func (suite *UseCaseSuite) dueDeadline(due NonceDue, blockTime time.Duration, trigger <-chan lib.BlockHeight) time.Time {
+	if trigger != nil && suite.Events.IsSubscribed() {
+		return due.At.Add(blockTime)
+	}
+	return due.At
+}
+
+// Blocks until the deadline, the event of the block at the height, or the context is done.
+// True if the block at the height was committed.
+func (suite *UseCaseSuite) waitUntil(ctx context.Context, height lib.BlockHeight, deadline time.Time, trigger <-chan lib.BlockHeight) bool {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for {
+		select {
+		case committed := <-trigger:
+			if committed >= height {
+				return true
+			}
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// Blocks until the nonce of the topic is due, or the context is done. As the estimated time drifts with the block time,
+// the wait is cut to half the remaining time, and to loopSeconds, after which the time of the due block is estimated
+// again from the chain head, until it is less than two blocks away or committed.
+func (suite *UseCaseSuite) WaitForScheduledNonce(ctx context.Context, schedule TopicSchedule, due NonceDue, loopSeconds int64, trigger <-chan lib.BlockHeight) {
+	for ctx.Err() == nil {
+		deadline := suite.dueDeadline(due, schedule.BlockTime, trigger)
+		remaining := time.Until(deadline)
+		if remaining <= 2*schedule.BlockTime {
+			suite.waitUntil(ctx, due.Height, deadline, trigger)
+			return
+		}
+		wait := min(remaining/2, time.Duration(loopSeconds)*time.Second)
+		if suite.waitUntil(ctx, due.Height, time.Now().Add(max(wait, schedule.BlockTime)), trigger) || ctx.Err() != nil {
+			return
+		}
+		var err error
+		if schedule, err = suite.Scheduler.GetTopicSchedule(ctx, schedule.TopicId); err != nil {
+			log.Warn().Err(err).Uint64("topicId", schedule.TopicId).Msg("Could not estimate again when the nonce is due, checking for nonces")
+			return
+		}
+		if schedule.LatestHeight >= due.Height {
+			return
+		}
+		due = schedule.dueAt(due.Height)
+		log.Debug().Uint64("topicId", schedule.TopicId).Int64("BlockHeight", due.Height).Time("at", due.At).Msg("Nonce expected time estimated again")
+	}
+}
+
+// Blocks until the next worker nonce of the topic is due, or for LoopSeconds if it can't be scheduled
+func (suite *UseCaseSuite) WaitForNextWorkerNonce(ctx context.Context, worker lib.WorkerConfig, trigger <-chan lib.BlockHeight) {
+	schedule, err := suite.Scheduler.GetTopicSchedule(ctx, worker.TopicId)
+	if err != nil {
+		log.Warn().Err(err).Uint64("topicId", worker.TopicId).Msg("Could not schedule next worker nonce, polling instead")
+		suite.WaitForNonceCheck(ctx, trigger, worker.LoopSeconds)
+		return
+	}
+	due := schedule.NextWorkerNonce()
+	log.Debug().Uint64("topicId", worker.TopicId).Int64("BlockHeight", due.Height).Time("at", due.At).Msg("Next worker nonce expected")
+	suite.WaitForScheduledNonce(ctx, schedule, due, worker.LoopSeconds, trigger)
+}
+
+// Blocks until the next reputer nonce of the topic can be submitted, or for LoopSeconds if it can't be scheduled.
+// Already open nonces waiting for their submission window are taken into account, as they may not fall
+// on the current epoch boundaries if the topic skipped epochs.
+func (suite *UseCaseSuite) WaitForNextReputerNonce(ctx context.Context, reputer lib.ReputerConfig, waitingNonces []lib.BlockHeight, trigger <-chan lib.BlockHeight) {
+	schedule, err := suite.Scheduler.GetTopicSchedule(ctx, reputer.TopicId)
+	if err != nil {
+		log.Warn().Err(err).Uint64("topicId", reputer.TopicId).Msg("Could not schedule next reputer nonce, polling instead")
+		suite.WaitForNonceCheck(ctx, trigger, reputer.LoopSeconds)
+		return
+	}
+	due := schedule.NextReputerNonce()
+	for _, nonce := range waitingNonces {
+		if opensAt := schedule.ReputerWindowOpensAt(nonce); opensAt.Height > schedule.LatestHeight && opensAt.Height < due.Height {
+			due = opensAt
+		}
+	}
+	log.Debug().Uint64("topicId", reputer.TopicId).Int64("BlockHeight", due.Height).Time("at", due.At).Msg("Next reputer nonce expected")
+	suite.WaitForScheduledNonce(ctx, schedule, due, reputer.LoopSeconds, trigger)
+}
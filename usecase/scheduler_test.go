@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Chain stand-in producing a block every blockTime since genesis
+type fakeScheduleSource struct {
+	topic        emissionstypes.Topic
+	genesis      time.Time
+	blockTime    time.Duration
+	latestHeight lib.BlockHeight
+	followClock  bool // the latest height is the one produced by now, instead of latestHeight
+	err          error
+}
+
+func (f *fakeScheduleSource) GetTopicInfo(ctx context.Context, topicId emissionstypes.TopicId) (*emissionstypes.Topic, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &f.topic, nil
+}
+
+func (f *fakeScheduleSource) GetLatestBlock(ctx context.Context) (lib.BlockHeight, time.Time, error) {
+	if f.err != nil {
+		return 0, time.Time{}, f.err
+	}
+	latestHeight := f.latestHeight
+	if f.followClock {
+		latestHeight = lib.BlockHeight(time.Since(f.genesis) / f.blockTime)
+	}
+	blockTime, _ := f.GetBlockTime(ctx, latestHeight)
+	return latestHeight, blockTime, nil
+}
+
+func (f *fakeScheduleSource) GetBlockTime(ctx context.Context, height lib.BlockHeight) (time.Time, error) {
+	return f.genesis.Add(time.Duration(height) * f.blockTime), nil
+}
+
+func TestTopicSchedule(t *testing.T) {
+	source := &fakeScheduleSource{
+		topic: emissionstypes.Topic{
+			Id:                     1,
+			EpochLength:            10,
+			EpochLastEnded:         100,
+			GroundTruthLag:         20,
+			WorkerSubmissionWindow: 5,
+		},
+		genesis:      time.Now().Add(-1000 * 2 * time.Second),
+		blockTime:    2 * time.Second,
+		latestHeight: 103,
+	}
+	scheduler := NewScheduler(source)
+
+	schedule, err := scheduler.GetTopicSchedule(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, schedule.BlockTime, "block time is measured from the headers")
+
+	tests := []struct {
+		name               string
+		latestHeight       lib.BlockHeight
+		nextWorkerNonce    lib.BlockHeight
+		nextReputerNonce   lib.BlockHeight
+		openReputerNonces  []lib.BlockHeight
+		closedReputerNonce []lib.BlockHeight
+	}{
+		{"within epoch", 103, 110, 110, []lib.BlockHeight{70, 80}, []lib.BlockHeight{60, 90, 100}},
+		{"on epoch end", 110, 120, 120, []lib.BlockHeight{90}, []lib.BlockHeight{100}},
+		{"epochs skipped since last ended", 137, 140, 140, []lib.BlockHeight{100, 110}, []lib.BlockHeight{120}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source.latestHeight = tt.latestHeight
+			schedule, err := scheduler.GetTopicSchedule(context.Background(), 1)
+			require.NoError(t, err)
+
+			workerDue := schedule.NextWorkerNonce()
+			assert.Equal(t, tt.nextWorkerNonce, workerDue.Height)
+			assert.Equal(t, schedule.LatestTime.Add(time.Duration(tt.nextWorkerNonce-tt.latestHeight)*2*time.Second), workerDue.At)
+			assert.Equal(t, tt.nextReputerNonce, schedule.NextReputerNonce().Height)
+			for _, nonce := range tt.openReputerNonces {
+				assert.True(t, schedule.IsReputerWindowOpen(nonce), "reputer window of %d should be open", nonce)
+			}
+			for _, nonce := range tt.closedReputerNonce {
+				assert.False(t, schedule.IsReputerWindowOpen(nonce), "reputer window of %d should be closed", nonce)
+			}
+		})
+	}
+
+	assert.Equal(t, lib.BlockHeight(120), schedule.ReputerWindowClosesAt(80).Height)
+	assert.Equal(t, schedule.LatestTime.Add(30*time.Second), schedule.ReputerLossDeadline(80), "losses are due 2 blocks before the window closes")
+
+	source.err = errors.New("node unavailable")
+	_, err = scheduler.GetTopicSchedule(context.Background(), 1)
+	assert.Error(t, err)
+
+	var noScheduler *Scheduler
+	_, err = noScheduler.GetTopicSchedule(context.Background(), 1)
+	assert.Error(t, err)
+}
+
+func TestSchedulerBlockTimeFallback(t *testing.T) {
+	source := &fakeScheduleSource{err: errors.New("node unavailable")}
+	scheduler := NewScheduler(source)
+	assert.Equal(t, lib.SECONDS_PER_BLOCK*time.Second, scheduler.BlockTime(context.Background()))
+}
+
+func TestWaitUntilDue(t *testing.T) {
+	suite := &UseCaseSuite{Events: NewEventDispatcher()}
+	trigger := suite.Events.RegisterWorker()
+	suite.Events.SetSubscribed(true)
+
+	done := make(chan struct{})
+	go func() {
+		suite.WaitUntilDue(context.Background(), NonceDue{Height: 50, At: time.Now().Add(time.Hour)}, time.Second, trigger)
+		close(done)
+	}()
+
+	// Blocks before the due height, and subscription changes, don't end the wait
+	suite.Events.Dispatch(lib.BlockEvent{Height: 48})
+	suite.Events.Dispatch(lib.BlockEvent{Height: 49})
+	select {
+	case <-done:
+		t.Fatal("wait ended before the due block")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	suite.Events.Dispatch(lib.BlockEvent{Height: 50})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("wait did not end on the due block")
+	}
+
+	// Without events, the estimated time ends the wait
+	start := time.Now()
+	(&UseCaseSuite{}).WaitUntilDue(context.Background(), NonceDue{Height: 50, At: start.Add(50 * time.Millisecond)}, time.Hour, nil)
+	assert.WithinDuration(t, start.Add(50*time.Millisecond), time.Now(), 40*time.Millisecond)
+}
+
+func TestWaitForScheduledNonceWithShorterBlockTime(t *testing.T) {
+	// Blocks are produced every 20ms, while the scheduler still estimates 40ms
+	source := &fakeScheduleSource{
+		topic:       emissionstypes.Topic{Id: 1, EpochLength: 20, EpochLastEnded: 100},
+		genesis:     time.Now().Add(-105 * 20 * time.Millisecond),
+		blockTime:   20 * time.Millisecond,
+		followClock: true,
+	}
+	scheduler := NewScheduler(source)
+	scheduler.blockTime, scheduler.measuredAt = 40*time.Millisecond, time.Now()
+	suite := &UseCaseSuite{Scheduler: scheduler}
+
+	schedule, err := scheduler.GetTopicSchedule(context.Background(), 1)
+	require.NoError(t, err)
+	due := schedule.NextWorkerNonce()
+	require.Equal(t, lib.BlockHeight(120), due.Height)
+	estimated := time.Until(due.At)
+
+	start := time.Now()
+	suite.WaitForScheduledNonce(context.Background(), schedule, due, 3600, nil)
+	latestHeight, _, err := source.GetLatestBlock(context.Background())
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, latestHeight, due.Height, "woke up before the due block")
+	assert.Less(t, time.Since(start), estimated*3/4, "woke up on the estimated time of the due block, blocks late")
+	assert.LessOrEqual(t, latestHeight, due.Height+3, "woke up blocks late")
+}
@@ -2,70 +2,66 @@ package usecase
 
 import (
 	"allora_offchain_node/lib"
+	"context"
+	"errors"
 	"sync"
 
-	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
 	"github.com/rs/zerolog/log"
 )
 
-func (suite *UseCaseSuite) Spawn() {
+// Runs the actor processes until the context is done, e.g. on SIGTERM, and returns once they have all stopped.
+// The actors are reconciled with the config on every Reload meanwhile.
+func (suite *UseCaseSuite) Spawn(ctx context.Context) {
 	var wg sync.WaitGroup
 
-	// Run worker process per topic
-	alreadyStartedWorkerForTopic := make(map[emissionstypes.TopicId]bool)
-	for _, worker := range suite.Node.Worker {
-		if _, ok := alreadyStartedWorkerForTopic[worker.TopicId]; ok {
-			log.Debug().Uint64("topicId", worker.TopicId).Msg("Worker already started for topicId")
-			continue
-		}
-		alreadyStartedWorkerForTopic[worker.TopicId] = true
-
+	suite.Scheduler = NewScheduler(&suite.Node)
+	if suite.Node.Wallet.SubscribeToEvents {
+		suite.Events = NewEventDispatcher()
 		wg.Add(1)
-		go func(worker lib.WorkerConfig) {
+		go func() {
 			defer wg.Done()
-			suite.runWorkerProcess(worker)
-		}(worker)
+			suite.runEventSubscription(ctx)
+		}()
 	}
 
-	// Run reputer process per topic
-	alreadyStartedReputerForTopic := make(map[emissionstypes.TopicId]bool)
-	for _, reputer := range suite.Node.Reputer {
-		if _, ok := alreadyStartedReputerForTopic[reputer.TopicId]; ok {
-			log.Debug().Uint64("topicId", reputer.TopicId).Msg("Reputer already started for topicId")
-			continue
-		}
-		alreadyStartedReputerForTopic[reputer.TopicId] = true
+	// Run a process per worker and reputer config. Duplicates are rejected by UserConfig.Validate
+	suite.startActors(ctx)
 
-		wg.Add(1)
-		go func(reputer lib.ReputerConfig) {
-			defer wg.Done()
-			suite.runReputerProcess(reputer)
-		}(reputer)
-	}
-
-	// Wait for all goroutines to finish
+	<-ctx.Done()
+	suite.waitForActors()
 	wg.Wait()
 }
 
-func (suite *UseCaseSuite) runWorkerProcess(worker lib.WorkerConfig) {
-	log.Info().Uint64("topicId", worker.TopicId).Msg("Running worker process for topic")
+// Runs the worker with the suite of its wallet, see ForActor, reading its config on every iteration
+func (suite *UseCaseSuite) runWorkerProcess(ctx context.Context, config *liveConfig[lib.WorkerConfig]) {
+	worker := config.Get()
+	log.Info().Uint64("topicId", worker.TopicId).Str("address", suite.Node.Wallet.Address).Msg("Running worker process for topic")
 
-	registered := suite.Node.RegisterWorkerIdempotently(worker)
+	registered := suite.Node.RegisterWorkerIdempotently(ctx, worker)
 	if !registered {
 		log.Error().Uint64("topicId", worker.TopicId).Msg("Failed to register worker for topic")
 		return
 	}
 
-	latestNonceHeightActedUpon := int64(0)
-	for {
-		latestOpenWorkerNonce, err := suite.Node.GetLatestOpenWorkerNonceByTopicId(worker.TopicId)
+	trigger := suite.Events.RegisterWorker()
+	defer suite.Events.Unregister(trigger)
+	// Resume after the latest nonce the ledger shows as submitted, so a restart doesn't resubmit it
+	latestNonceHeightActedUpon, err := suite.Ledger.LatestDoneNonce(lib.ROLE_WORKER, suite.Node.Wallet.Address, worker.TopicId)
+	if err != nil {
+		log.Warn().Err(err).Uint64("topicId", worker.TopicId).Msg("Could not read submission ledger, acting upon the latest open worker nonce")
+	} else if latestNonceHeightActedUpon > 0 {
+		log.Info().Uint64("topicId", worker.TopicId).Int64("BlockHeight", latestNonceHeightActedUpon).Msg("Resuming worker after the latest nonce submitted")
+	}
+	for ctx.Err() == nil {
+		worker = config.Get()
+		latestOpenWorkerNonce, err := suite.Node.GetLatestOpenWorkerNonceByTopicId(ctx, worker.TopicId)
 		if err != nil {
 			log.Warn().Err(err).Uint64("topicId", worker.TopicId).Msg("Error getting latest open worker nonce on topic - node availability issue?")
 		} else {
 			if latestOpenWorkerNonce.BlockHeight > latestNonceHeightActedUpon {
 				log.Debug().Uint64("topicId", worker.TopicId).Int64("BlockHeight", latestOpenWorkerNonce.BlockHeight).Msg("Building and committing worker payload for topic")
 
-				success, err := suite.BuildCommitWorkerPayload(worker, latestOpenWorkerNonce)
+				success, err := suite.BuildCommitWorkerPayload(ctx, worker, latestOpenWorkerNonce)
 				if !success || err != nil {
 					log.Error().Err(err).Uint64("topicId", worker.TopicId).Int64("BlockHeight", latestOpenWorkerNonce.BlockHeight).Msg("Error building and committing worker payload for topic")
 				}
@@ -74,37 +70,73 @@ func (suite *UseCaseSuite) runWorkerProcess(worker lib.WorkerConfig) {
 				log.Debug().Uint64("topicId", worker.TopicId).Msg("No new worker nonce found")
 			}
 		}
-		suite.Wait(worker.LoopSeconds)
+		suite.WaitForNextWorkerNonce(ctx, worker, trigger)
 	}
+	log.Info().Uint64("topicId", worker.TopicId).Msg("Worker process stopped")
 }
 
-func (suite *UseCaseSuite) runReputerProcess(reputer lib.ReputerConfig) {
-	log.Debug().Uint64("topicId", reputer.TopicId).Msg("Running reputer process for topic")
+// Runs the reputer with the suite of its wallet, see ForActor, reading its config on every iteration
+func (suite *UseCaseSuite) runReputerProcess(ctx context.Context, config *liveConfig[lib.ReputerConfig]) {
+	reputer := config.Get()
+	log.Debug().Uint64("topicId", reputer.TopicId).Str("address", suite.Node.Wallet.Address).Msg("Running reputer process for topic")
 
-	registeredAndStaked := suite.Node.RegisterAndStakeReputerIdempotently(reputer)
+	registeredAndStaked := suite.Node.RegisterAndStakeReputerIdempotently(ctx, reputer)
 	if !registeredAndStaked {
 		log.Error().Uint64("topicId", reputer.TopicId).Msg("Failed to register or sufficiently stake reputer for topic")
 		return
 	}
 
-	latestNonceHeightActedUpon := int64(0)
-	for {
-		latestOpenReputerNonce, err := suite.Node.GetOldestReputerNonceByTopicId(reputer.TopicId)
+	trigger := suite.Events.RegisterReputer(reputer.TopicId)
+	defer suite.Events.Unregister(trigger)
+	tracker := NewReputerNonceTracker()
+	for ctx.Err() == nil {
+		reputer = config.Get()
+		waitingNonces := []lib.BlockHeight{}
+		openReputerNonces, err := suite.Node.GetOpenReputerNoncesByTopicId(ctx, reputer.TopicId)
 		if err != nil {
-			log.Warn().Err(err).Uint64("topicId", reputer.TopicId).Int64("BlockHeight", latestOpenReputerNonce).Msg("Error getting latest open reputer nonce on topic - node availability issue?")
+			log.Warn().Err(err).Uint64("topicId", reputer.TopicId).Msg("Error getting open reputer nonces on topic - node availability issue?")
 		} else {
-			if latestOpenReputerNonce > latestNonceHeightActedUpon {
-				log.Debug().Uint64("topicId", reputer.TopicId).Int64("BlockHeight", latestOpenReputerNonce).Msg("Building and committing reputer payload for topic")
+			schedule, scheduleErr := suite.Scheduler.GetTopicSchedule(ctx, reputer.TopicId)
+			if scheduleErr != nil {
+				log.Warn().Err(scheduleErr).Uint64("topicId", reputer.TopicId).Msg("Could not get topic schedule, acting upon every open reputer nonce")
+			}
+			pendingNonces := tracker.Update(openReputerNonces)
+			if len(pendingNonces) == 0 {
+				log.Debug().Uint64("topicId", reputer.TopicId).Msg("No new reputer nonce found")
+			}
+			// Oldest first, as their windows close first
+			for _, nonce := range pendingNonces {
+				if ctx.Err() != nil {
+					break
+				}
+				if scheduleErr == nil && !schedule.IsReputerWindowOpen(nonce) {
+					log.Debug().Uint64("topicId", reputer.TopicId).Int64("BlockHeight", nonce).Msg("Reputer submission window not open for nonce")
+					waitingNonces = append(waitingNonces, nonce)
+					continue
+				}
+				if tracker.Attempts(nonce) == 0 && suite.IsSubmissionDone(lib.ROLE_REPUTER, reputer.TopicId, nonce) {
+					log.Info().Uint64("topicId", reputer.TopicId).Int64("BlockHeight", nonce).Msg("Reputer payload already submitted for nonce according to the ledger")
+					tracker.Record(nonce, nil)
+					continue
+				}
+				log.Debug().Uint64("topicId", reputer.TopicId).Int64("BlockHeight", nonce).Int("previousAttempts", tracker.Attempts(nonce)).Msg("Building and committing reputer payload for topic")
 
-				success, err := suite.BuildCommitReputerPayload(reputer, latestOpenReputerNonce)
+				success, err := suite.BuildCommitReputerPayload(ctx, reputer, nonce)
 				if !success || err != nil {
-					log.Error().Err(err).Uint64("topicId", reputer.TopicId).Msg("Error building and committing reputer payload for topic")
+					log.Error().Err(err).Uint64("topicId", reputer.TopicId).Int64("BlockHeight", nonce).Msg("Error building and committing reputer payload for topic, will retry while the nonce is open")
+					if err == nil {
+						err = errors.New("reputer payload not committed")
+					}
 				}
-				latestNonceHeightActedUpon = latestOpenReputerNonce
-			} else {
-				log.Debug().Uint64("topicId", reputer.TopicId).Msg("No new reputer nonce found")
+				tracker.Record(nonce, err)
 			}
 		}
-		suite.Wait(reputer.LoopSeconds)
+		if tracker.HasFailures() {
+			// Failed nonces are retried on the regular cadence, without waiting for chain events
+			suite.WaitForNonceRetry(ctx, trigger, reputer.LoopSeconds)
+		} else {
+			suite.WaitForNextReputerNonce(ctx, reputer, waitingNonces, trigger)
+		}
 	}
+	log.Info().Uint64("topicId", reputer.TopicId).Msg("Reputer process stopped")
 }
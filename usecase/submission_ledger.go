@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Records the payload about to be sent for the nonce as pending, counting the attempts at the nonce
+func (suite *UseCaseSuite) RecordSubmissionPending(submission *lib.Submission) {
+	previous, err := suite.Ledger.Get(submission.Role, submission.Actor, submission.TopicId, submission.Nonce)
+	if err != nil {
+		log.Warn().Err(err).Uint64("topicId", submission.TopicId).Int64("BlockHeight", submission.Nonce).Msg("Could not read submission ledger")
+	}
+	submission.Attempts = 1
+	if previous != nil {
+		submission.Attempts = previous.Attempts + 1
+	}
+	submission.Outcome = lib.SUBMISSION_PENDING
+	suite.recordSubmission(submission)
+}
+
+// Records the outcome of sending the submission: its tx result, or the error preventing it
+func (suite *UseCaseSuite) RecordSubmissionOutcome(submission *lib.Submission, result *lib.TxResult, err error) {
+	var txErr *lib.TxError
+	switch {
+	case err != nil:
+		submission.Outcome = lib.SUBMISSION_FAILED
+		submission.Error = err.Error()
+		if errors.As(err, &txErr) {
+			submission.TxHash = txErr.TxHash
+			if txErr.Result != nil {
+				submission.Height = txErr.Result.Height
+				submission.Fee = txErr.Result.Fee
+			}
+		}
+	case result == nil:
+		submission.Outcome = lib.SUBMISSION_ALREADY_SUBMITTED
+	default:
+		submission.Outcome = lib.SUBMISSION_INCLUDED
+		submission.TxHash = result.TxHash
+		submission.Height = result.Height
+		submission.Fee = result.Fee
+	}
+	suite.recordSubmission(submission)
+}
+
+// Records the submission as built but not sent, since SubmitTx is off
+func (suite *UseCaseSuite) RecordSubmissionDryRun(submission *lib.Submission) {
+	submission.Outcome = lib.SUBMISSION_DRY_RUN
+	suite.recordSubmission(submission)
+}
+
+func (suite *UseCaseSuite) recordSubmission(submission *lib.Submission) {
+	submission.UpdatedAt = time.Now().UTC()
+	if err := suite.Ledger.Record(*submission); err != nil {
+		log.Error().Err(err).Uint64("topicId", submission.TopicId).Int64("BlockHeight", submission.Nonce).Msg("Could not record submission in ledger")
+	}
+}
+
+// True if the ledger shows the actor's payload for the nonce needs no further submission, e.g. after a restart
+func (suite *UseCaseSuite) IsSubmissionDone(role lib.ActorRole, topicId uint64, nonce lib.BlockHeight) bool {
+	submission, err := suite.Ledger.Get(role, suite.Node.Wallet.Address, topicId, nonce)
+	if err != nil {
+		log.Warn().Err(err).Uint64("topicId", topicId).Int64("BlockHeight", nonce).Msg("Could not read submission ledger")
+		return false
+	}
+	return submission != nil && submission.Outcome.IsDone()
+}
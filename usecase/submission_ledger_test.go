@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunSubmissionIsNotDone(t *testing.T) {
+	ledger, err := lib.OpenSubmissionLedger(filepath.Join(t.TempDir(), lib.LEDGER_FILE_NAME), false)
+	require.NoError(t, err)
+	defer ledger.Close()
+	suite := &UseCaseSuite{Node: lib.NodeConfig{Wallet: lib.WalletConfig{Address: "allo1actor"}}, Ledger: ledger}
+	newSubmission := func() *lib.Submission {
+		return &lib.Submission{Role: lib.ROLE_WORKER, Actor: "allo1actor", TopicId: 1, Nonce: 100}
+	}
+
+	dryRun := newSubmission()
+	suite.RecordSubmissionPending(dryRun)
+	suite.RecordSubmissionDryRun(dryRun)
+	assert.False(t, suite.IsSubmissionDone(lib.ROLE_WORKER, 1, 100), "a dry run sent nothing")
+	latest, err := ledger.LatestDoneNonce(lib.ROLE_WORKER, "allo1actor", 1)
+	require.NoError(t, err)
+	assert.Equal(t, lib.BlockHeight(0), latest, "a restart with SubmitTx on must not resume after a dry run")
+
+	submission := newSubmission()
+	suite.RecordSubmissionPending(submission)
+	suite.RecordSubmissionOutcome(submission, &lib.TxResult{TxHash: "AA", Height: 101}, nil)
+	assert.True(t, suite.IsSubmissionDone(lib.ROLE_WORKER, 1, 100))
+	latest, err = ledger.LatestDoneNonce(lib.ROLE_WORKER, "allo1actor", 1)
+	require.NoError(t, err)
+	assert.Equal(t, lib.BlockHeight(100), latest)
+	recorded, err := ledger.Get(lib.ROLE_WORKER, "allo1actor", 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 2, recorded.Attempts)
+}
@@ -2,19 +2,129 @@ package usecase
 
 import (
 	lib "allora_offchain_node/lib"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/rs/zerolog/log"
 )
 
 type UseCaseSuite struct {
-	Node    lib.NodeConfig
-	Metrics lib.Metrics
+	Node         lib.NodeConfig
+	Metrics      lib.Metrics
+	Events       *EventDispatcher           // nil unless the node subscribes to chain events
+	Scheduler    *Scheduler                 // set once the actors are spawned, nil in the admin suite
+	ActorNodes   map[string]*lib.NodeConfig // nodes of the actors with their own wallet, by wallet key name
+	Ledger       *lib.SubmissionLedger      // submissions of every actor, nil if not opened
+	GroundTruths *lib.GroundTruthCache      // ground truths shared by the reputers, nil if not cached
+	Actors       *ActorSupervisor           // running actors, reconciled with the config on reload
 }
 
 // Static method to create a new UseCaseSuite
 func NewUseCaseSuite(userConfig lib.UserConfig) (*UseCaseSuite, error) {
-	userConfig.ValidateConfigAdapters()
+	return newUseCaseSuite(userConfig, true)
+}
+
+// Creates a suite to manage the accounts of the config with admin commands, leaving the submission ledger
+// unopened so that the commands can run alongside the node
+func NewAdminSuite(userConfig lib.UserConfig) (*UseCaseSuite, error) {
+	return newUseCaseSuite(userConfig, false)
+}
+
+func newUseCaseSuite(userConfig lib.UserConfig, openLedger bool) (*UseCaseSuite, error) {
+	if err := validateUserConfig(&userConfig); err != nil {
+		return nil, err
+	}
+	// Reloads are compared with the config as provided, before the node fills it in
+	actors := NewActorSupervisor(userConfig)
 	nodeConfig, err := userConfig.GenerateNodeConfig()
 	if err != nil {
 		return nil, err
 	}
-	return &UseCaseSuite{Node: *nodeConfig}, nil
+	if nodeConfig == nil {
+		return nil, errors.New("cannot connect to the chain")
+	}
+	var ledger *lib.SubmissionLedger
+	var groundTruths *lib.GroundTruthCache
+	if openLedger {
+		ledger, err = lib.OpenSubmissionLedger(nodeConfig.Wallet.LedgerFilePath(), false)
+		if err != nil {
+			return nil, err
+		}
+		groundTruths, err = lib.NewGroundTruthCache(ledger, time.Duration(nodeConfig.Wallet.GroundTruthCacheSeconds)*time.Second)
+		if err != nil {
+			ledger.Close()
+			return nil, err
+		}
+	}
+	suite := &UseCaseSuite{
+		Node:         *nodeConfig,
+		ActorNodes:   make(map[string]*lib.NodeConfig),
+		Ledger:       ledger,
+		GroundTruths: groundTruths,
+		Actors:       actors,
+	}
+
+	for _, worker := range userConfig.Worker {
+		if err := suite.loadActorNode(worker.Wallet); err != nil {
+			return nil, err
+		}
+	}
+	for _, reputer := range userConfig.Reputer {
+		if err := suite.loadActorNode(reputer.Wallet); err != nil {
+			return nil, err
+		}
+	}
+	return suite, nil
+}
+
+// Releases the suite once its actors have stopped: abandons the tx batches being sent, stores the ground truth
+// cache hits, then closes the ledger
+func (suite *UseCaseSuite) Close() error {
+	suite.Node.Chain.Batcher.Close()
+	for _, actorNode := range suite.ActorNodes {
+		actorNode.Chain.Batcher.Close()
+	}
+	if err := suite.GroundTruths.Close(); err != nil {
+		log.Warn().Err(err).Msg("Could not store ground truth cache hits")
+	}
+	return suite.Ledger.Close()
+}
+
+// Loads the node of an actor wallet, once per key so that actors sharing a wallet share its account.
+// Fails if the wallet was already loaded with another config, e.g. by a removed actor before a reload.
+func (suite *UseCaseSuite) loadActorNode(wallet *lib.WalletConfig) error {
+	merged := suite.Node.Wallet.Override(wallet)
+	keyName := merged.AddressKeyName
+	if wallet == nil || keyName == suite.Node.Wallet.AddressKeyName {
+		return nil
+	}
+	if actorNode, ok := suite.ActorNodes[keyName]; ok {
+		// The address and SubmitTx are filled in on loading
+		loaded := actorNode.Wallet
+		loaded.Address, loaded.SubmitTx = merged.Address, merged.SubmitTx
+		if !reflect.DeepEqual(loaded, merged) {
+			return fmt.Errorf("wallet %q is already loaded with another config, restart the node to apply it", keyName)
+		}
+		return nil
+	}
+	actorNode, err := suite.Node.GenerateActorNodeConfig(wallet)
+	if err != nil {
+		return err
+	}
+	suite.ActorNodes[keyName] = actorNode
+	return nil
+}
+
+// Returns a copy of the suite acting with the given actor wallet, or the suite itself if the actor uses the node's wallet.
+// Submissions and metrics of the copy are attributed to the actor's address.
+func (suite *UseCaseSuite) ForActor(wallet *lib.WalletConfig) *UseCaseSuite {
+	actorNode, ok := suite.ActorNodes[suite.Node.Wallet.Override(wallet).AddressKeyName]
+	if wallet == nil || !ok {
+		return suite
+	}
+	actor := *suite
+	actor.Node = *actorNode
+	return &actor
 }
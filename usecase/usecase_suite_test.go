@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"allora_offchain_node/lib"
+	"testing"
+
+	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForActor(t *testing.T) {
+	suite := &UseCaseSuite{
+		Node: lib.NodeConfig{
+			Wallet: lib.WalletConfig{Address: "allo1node", AddressKeyName: "node"},
+		},
+		Events: NewEventDispatcher(),
+		ActorNodes: map[string]*lib.NodeConfig{
+			"challenger": {
+				Wallet: lib.WalletConfig{Address: "allo1challenger", AddressKeyName: "challenger"},
+				Chain:  lib.ChainConfig{Address: "allo1challenger"},
+			},
+		},
+	}
+
+	assert.Same(t, suite, suite.ForActor(nil))
+	assert.Same(t, suite, suite.ForActor(&lib.WalletConfig{AddressKeyName: "node"}))
+
+	actor := suite.ForActor(&lib.WalletConfig{AddressKeyName: "challenger"})
+	require.NotSame(t, suite, actor)
+	assert.Equal(t, "allo1challenger", actor.Node.Chain.Address)
+	assert.Equal(t, "allo1node", suite.Node.Wallet.Address, "the node's suite is left untouched")
+	assert.Same(t, suite.Events, actor.Events, "actors share the node's events")
+
+	// Payloads built by the actor are attributed to its own address
+	payload, err := actor.BuildWorkerPayload(lib.WorkerResponse{
+		WorkerConfig: lib.WorkerConfig{TopicId: emissionstypes.TopicId(1)},
+		InfererValue: "9.5",
+	}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "allo1challenger", payload.Inference.Inferer)
+
+	// A loaded wallet is shared by the actors configuring it alike, and never silently loaded with another config
+	require.NoError(t, suite.loadActorNode(&lib.WalletConfig{AddressKeyName: "challenger"}))
+	err = suite.loadActorNode(&lib.WalletConfig{AddressKeyName: "challenger", TxBatchWindowMillis: 500})
+	assert.EqualError(t, err, `wallet "challenger" is already loaded with another config, restart the node to apply it`)
+}
@@ -1,13 +1,34 @@
 package usecase
 
 import (
+	"allora_offchain_node/lib"
+	"context"
+	"errors"
 	"time"
 
+	"github.com/rs/zerolog/log"
+
 	emissionstypes "github.com/allora-network/allora-chain/x/emissions/types"
 )
 
-func (suite *UseCaseSuite) Wait(seconds int64) {
-	time.Sleep(time.Duration(seconds) * time.Second)
+// Sleeps for the given number of seconds, or until the context is done
+func (suite *UseCaseSuite) Wait(ctx context.Context, seconds int64) {
+	_ = lib.SleepWithContext(ctx, time.Duration(seconds)*time.Second)
+}
+
+// Counts the on-chain outcome of the tx carrying the topic's payload
+func (suite *UseCaseSuite) RecordTxOutcome(topicId emissionstypes.TopicId, result *lib.TxResult, err error) {
+	var txErr *lib.TxError
+	var inclusionErr *lib.TxInclusionError
+	switch {
+	case err == nil && result != nil:
+		log.Info().Uint64("topicId", topicId).Str("txHash", result.TxHash).Int64("height", result.Height).Int64("gasUsed", result.GasUsed).Msg("Tx included in a block")
+		suite.Metrics.IncrementMetricsCounter(lib.TxIncludedCount, suite.Node.Chain.Address, topicId)
+	case errors.As(err, &txErr) && txErr.Result != nil:
+		suite.Metrics.IncrementMetricsCounter(lib.TxFailedAfterInclusionCount, suite.Node.Chain.Address, topicId)
+	case errors.As(err, &inclusionErr):
+		suite.Metrics.IncrementMetricsCounter(lib.TxUnconfirmedCount, suite.Node.Chain.Address, topicId)
+	}
 }
 
 func IsEmpty(vb emissionstypes.ValueBundle) bool {